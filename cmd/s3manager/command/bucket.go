@@ -0,0 +1,60 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+func runMb(ctx context.Context, args []string) error {
+	fs, cfgPath := newFlagSet("mb", "s3://bucket")
+	region := fs.String("region", "", "region of the bucket")
+	versioning := fs.Bool("versioning", false, "enable versioning")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := wantArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	bucket, err := parseBucket(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	srvc, err := connect(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer srvc.Close()
+
+	opts := model.CreateBucketOptions{Region: *region, Versioning: *versioning}
+	if err = srvc.CreateBucket(ctx, bucket, opts); err != nil {
+		return fmt.Errorf("creating bucket: %w", err)
+	}
+	return nil
+}
+
+func runRb(ctx context.Context, args []string) error {
+	fs, cfgPath := newFlagSet("rb", "s3://bucket")
+	force := fs.Bool("f", false, "delete every object in the bucket first")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := wantArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	bucket, err := parseBucket(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	srvc, err := connect(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer srvc.Close()
+
+	if err = srvc.DeleteBucket(ctx, bucket, *force); err != nil {
+		return fmt.Errorf("deleting bucket: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func runCat(ctx context.Context, args []string) error {
+	fs, cfgPath := newFlagSet("cat", "s3://bucket/key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := wantArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	bucket, key, err := parseObject(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	srvc, err := connect(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer srvc.Close()
+
+	body, _, err := srvc.GetObject(ctx, bucket, key)
+	if err != nil {
+		return fmt.Errorf("getting object: %w", err)
+	}
+	defer body.Close()
+	_, err = io.Copy(os.Stdout, body)
+	return err
+}
+
+func runStat(ctx context.Context, args []string) error {
+	fs, cfgPath := newFlagSet("stat", "s3://bucket/key")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := wantArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	bucket, key, err := parseObject(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	srvc, err := connect(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer srvc.Close()
+
+	obj, err := srvc.StatObject(ctx, bucket, key)
+	if err != nil {
+		return fmt.Errorf("getting object: %w", err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(obj)
+	}
+	fmt.Printf("Key:           %s\n", aws.ToString(obj.Key))
+	fmt.Printf("Size:          %d\n", aws.ToInt64(obj.Size))
+	fmt.Printf("Last modified: %s\n", aws.ToString(obj.LastModified))
+	fmt.Printf("ETag:          %s\n", aws.ToString(obj.ETag))
+	fmt.Printf("Storage class: %s\n", aws.ToString(obj.StorageClass))
+	if r := obj.Restore; r != nil {
+		if r.InProgress {
+			fmt.Println("Restore:       in progress")
+		} else {
+			fmt.Printf("Restore:       done, until %s\n", aws.ToString(r.ExpiresAt))
+		}
+	}
+	fmt.Printf("Content type:  %s\n", aws.ToString(obj.ContentType))
+	for _, k := range slices.Sorted(maps.Keys(obj.Checksums)) {
+		fmt.Printf("Checksum:      %s %s\n", k, obj.Checksums[k])
+	}
+	for _, k := range slices.Sorted(maps.Keys(obj.Metadata)) {
+		fmt.Printf("Metadata:      %s=%s\n", k, obj.Metadata[k])
+	}
+	return nil
+}
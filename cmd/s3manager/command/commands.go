@@ -0,0 +1,140 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/hossein1376/s3manager/internal/config"
+	"github.com/hossein1376/s3manager/internal/services"
+)
+
+// remoteScheme marks a path in S3, as opposed to a local one.
+const remoteScheme = "s3://"
+
+type command struct {
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"ls": {
+		summary: "list buckets, or the objects in a bucket",
+		run:     runLs,
+	},
+	"cp": {
+		summary: "copy files to a bucket, or objects to local files",
+		run:     runCp,
+	},
+	"rm": {
+		summary: "delete an object, or every object under a prefix",
+		run:     runRm,
+	},
+	"mb": {
+		summary: "create a bucket",
+		run:     runMb,
+	},
+	"rb": {
+		summary: "delete a bucket",
+		run:     runRb,
+	},
+	"cat": {
+		summary: "write an object to the standard output",
+		run:     runCat,
+	},
+	"stat": {
+		summary: "show the properties and metadata of an object",
+		run:     runStat,
+	},
+}
+
+var commandOrder = []string{"ls", "cp", "rm", "mb", "rb", "cat", "stat"}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "usage: s3manager [-c config]")
+	fmt.Fprintln(out, "       s3manager <command> [-c config] [flags] [args]")
+	fmt.Fprintln(out, "\nWithout a command, the web manager is started.\n\nCommands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(out, "  %-5s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(out, "\nRun 's3manager <command> -h' for its flags.")
+}
+
+// newFlagSet returns the flags of a subcommand, along with the config file
+// path that every one of them takes. args describes its positional arguments.
+func newFlagSet(name, args string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	cfgPath := fs.String("c", defaultConfigPath, "config file path")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: s3manager %s [-c config] [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs, cfgPath
+}
+
+// connect loads the config and builds the services of a subcommand. Logs go
+// to the standard error, to keep the output clean for scripts.
+func connect(cfgPath string) (*services.Services, error) {
+	cfg, err := config.New(cfgPath)
+	if err != nil {
+		return nil, fmt.Errorf("new config: %w", err)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(
+		os.Stderr, &slog.HandlerOptions{Level: cfg.Logger.Level},
+	)))
+	if cfg.IsDefault {
+		slog.Warn("using default configs, use -c flag to specify configuration file")
+	}
+	return newServices(cfg, nil)
+}
+
+// parseRemote splits a bucket path into the bucket and the key, the scheme is
+// optional.
+func parseRemote(path string) (bucket, key string, err error) {
+	bucket, key, _ = strings.Cut(strings.TrimPrefix(path, remoteScheme), "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("missing bucket name in %q", path)
+	}
+	return bucket, key, nil
+}
+
+// parseBucket is parseRemote for commands that take a bucket alone.
+func parseBucket(path string) (string, error) {
+	bucket, key, err := parseRemote(path)
+	if err != nil {
+		return "", err
+	}
+	if key != "" {
+		return "", fmt.Errorf("expected a bucket, not the object path %q", path)
+	}
+	return bucket, nil
+}
+
+// parseObject is parseRemote for commands that take a single object.
+func parseObject(path string) (bucket, key string, err error) {
+	bucket, key, err = parseRemote(path)
+	if err != nil {
+		return "", "", err
+	}
+	if key == "" {
+		return "", "", fmt.Errorf("missing object key in %q", path)
+	}
+	return bucket, key, nil
+}
+
+func isRemote(path string) bool {
+	return strings.HasPrefix(path, remoteScheme)
+}
+
+// wantArgs checks the number of positional arguments left after the flags.
+func wantArgs(fs *flag.FlagSet, min, max int) error {
+	if n := fs.NArg(); n < min || n > max {
+		fs.Usage()
+		return fmt.Errorf("%s: wrong number of arguments", fs.Name())
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/hossein1376/s3manager/internal/model"
+	"github.com/hossein1376/s3manager/internal/services"
+)
+
+func runCp(ctx context.Context, args []string) error {
+	flags, cfgPath := newFlagSet("cp", "source destination")
+	recursive := flags.Bool("r", false, "copy a whole directory or prefix")
+	storageClass := flags.String("storage-class", "", "storage class of uploaded objects")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := wantArgs(flags, 2, 2); err != nil {
+		return err
+	}
+	src, dst := flags.Arg(0), flags.Arg(1)
+	if isRemote(src) == isRemote(dst) {
+		return errors.New("cp: exactly one of source and destination must start with " + remoteScheme)
+	}
+	srvc, err := connect(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer srvc.Close()
+
+	if isRemote(dst) {
+		bucket, key, err := parseRemote(dst)
+		if err != nil {
+			return err
+		}
+		opts := model.PutObjectOptions{StorageClass: strings.ToUpper(*storageClass)}
+		if *recursive {
+			return uploadDir(ctx, srvc, src, bucket, key, opts)
+		}
+		if key == "" || strings.HasSuffix(key, "/") {
+			key += filepath.Base(src)
+		}
+		return uploadFile(ctx, srvc, src, bucket, key, opts)
+	}
+
+	bucket, key, err := parseRemote(src)
+	if err != nil {
+		return err
+	}
+	if *recursive {
+		return downloadPrefix(ctx, srvc, bucket, key, dst)
+	}
+	if info, err := os.Stat(dst); err == nil && info.IsDir() {
+		dst = filepath.Join(dst, path.Base(key))
+	}
+	return downloadFile(ctx, srvc, bucket, key, dst)
+}
+
+// uploadDir puts every file under dir to the prefix, keeping their relative
+// paths.
+func uploadDir(
+	ctx context.Context,
+	srvc *services.Services,
+	dir, bucket, prefix string,
+	opts model.PutObjectOptions,
+) error {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		return uploadFile(ctx, srvc, p, bucket, prefix+filepath.ToSlash(rel), opts)
+	})
+}
+
+func uploadFile(
+	ctx context.Context,
+	srvc *services.Services,
+	file, bucket, key string,
+	opts model.PutObjectOptions,
+) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	mimeType := mime.TypeByExtension(filepath.Ext(file))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	if _, err = srvc.PutObject(ctx, bucket, key, mimeType, f, opts); err != nil {
+		return fmt.Errorf("uploading %s: %w", file, err)
+	}
+	fmt.Printf("%s -> %s%s/%s\n", file, remoteScheme, bucket, key)
+	return nil
+}
+
+// downloadPrefix writes every object under the prefix into dir, nested
+// according to their keys.
+func downloadPrefix(
+	ctx context.Context, srvc *services.Services, bucket, prefix, dir string,
+) error {
+	opt := model.ListObjectsOption{Path: prefix, Recursive: true}
+	for {
+		objects, next, err := srvc.ListObjects(ctx, bucket, listPageSize, opt)
+		if err != nil {
+			return fmt.Errorf("listing objects: %w", err)
+		}
+		for _, o := range objects {
+			rel := aws.ToString(o.Key)
+			// Folder markers have nothing to write.
+			if o.IsDir || rel == "" || strings.HasSuffix(rel, "/") {
+				continue
+			}
+			if !filepath.IsLocal(filepath.FromSlash(rel)) {
+				return fmt.Errorf("key %q leads outside of %s", rel, dir)
+			}
+			target := filepath.Join(dir, filepath.FromSlash(rel))
+			if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			key := rel
+			if prefix != "" {
+				key = strings.TrimSuffix(prefix, "/") + "/" + rel
+			}
+			if err = downloadFile(ctx, srvc, bucket, key, target); err != nil {
+				return err
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		opt.ContinuationToken = next
+	}
+}
+
+func downloadFile(
+	ctx context.Context, srvc *services.Services, bucket, key, file string,
+) error {
+	body, _, err := srvc.GetObject(ctx, bucket, key)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", key, err)
+	}
+	defer body.Close()
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, body); err != nil {
+		f.Close()
+		return fmt.Errorf("downloading %s: %w", key, err)
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("%s%s/%s -> %s\n", remoteScheme, bucket, key, file)
+	return nil
+}
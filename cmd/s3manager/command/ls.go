@@ -0,0 +1,78 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const listPageSize = 1000
+
+func runLs(ctx context.Context, args []string) error {
+	fs, cfgPath := newFlagSet("ls", "[s3://bucket[/prefix]]")
+	recursive := fs.Bool("r", false, "list every object under the prefix")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := wantArgs(fs, 0, 1); err != nil {
+		return err
+	}
+	srvc, err := connect(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer srvc.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	if fs.NArg() == 0 {
+		var token *string
+		for {
+			buckets, next, err := srvc.ListBuckets(
+				ctx, listPageSize, model.ListBucketsOptions{ContinuationToken: token},
+			)
+			if err != nil {
+				return fmt.Errorf("listing buckets: %w", err)
+			}
+			for _, b := range buckets {
+				fmt.Fprintf(w, "%s\t%s\n", aws.ToString(b.CreatedAt), aws.ToString(b.Name))
+			}
+			if next == nil {
+				return nil
+			}
+			token = next
+		}
+	}
+
+	bucket, prefix, err := parseRemote(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	opt := model.ListObjectsOption{Path: prefix, Recursive: *recursive}
+	for {
+		objects, next, err := srvc.ListObjects(ctx, bucket, listPageSize, opt)
+		if err != nil {
+			return fmt.Errorf("listing objects: %w", err)
+		}
+		for _, o := range objects {
+			if o.IsDir {
+				fmt.Fprintf(w, "\tDIR\t%s/\n", aws.ToString(o.Key))
+				continue
+			}
+			fmt.Fprintf(
+				w, "%s\t%d\t%s\n",
+				aws.ToString(o.LastModified), aws.ToInt64(o.Size), aws.ToString(o.Key),
+			)
+		}
+		if next == nil {
+			return nil
+		}
+		opt.ContinuationToken = next
+	}
+}
@@ -0,0 +1,57 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+func runRm(ctx context.Context, args []string) error {
+	fs, cfgPath := newFlagSet("rm", "s3://bucket/key")
+	recursive := fs.Bool("r", false, "delete every object under the prefix")
+	dryRun := fs.Bool("dry-run", false, "only report what a recursive delete would remove")
+	permanent := fs.Bool("permanent", false, "skip the trash")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := wantArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	bucket, key, err := parseObject(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	srvc, err := connect(*cfgPath)
+	if err != nil {
+		return err
+	}
+	defer srvc.Close()
+
+	if *dryRun {
+		preview, err := srvc.PreviewDelete(ctx, bucket, []string{key})
+		if err != nil {
+			return fmt.Errorf("previewing delete: %w", err)
+		}
+		for _, k := range preview.Sample {
+			fmt.Println(k)
+		}
+		fmt.Fprintf(
+			os.Stderr, "would delete %d objects, %d bytes\n",
+			preview.Objects, preview.Bytes,
+		)
+		return nil
+	}
+
+	// Unlike the web manager, the -r flag itself stands for the confirmation.
+	opts := model.DeleteObjectOptions{
+		Recursive: *recursive,
+		Permanent: *permanent,
+		DeletedBy: os.Getenv("USER"),
+	}
+	if err = srvc.DeleteObject(ctx, bucket, key, opts); err != nil {
+		return fmt.Errorf("deleting object: %w", err)
+	}
+	return nil
+}
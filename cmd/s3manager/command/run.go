@@ -2,9 +2,11 @@ package command
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"time"
@@ -17,16 +19,51 @@ import (
 
 	"github.com/hossein1376/s3manager/internal/config"
 	"github.com/hossein1376/s3manager/internal/handlers"
+	"github.com/hossein1376/s3manager/internal/metrics"
 )
 
+const defaultConfigPath = "assets/config.yaml"
+
+// Run starts the server, or runs a subcommand when its name is the first
+// argument.
 func Run() error {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		return serve(args)
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return nil
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return serve(args)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := cmd.run(ctx, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+func serve(args []string) error {
 	ctx := context.Background()
 
-	var cfgPath string
-	flag.StringVar(&cfgPath, "c", "assets/config.yaml", "config file path")
-	flag.Parse()
+	fs := flag.NewFlagSet("s3manager", flag.ContinueOnError)
+	cfgPath := fs.String("c", defaultConfigPath, "config file path")
+	fs.Usage = usage
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
 
-	cfg, err := config.New(cfgPath)
+	cfg, err := config.New(*cfgPath)
 	if err != nil {
 		return fmt.Errorf("new config: %w", err)
 	}
@@ -36,36 +73,117 @@ func Run() error {
 		slog.Warn("using default configs, use -c flag to specify configuration file")
 	}
 
-	s3Client := s3.NewFromConfig(aws.Config{
-		BaseEndpoint: aws.String(cfg.S3.Endpoint),
-		Region:       cfg.S3.Region,
-		Credentials: credentials.NewStaticCredentialsProvider(
-			cfg.S3.AccessKeyID, cfg.S3.SecretAccessKey, "",
-		),
-		HTTPClient: nil,
-	})
+	var reg *metrics.Registry
+	if cfg.Metrics.Enabled {
+		reg = metrics.NewRegistry()
+	}
 
-	srvc := services.New(s3Client)
-	server, err := handlers.NewServer(cfg, srvc)
+	srvc, err := newServices(cfg, reg)
+	if err != nil {
+		return fmt.Errorf("new services: %w", err)
+	}
+	defer srvc.Close()
+	server, err := handlers.NewServer(cfg, srvc, reg)
 	if err != nil {
 		return fmt.Errorf("new server: %w", err)
 	}
+	servers := []*http.Server{server}
+	if reg != nil && cfg.Metrics.Address != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", reg)
+		servers = append(servers, &http.Server{
+			Addr:              cfg.Metrics.Address,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		})
+	}
 
-	errCh := make(chan error)
+	errCh := make(chan error, len(servers))
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, os.Interrupt)
 
-	go func() {
-		slog.InfoContext(ctx, "starting server", slog.String("address", server.Addr))
-		errCh <- server.ListenAndServe()
-	}()
+	for _, srv := range servers {
+		go func() {
+			slog.InfoContext(ctx, "starting server", slog.String("address", srv.Addr))
+			errCh <- srv.ListenAndServe()
+		}()
+	}
 
+	var shutdownErr error
 	select {
-	case err := <-errCh:
-		return fmt.Errorf("server error: %w", err)
+	case err = <-errCh:
+		shutdownErr = fmt.Errorf("server error: %w", err)
 	case <-signalCh:
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		return server.Shutdown(shutdownCtx)
 	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, srv := range servers {
+		err = srv.Shutdown(shutdownCtx)
+		if shutdownErr == nil {
+			shutdownErr = err
+		}
+	}
+	return shutdownErr
+}
+
+// newServices connects to the configured S3 endpoint, the same way for the
+// server and the subcommands. Calls to S3 are recorded in reg unless it's nil.
+func newServices(
+	cfg config.Config, reg *metrics.Registry,
+) (*services.Services, error) {
+	checksum, err := services.ParseChecksumAlgorithm(cfg.Checksums.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	retry, err := services.Retry(
+		cfg.S3.Retry.Mode, cfg.S3.Retry.MaxAttempts, cfg.S3.Retry.MaxBackoff,
+	)
+	if err != nil {
+		return nil, err
+	}
+	clientOpts := []func(*s3.Options){retry}
+	if limit := cfg.S3.RateLimit; limit.RequestsPerSecond > 0 {
+		clientOpts = append(
+			clientOpts, services.RateLimit(limit.RequestsPerSecond, limit.Burst),
+		)
+	}
+
+	s3Client := s3.NewFromConfig(aws.Config{
+		BaseEndpoint: aws.String(cfg.S3.Endpoint),
+		Region:       cfg.S3.Region,
+		Credentials: credentials.NewStaticCredentialsProvider(
+			cfg.S3.AccessKeyID, cfg.S3.SecretAccessKey, "",
+		),
+		HTTPClient: nil,
+	}, clientOpts...)
+
+	opts := []services.Option{
+		services.WithJobWorkers(cfg.Jobs.Workers),
+		services.WithThumbnails(
+			cfg.Thumbnails.CacheDir,
+			cfg.Thumbnails.MaxSizeBytes,
+			cfg.Thumbnails.MaxPixels,
+		),
+		services.WithThumbnailLimits(
+			cfg.Thumbnails.Concurrency, cfg.Thumbnails.MaxCacheBytes,
+		),
+		services.WithChecksums(checksum),
+	}
+	if cfg.Trash.Enabled {
+		opts = append(opts, services.WithTrash(
+			cfg.Trash.Bucket,
+			cfg.Trash.Prefix,
+			time.Duration(cfg.Trash.RetentionDays)*24*time.Hour,
+		))
+	}
+	if len(cfg.Fetch.AllowedHosts) > 0 {
+		opts = append(opts, services.WithFetch(
+			cfg.Fetch.AllowedHosts, cfg.S3.MaxSizeBytes,
+		))
+	}
+	if reg != nil {
+		opts = append(opts, services.WithMetrics(reg))
+	}
+	return services.New(s3Client, opts...), nil
 }
@@ -43,6 +43,11 @@ func defaults() Config {
 			SecretAccessKey: secretKey,
 			Region:          "auto",
 			MaxSizeBytes:    100 * 1024 * 1024, // 100mb
+			Retry: Retry{
+				Mode:        "standard",
+				MaxAttempts: 3,
+				MaxBackoff:  20 * time.Second,
+			},
 		},
 		Server: Server{
 			Address:      "0.0.0.0:8080",
@@ -50,6 +55,31 @@ func defaults() Config {
 			WriteTimeout: 1 * time.Minute,
 			DisableUI:    false,
 		},
+		Jobs: Jobs{
+			Workers: 2,
+		},
+		Trash: Trash{
+			Prefix:        ".trash/",
+			RetentionDays: 30,
+		},
+		Editor: Editor{
+			MaxSizeBytes: 1024 * 1024, // 1mb
+		},
+		Checksums: Checksums{
+			Algorithm: "CRC32C",
+		},
+		Diff: Diff{
+			MaxSizeBytes: 5 * 1024 * 1024, // 5mb
+		},
+		Thumbnails: Thumbnails{
+			MaxSizeBytes:  20 * 1024 * 1024, // 20mb
+			MaxPixels:     40_000_000,
+			Concurrency:   2,
+			MaxCacheBytes: 256 * 1024 * 1024, // 256mb
+		},
+		Metrics: Metrics{
+			Enabled: true,
+		},
 		Logger: Logger{
 			Level: slog.LevelInfo,
 		},
@@ -58,18 +88,45 @@ func defaults() Config {
 }
 
 type Config struct {
-	S3        S3     `yaml:"s3"`
-	Server    Server `yaml:"server"`
-	Logger    Logger `yaml:"logger"`
-	IsDefault bool   `yaml:"-"`
+	S3         S3         `yaml:"s3"`
+	Server     Server     `yaml:"server"`
+	Jobs       Jobs       `yaml:"jobs"`
+	Trash      Trash      `yaml:"trash"`
+	Fetch      Fetch      `yaml:"fetch"`
+	Editor     Editor     `yaml:"editor"`
+	Diff       Diff       `yaml:"diff"`
+	Checksums  Checksums  `yaml:"checksums"`
+	Thumbnails Thumbnails `yaml:"thumbnails"`
+	Metrics    Metrics    `yaml:"metrics"`
+	Logger     Logger     `yaml:"logger"`
+	IsDefault  bool       `yaml:"-"`
 }
 
 type S3 struct {
-	Endpoint        string `yaml:"endpoint"`
-	AccessKeyID     string `yaml:"access-key"`
-	SecretAccessKey string `yaml:"secret-access-key"`
-	Region          string `yaml:"region"`
-	MaxSizeBytes    int64  `yaml:"max-size-bytes"`
+	Endpoint        string    `yaml:"endpoint"`
+	AccessKeyID     string    `yaml:"access-key"`
+	SecretAccessKey string    `yaml:"secret-access-key"`
+	Region          string    `yaml:"region"`
+	MaxSizeBytes    int64     `yaml:"max-size-bytes"`
+	Retry           Retry     `yaml:"retry"`
+	RateLimit       RateLimit `yaml:"rate-limit"`
+}
+
+// Retry sets how failed S3 requests are retried. Mode is "standard", or
+// "adaptive" to also slow down once S3 throttles the client. MaxAttempts
+// counts the first attempt and MaxBackoff caps the wait between two of them;
+// zero keeps the SDK's defaults.
+type Retry struct {
+	Mode        string        `yaml:"mode"`
+	MaxAttempts int           `yaml:"max-attempts"`
+	MaxBackoff  time.Duration `yaml:"max-backoff"`
+}
+
+// RateLimit caps the requests sent to S3 per second, retries included, with
+// bursts of up to Burst requests. Zero doesn't limit them.
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"requests-per-second"`
+	Burst             int     `yaml:"burst"`
 }
 
 type Server struct {
@@ -79,6 +136,64 @@ type Server struct {
 	DisableUI    bool          `yaml:"disable-ui"`
 }
 
+type Jobs struct {
+	Workers int `yaml:"workers"`
+}
+
+// Trash keeps deleted objects under Prefix, in Bucket or in the bucket they
+// were deleted from, and purges them after RetentionDays. Zero keeps them
+// until they're purged by hand.
+type Trash struct {
+	Enabled       bool   `yaml:"enabled"`
+	Bucket        string `yaml:"bucket"`
+	Prefix        string `yaml:"prefix"`
+	RetentionDays int    `yaml:"retention-days"`
+}
+
+// Fetch lists the hosts that files may be downloaded from into buckets, by
+// name or as "*.example.com" for any subdomain. A host without a port only
+// allows the default ports, "host:port" allows that port alone. Fetching is
+// off when empty.
+type Fetch struct {
+	AllowedHosts []string `yaml:"allowed-hosts"`
+}
+
+// Editor limits the size of the text objects that can be edited in the UI.
+type Editor struct {
+	MaxSizeBytes int64 `yaml:"max-size-bytes"`
+}
+
+// Diff limits the size of each of the two objects that are compared.
+type Diff struct {
+	MaxSizeBytes int64 `yaml:"max-size-bytes"`
+}
+
+// Checksums sets the algorithm of the checksums sent with uploads: CRC32C
+// when empty, any other one S3 supports, or "none".
+type Checksums struct {
+	Algorithm string `yaml:"algorithm"`
+}
+
+// Thumbnails are cached in CacheDir, a directory under the system's temp
+// directory when empty, and the ones used least recently are evicted once
+// they take more than MaxCacheBytes. Images larger than MaxSizeBytes, or with
+// more than MaxPixels pixels, are never decoded, and at most Concurrency of
+// them are decoded at once.
+type Thumbnails struct {
+	CacheDir      string `yaml:"cache-dir"`
+	MaxSizeBytes  int64  `yaml:"max-size-bytes"`
+	MaxPixels     int    `yaml:"max-pixels"`
+	Concurrency   int    `yaml:"concurrency"`
+	MaxCacheBytes int64  `yaml:"max-cache-bytes"`
+}
+
+// Metrics serves Prometheus metrics at /metrics, on the server's address, or
+// on Address when it's set so they can be kept off the public listener.
+type Metrics struct {
+	Enabled bool   `yaml:"enabled"`
+	Address string `yaml:"address"`
+}
+
 type Logger struct {
 	Level slog.Level `yaml:"level"`
 }
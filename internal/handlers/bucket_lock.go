@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+func (h *Handler) GetBucketObjectLockHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	if v, ok := validateBucket(bucketName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	cfg, err := h.service.GetBucketObjectLock(ctx, bucketName)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("getting object lock: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: cfg}))
+}
+
+func (h *Handler) PutBucketObjectLockHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	req, err := grape.ReadJSON[PutBucketObjectLockRequest](w, r)
+	if err != nil {
+		grape.ExtractFromErr(ctx, w, errs.BadRequest(errs.WithMsg(err.Error())))
+		return
+	}
+	if v, ok := validateBucket(bucketName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	cfg := model.ObjectLockConfig{
+		Enabled: true, DefaultRetention: req.DefaultRetention,
+	}
+	err = h.service.PutBucketObjectLock(ctx, bucketName, cfg)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("putting object lock: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithStatus(http.StatusNoContent))
+}
+
+// PutBucketObjectLockRequest sets the bucket default retention. Omitting the
+// default retention removes it, object lock itself can never be disabled.
+type PutBucketObjectLockRequest struct {
+	DefaultRetention *model.DefaultRetention `json:"default_retention,omitempty"`
+}
+
+func (p PutBucketObjectLockRequest) Validate() error {
+	v := validator.New()
+	checkDefaultRetention(v, p.DefaultRetention)
+	if ok := v.Validate(); !ok {
+		return errs.BadRequest(errs.WithMsg(v.Errors.Error()))
+	}
+	return nil
+}
+
+func checkDefaultRetention(v *validator.Validator, r *model.DefaultRetention) {
+	if r == nil {
+		return
+	}
+	v.Check(
+		"default_retention",
+		validator.Case{
+			Cond: validRetentionMode(r.Mode),
+			Msg:  "Retention mode must be either GOVERNANCE or COMPLIANCE",
+		},
+		validator.Case{
+			Cond: (r.Days == nil) != (r.Years == nil),
+			Msg:  "Exactly one of days or years must be set",
+		},
+		validator.Case{
+			Cond: (r.Days == nil || *r.Days > 0) && (r.Years == nil || *r.Years > 0),
+			Msg:  "Retention period must be positive",
+		},
+	)
+}
+
+func validRetentionMode(mode string) bool {
+	return mode == model.RetentionGovernance || mode == model.RetentionCompliance
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+)
+
+func (h *Handler) GetBucketStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	refresh, err := grape.Query(query, "refresh", strconv.ParseBool)
+	if err != nil {
+		if !errors.Is(err, grape.ErrMissingQuery) {
+			grape.ExtractFromErr(
+				ctx, w, errs.BadRequest(errs.WithMsg("Invalid refresh param")),
+			)
+			return
+		}
+		refresh = false
+	}
+	if v, ok := validateBucket(bucketName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	stats, err := h.service.BucketStats(ctx, bucketName, prefix, refresh)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("getting bucket stats: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: stats}))
+}
+
+func (h *Handler) CancelBucketStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	prefix := r.URL.Query().Get("prefix")
+	if v, ok := validateBucket(bucketName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	err := h.service.CancelBucketStats(ctx, bucketName, prefix)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("cancelling bucket stats: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithStatus(http.StatusNoContent))
+}
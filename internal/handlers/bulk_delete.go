@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const maxBulkDeleteItems = 10000
+
+func (h *Handler) BulkDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	dryRun, err := grape.Query(r.URL.Query(), "dry_run", strconv.ParseBool)
+	if err != nil {
+		if !errors.Is(err, grape.ErrMissingQuery) {
+			grape.ExtractFromErr(
+				ctx, w, errs.BadRequest(errs.WithMsg("Invalid dry_run param")),
+			)
+			return
+		}
+		dryRun = false
+	}
+	req, err := grape.ReadJSON[BulkDeleteRequest](w, r)
+	if err != nil {
+		grape.ExtractFromErr(ctx, w, errs.BadRequest(errs.WithMsg(err.Error())))
+		return
+	}
+	if v, ok := validateBucket(bucketName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	// Prefixes are deleted recursively, so they need a dry run first, the same
+	// as recursive deletes of a single prefix.
+	if dryRun {
+		preview, err := h.service.PreviewDelete(ctx, bucketName, req.Prefixes)
+		if err != nil {
+			writeError(ctx, w, fmt.Errorf("previewing deletion: %w", err))
+			return
+		}
+		grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: preview}))
+		return
+	}
+	if len(req.Prefixes) > 0 {
+		err = h.service.ConfirmDelete(bucketName, req.Prefixes, req.Confirm)
+		if err != nil {
+			writeError(ctx, w, fmt.Errorf("confirming deletion: %w", err))
+			return
+		}
+	}
+
+	opts := model.DeleteObjectsOptions{
+		Permanent: req.Permanent,
+		DeletedBy: requestUser(r),
+	}
+	results, err := h.service.DeleteObjects(
+		ctx, bucketName, req.Keys, req.Prefixes, opts,
+	)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("bulk deleting objects: %w", err))
+		return
+	}
+
+	resp := bulkDeleteResponse{Results: results}
+	for _, res := range results {
+		if res.Deleted {
+			resp.Deleted++
+		} else {
+			resp.Failed++
+		}
+	}
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: resp}))
+}
+
+// BulkDeleteRequest lists the keys to delete. Each prefix expands to every
+// object under it, which needs the token of a dry run of the same prefixes.
+// Keys go to the trash when it's enabled, unless Permanent is set.
+type BulkDeleteRequest struct {
+	Keys      []string `json:"keys"`
+	Prefixes  []string `json:"prefixes,omitempty"`
+	Confirm   string   `json:"confirm,omitempty"`
+	Permanent bool     `json:"permanent,omitempty"`
+}
+
+func (b BulkDeleteRequest) Validate() error {
+	v := validator.New()
+	total := len(b.Keys) + len(b.Prefixes)
+	v.Check(
+		"keys",
+		validator.Case{
+			Cond: total > 0, Msg: "At least one key or prefix is required",
+		},
+		validator.Case{
+			Cond: total <= maxBulkDeleteItems,
+			Msg: fmt.Sprintf(
+				"Cannot delete more than %d keys and prefixes at once",
+				maxBulkDeleteItems,
+			),
+		},
+	)
+	for _, key := range b.Keys {
+		v.Check("keys", validator.Case{
+			Cond: !validator.Empty(key), Msg: "Keys cannot be empty",
+		})
+	}
+	// An empty prefix would delete the whole bucket.
+	for _, prefix := range b.Prefixes {
+		v.Check("prefixes", validator.Case{
+			Cond: !validator.Empty(prefix), Msg: "Prefixes cannot be empty",
+		})
+	}
+	if ok := v.Validate(); !ok {
+		return errs.BadRequest(errs.WithMsg(v.Errors.Error()))
+	}
+	return nil
+}
+
+type bulkDeleteResponse struct {
+	Deleted int                  `json:"deleted"`
+	Failed  int                  `json:"failed"`
+	Results []model.DeleteResult `json:"results"`
+}
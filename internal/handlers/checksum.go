@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+// GetObjectChecksumHandler returns the SHA-256 or MD5 of an object. A stored
+// SHA-256 is returned without reading the object, unless compute is set.
+func (h *Handler) GetObjectChecksumHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	objectName := r.PathValue("object")
+	query := r.URL.Query()
+	algorithm := strings.ToLower(query.Get("algorithm"))
+	if algorithm == "" {
+		algorithm = model.ChecksumSHA256
+	}
+	compute, err := grape.Query(query, "compute", strconv.ParseBool)
+	switch {
+	case err == nil:
+		// continue
+	case errors.Is(err, grape.ErrMissingQuery):
+		compute = false
+	default:
+		grape.ExtractFromErr(
+			ctx, w, errs.BadRequest(errs.WithMsg("Invalid compute param")),
+		)
+		return
+	}
+	v, _ := validateBucket(bucketName)
+	v.Check("key", validator.Case{
+		Cond: !validator.Empty(objectName), Msg: "object name is required",
+	})
+	v.Check("algorithm", validator.Case{
+		Cond: algorithm == model.ChecksumSHA256 || algorithm == model.ChecksumMD5,
+		Msg:  "Algorithm must be sha256 or md5",
+	})
+	if ok := v.Validate(); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	sum, err := h.service.ObjectChecksum(ctx, bucketName, objectName, algorithm, compute)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("getting checksum: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: sum}))
+}
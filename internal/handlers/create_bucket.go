@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"net/http"
+	"slices"
 
 	"github.com/hossein1376/grape"
 	"github.com/hossein1376/grape/errs"
 	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
 )
 
 func (h *Handler) CreateBucketHandler(w http.ResponseWriter, r *http.Request) {
@@ -16,9 +18,17 @@ func (h *Handler) CreateBucketHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.CreateBucket(ctx, req.Name)
+	opts := model.CreateBucketOptions{
+		Region:          req.Region,
+		ObjectLock:      req.ObjectLock,
+		ObjectOwnership: req.ObjectOwnership,
+		Versioning:      req.Versioning,
+		Tags:            req.Tags,
+		Encryption:      req.Encryption,
+	}
+	err = h.service.CreateBucket(ctx, req.Name, opts)
 	if err != nil {
-		grape.ExtractFromErr(ctx, w, err)
+		writeError(ctx, w, err)
 		return
 	}
 
@@ -26,7 +36,13 @@ func (h *Handler) CreateBucketHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 type CreateBucketRequest struct {
-	Name string `json:"name"`
+	Name            string                  `json:"name"`
+	Region          string                  `json:"region,omitempty"`
+	ObjectLock      *model.ObjectLockConfig `json:"object_lock,omitempty"`
+	ObjectOwnership string                  `json:"object_ownership,omitempty"`
+	Versioning      bool                    `json:"versioning,omitempty"`
+	Tags            map[string]string       `json:"tags,omitempty"`
+	Encryption      *model.BucketEncryption `json:"encryption,omitempty"`
 }
 
 func (c CreateBucketRequest) Validate() error {
@@ -42,8 +58,69 @@ func (c CreateBucketRequest) Validate() error {
 			Msg:  "Bucket name cannot contain invalid characters",
 		},
 	)
+	if c.ObjectLock != nil {
+		v.Check(
+			"object_lock",
+			validator.Case{
+				Cond: c.ObjectLock.Enabled || c.ObjectLock.DefaultRetention == nil,
+				Msg:  "Default retention requires object lock to be enabled",
+			},
+		)
+		checkDefaultRetention(v, c.ObjectLock.DefaultRetention)
+	}
+	v.Check(
+		"object_ownership",
+		validator.Case{
+			Cond: slices.Contains(objectOwnerships, c.ObjectOwnership),
+			Msg:  "Object ownership must be one of BucketOwnerEnforced, BucketOwnerPreferred or ObjectWriter",
+		},
+	)
+	v.Check(
+		"tags",
+		validator.Case{
+			Cond: len(c.Tags) <= 50, Msg: "A bucket cannot have more than 50 tags",
+		},
+	)
+	for k, val := range c.Tags {
+		v.Check(
+			"tags",
+			validator.Case{
+				Cond: validator.LengthMin(k, 1) &&
+					validator.Not(validator.LengthMin(k, 129)),
+				Msg: "Tag keys must be between 1 and 128 characters",
+			},
+			validator.Case{
+				Cond: validator.Not(validator.LengthMin(val, 257)),
+				Msg:  "Tag values cannot be longer than 256 characters",
+			},
+		)
+	}
+	if c.Encryption != nil {
+		v.Check(
+			"encryption",
+			validator.Case{
+				Cond: slices.Contains(encryptionAlgorithms, c.Encryption.Algorithm),
+				Msg:  "Encryption algorithm must be one of AES256, aws:kms or aws:kms:dsse",
+			},
+			validator.Case{
+				Cond: c.Encryption.KMSKeyID == nil ||
+					c.Encryption.Algorithm != encryptionAES256,
+				Msg: "KMS key can only be set with KMS encryption",
+			},
+		)
+	}
 	if ok := v.Validate(); !ok {
 		return errs.BadRequest(errs.WithMsg(v.Errors.Error()))
 	}
 	return nil
 }
+
+const encryptionAES256 = "AES256"
+
+var (
+	// The empty string leaves the ownership to the provider's default.
+	objectOwnerships = []string{
+		"", "BucketOwnerEnforced", "BucketOwnerPreferred", "ObjectWriter",
+	}
+	encryptionAlgorithms = []string{encryptionAES256, "aws:kms", "aws:kms:dsse"}
+)
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/validator"
+)
+
+func (h *Handler) CreateFolderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	req, err := grape.ReadJSON[CreateFolderRequest](w, r)
+	if err != nil {
+		grape.ExtractFromErr(ctx, w, errs.BadRequest(errs.WithMsg(err.Error())))
+		return
+	}
+	if v, ok := validateBucket(bucketName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	folder, err := h.service.CreateFolder(ctx, bucketName, req.Path)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("creating folder: %w", err))
+		return
+	}
+
+	grape.WriteJSON(
+		ctx,
+		w,
+		grape.WithStatus(http.StatusCreated),
+		grape.WithData(grape.Response{Data: folder}),
+	)
+}
+
+// CreateFolderRequest names the folder to create, relative to the bucket's
+// root. A trailing slash is optional.
+type CreateFolderRequest struct {
+	Path string `json:"path"`
+}
+
+func (c CreateFolderRequest) Validate() error {
+	v := validator.New()
+	name := strings.TrimSuffix(c.Path, "/")
+	v.Check(
+		"path",
+		validator.Case{
+			Cond: !validator.Empty(name), Msg: "Path is required",
+		},
+		validator.Case{
+			Cond: !strings.HasPrefix(name, "/") && !validator.Contains(name, "//"),
+			Msg:  "Path cannot start with a slash or have empty segments",
+		},
+	)
+	if ok := v.Validate(); !ok {
+		return errs.BadRequest(errs.WithMsg(v.Errors.Error()))
+	}
+	return nil
+}
@@ -3,18 +3,23 @@ package handlers
 import (
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 
 	"github.com/gabriel-vasile/mimetype"
 	"github.com/hossein1376/grape"
 	"github.com/hossein1376/grape/slogger"
 	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
 )
 
+const octetStream = "application/octet-stream"
+
 func (h *Handler) PutObjectHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	bucketName := r.PathValue("bucket")
 	objectKey := r.FormValue("key")
+	storageClass := r.FormValue("storage_class")
 	v := validator.New()
 	v.Check(
 		"bucket",
@@ -36,6 +41,7 @@ func (h *Handler) PutObjectHandler(w http.ResponseWriter, r *http.Request) {
 			Cond: !validator.Empty(objectKey), Msg: "object name is required",
 		},
 	)
+	checkStorageClass(v, storageClass)
 	if ok := v.Validate(); !ok {
 		resp := grape.Response{Message: "Bad input", Data: v.Errors}
 		grape.WriteJSON(
@@ -51,7 +57,7 @@ func (h *Handler) PutObjectHandler(w http.ResponseWriter, r *http.Request) {
 		)
 		return
 	}
-	file, _, err := r.FormFile("file")
+	file, header, err := r.FormFile("file")
 	if err != nil {
 		grape.ExtractFromErr(
 			ctx, w, fmt.Errorf("getting file from form: %w", err),
@@ -63,26 +69,18 @@ func (h *Handler) PutObjectHandler(w http.ResponseWriter, r *http.Request) {
 			slogger.Error(ctx, "closing file", slogger.Err("error", err))
 		}
 	}()
-	// Detect mime type using the first 512 bytes
-	buffer := make([]byte, 512)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
-		grape.ExtractFromErr(ctx, w, fmt.Errorf("reading file header: %w", err))
-		return
-	}
-	mimeType := mimetype.Detect(buffer[:n])
-
-	// Seek back to the beginning of the file
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		grape.ExtractFromErr(ctx, w, fmt.Errorf("seeking file: %w", err))
+	mimeType, err := contentType(file, header)
+	if err != nil {
+		writeError(ctx, w, err)
 		return
 	}
 
 	obj, err := h.service.PutObject(
-		ctx, bucketName, objectKey, mimeType.String(), file,
+		ctx, bucketName, objectKey, mimeType, file,
+		model.PutObjectOptions{StorageClass: storageClass},
 	)
 	if err != nil {
-		grape.ExtractFromErr(ctx, w, fmt.Errorf("putting object: %w", err))
+		writeError(ctx, w, fmt.Errorf("putting object: %w", err))
 		return
 	}
 
@@ -93,3 +91,22 @@ func (h *Handler) PutObjectHandler(w http.ResponseWriter, r *http.Request) {
 		grape.WithData(grape.Response{Data: obj}),
 	)
 }
+
+// contentType returns the type the client sent along with the file. Browsers
+// fall back to application/octet-stream when they don't know it, so then it's
+// detected from the first 512 bytes instead.
+func contentType(file multipart.File, header *multipart.FileHeader) (string, error) {
+	if ct := header.Header.Get("Content-Type"); ct != "" && ct != octetStream {
+		return ct, nil
+	}
+	buffer := make([]byte, 512)
+	n, err := file.Read(buffer)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("reading file header: %w", err)
+	}
+	// Seek back to the beginning of the file
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("seeking file: %w", err)
+	}
+	return mimetype.Detect(buffer[:n]).String(), nil
+}
@@ -24,6 +24,16 @@ func (h *Handler) DeleteBucketHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		recursive = false
 	}
+	dryRun, err := grape.Query(r.URL.Query(), "dry_run", strconv.ParseBool)
+	if err != nil {
+		if !errors.Is(err, grape.ErrMissingQuery) {
+			grape.ExtractFromErr(
+				ctx, w, errs.BadRequest(errs.WithMsg("Invalid dry_run param")),
+			)
+			return
+		}
+		dryRun = false
+	}
 	v := validator.New()
 	v.Check(
 		"bucket",
@@ -47,9 +57,29 @@ func (h *Handler) DeleteBucketHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if recursive {
+		prefixes := []string{""}
+		if dryRun {
+			preview, err := h.service.PreviewDelete(ctx, bucketName, prefixes)
+			if err != nil {
+				writeError(ctx, w, fmt.Errorf("previewing deletion: %w", err))
+				return
+			}
+			grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: preview}))
+			return
+		}
+		err = h.service.ConfirmDelete(
+			bucketName, prefixes, r.URL.Query().Get("confirm"),
+		)
+		if err != nil {
+			writeError(ctx, w, fmt.Errorf("confirming deletion: %w", err))
+			return
+		}
+	}
+
 	err = h.service.DeleteBucket(ctx, bucketName, recursive)
 	if err != nil {
-		grape.ExtractFromErr(ctx, w, fmt.Errorf("removing bucket: %w", err))
+		writeError(ctx, w, fmt.Errorf("removing bucket: %w", err))
 		return
 	}
 
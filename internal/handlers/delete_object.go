@@ -9,6 +9,7 @@ import (
 	"github.com/hossein1376/grape"
 	"github.com/hossein1376/grape/errs"
 	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
 )
 
 func (h *Handler) DeleteObjectHandle(w http.ResponseWriter, r *http.Request) {
@@ -26,6 +27,26 @@ func (h *Handler) DeleteObjectHandle(w http.ResponseWriter, r *http.Request) {
 		}
 		recursive = false
 	}
+	dryRun, err := grape.Query(r.URL.Query(), "dry_run", strconv.ParseBool)
+	if err != nil {
+		if !errors.Is(err, grape.ErrMissingQuery) {
+			grape.ExtractFromErr(
+				ctx, w, errs.BadRequest(errs.WithMsg("Invalid dry_run param")),
+			)
+			return
+		}
+		dryRun = false
+	}
+	permanent, err := grape.Query(r.URL.Query(), "permanent", strconv.ParseBool)
+	if err != nil {
+		if !errors.Is(err, grape.ErrMissingQuery) {
+			grape.ExtractFromErr(
+				ctx, w, errs.BadRequest(errs.WithMsg("Invalid permanent param")),
+			)
+			return
+		}
+		permanent = false
+	}
 	v := validator.New()
 	v.Check(
 		"bucket",
@@ -56,9 +77,34 @@ func (h *Handler) DeleteObjectHandle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.DeleteObject(ctx, bucketName, objectName, recursive)
+	if recursive {
+		prefixes := []string{objectName}
+		if dryRun {
+			preview, err := h.service.PreviewDelete(ctx, bucketName, prefixes)
+			if err != nil {
+				writeError(ctx, w, fmt.Errorf("previewing deletion: %w", err))
+				return
+			}
+			grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: preview}))
+			return
+		}
+		err = h.service.ConfirmDelete(
+			bucketName, prefixes, r.URL.Query().Get("confirm"),
+		)
+		if err != nil {
+			writeError(ctx, w, fmt.Errorf("confirming deletion: %w", err))
+			return
+		}
+	}
+
+	opts := model.DeleteObjectOptions{
+		Recursive: recursive,
+		Permanent: permanent,
+		DeletedBy: requestUser(r),
+	}
+	err = h.service.DeleteObject(ctx, bucketName, objectName, opts)
 	if err != nil {
-		grape.ExtractFromErr(ctx, w, fmt.Errorf("removing object: %w", err))
+		writeError(ctx, w, fmt.Errorf("removing object: %w", err))
 		return
 	}
 
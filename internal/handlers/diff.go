@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const defaultDiffMaxSize = 5 * 1024 * 1024
+
+// DiffObjectsHandler compares the objects named by the left and right query
+// params, each given as bucket/key. They can be in different buckets.
+func (h *Handler) DiffObjectsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	left, leftOK := parseObjectRef(query.Get("left"))
+	right, rightOK := parseObjectRef(query.Get("right"))
+	v := validator.New()
+	v.Check("left", validator.Case{
+		Cond: leftOK, Msg: "left must be given as bucket/key",
+	})
+	v.Check("right", validator.Case{
+		Cond: rightOK, Msg: "right must be given as bucket/key",
+	})
+	if ok := v.Validate(); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+	maxSize := h.cfg.Diff.MaxSizeBytes
+	if maxSize <= 0 {
+		maxSize = defaultDiffMaxSize
+	}
+
+	diff, err := h.service.DiffObjects(ctx, left, right, maxSize)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("comparing objects: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: diff}))
+}
+
+// parseObjectRef splits bucket/key at the first slash, since bucket names
+// can't have one.
+func parseObjectRef(s string) (model.ObjectRef, bool) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(s, "/"), "/")
+	if !ok || len(bucket) < 3 || key == "" || strings.HasSuffix(key, "/") {
+		return model.ObjectRef{}, false
+	}
+	return model.ObjectRef{Bucket: bucket, Key: key}, true
+}
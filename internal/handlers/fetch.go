@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+// FetchObjectHandler queues a fetch job that downloads a URL into the bucket.
+// Its progress is followed like any other job.
+func (h *Handler) FetchObjectHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req, err := grape.ReadJSON[FetchObjectRequest](w, r)
+	if err != nil {
+		grape.ExtractFromErr(ctx, w, errs.BadRequest(errs.WithMsg(err.Error())))
+		return
+	}
+	spec := model.JobSpec{
+		Type:         model.JobFetch,
+		Bucket:       r.PathValue("bucket"),
+		URL:          req.URL,
+		Key:          req.Key,
+		StorageClass: req.StorageClass,
+	}
+	if err = SubmitJobRequest(spec).Validate(); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	job, err := h.service.SubmitJob(ctx, spec)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("submitting fetch job: %w", err))
+		return
+	}
+
+	grape.WriteJSON(
+		ctx, w,
+		grape.WithStatus(http.StatusAccepted),
+		grape.WithData(grape.Response{Data: job}),
+	)
+}
+
+// FetchObjectRequest names the URL to download, the key to put it at and,
+// optionally, its storage class.
+type FetchObjectRequest struct {
+	URL          string `json:"url"`
+	Key          string `json:"key"`
+	StorageClass string `json:"storage_class,omitempty"`
+}
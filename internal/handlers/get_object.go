@@ -7,6 +7,7 @@ import (
 	"path"
 
 	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/slogger"
 	"github.com/hossein1376/grape/validator"
 )
 
@@ -45,7 +46,7 @@ func (h *Handler) GetObjectHandler(w http.ResponseWriter, r *http.Request) {
 
 	object, ct, err := h.service.GetObject(ctx, bucketName, objectName)
 	if err != nil {
-		grape.ExtractFromErr(ctx, w, fmt.Errorf("getting object: %w", err))
+		writeError(ctx, w, fmt.Errorf("getting object: %w", err))
 		return
 	}
 	defer object.Close()
@@ -60,10 +61,17 @@ func (h *Handler) GetObjectHandler(w http.ResponseWriter, r *http.Request) {
 	)
 	w.Header().Set("Content-Type", contentType)
 
-	_, err = io.Copy(w, object)
-	if err != nil {
-		grape.ExtractFromErr(ctx, w, fmt.Errorf("copying object: %w", err))
-		return
+	n, err := io.Copy(w, object)
+	switch {
+	case err == nil:
+		// continue
+	case n == 0:
+		writeError(ctx, w, fmt.Errorf("copying object: %w", err))
+	default:
+		// The status is already out, so the response is aborted for the
+		// client not to take a corrupted or partial download as complete.
+		// Unlike hijacking the connection, this works over HTTP/2 as well.
+		slogger.Error(ctx, "copying object", slogger.Err("error", err))
+		panic(http.ErrAbortHandler)
 	}
-
 }
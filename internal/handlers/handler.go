@@ -2,35 +2,77 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/slogger"
+	"github.com/hossein1376/grape/validator"
 	"github.com/hossein1376/s3manager/internal/config"
+	"github.com/hossein1376/s3manager/internal/metrics"
 	"github.com/hossein1376/s3manager/internal/model"
 	"github.com/hossein1376/s3manager/ui"
 )
 
 type Service interface {
 	ListObjects(ctx context.Context, bucketName string, maxKeys int32, opt model.ListObjectsOption) ([]model.Object, *string, error)
+	DiskUsage(ctx context.Context, bucketName, path string) ([]model.Object, bool, error)
 	ListBuckets(ctx context.Context, count int32, opts model.ListBucketsOptions) ([]model.Bucket, *string, error)
-	CreateBucket(ctx context.Context, name string) error
+	CreateBucket(ctx context.Context, name string, opts model.CreateBucketOptions) error
 	DeleteBucket(ctx context.Context, name string, recursive bool) error
-	PutObject(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader) (*model.Object, error)
-	DeleteObject(ctx context.Context, bucketName, objectKey string, recursive bool) error
+	PutObject(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader, opts model.PutObjectOptions) (*model.Object, error)
+	CreateFolder(ctx context.Context, bucketName, path string) (*model.Object, error)
+	GetObjectContent(ctx context.Context, bucketName, objectKey string, maxSize int64) (*model.ObjectContent, error)
+	UpdateObject(ctx context.Context, bucketName, objectKey, mimeType, etag string, r io.Reader) (*model.Object, error)
+	DeleteObject(ctx context.Context, bucketName, objectKey string, opts model.DeleteObjectOptions) error
+	DeleteObjects(ctx context.Context, bucketName string, keys, prefixes []string, opts model.DeleteObjectsOptions) ([]model.DeleteResult, error)
+	PreviewDelete(ctx context.Context, bucketName string, prefixes []string) (*model.DeletePreview, error)
+	ConfirmDelete(bucketName string, prefixes []string, token string) error
+	SubmitJob(ctx context.Context, spec model.JobSpec) (*model.Job, error)
+	ListJobs(ctx context.Context) ([]model.Job, error)
+	GetJob(ctx context.Context, id string) (*model.Job, error)
+	CancelJob(ctx context.Context, id string) error
+	SyncDiff(ctx context.Context, spec model.JobSpec) (*model.SyncDiff, error)
 	GetObject(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, *string, error)
+	Thumbnail(ctx context.Context, bucketName, objectKey string, size int) (*model.Thumbnail, error)
+	DiffObjects(ctx context.Context, left, right model.ObjectRef, maxSize int64) (*model.ObjectDiff, error)
+	ObjectChecksum(ctx context.Context, bucketName, objectKey, algorithm string, compute bool) (*model.ObjectChecksum, error)
+	RestoreObject(ctx context.Context, bucketName, objectKey string, opts model.RestoreObjectOptions) error
+	ListTrash(ctx context.Context, bucketName string, count int32, token *string) ([]model.TrashItem, *string, error)
+	RestoreTrash(ctx context.Context, bucketName, id string) error
+	PurgeTrash(ctx context.Context, bucketName, id string) error
+	EmptyTrash(ctx context.Context, bucketName string) error
+	GetBucketObjectLock(ctx context.Context, bucketName string) (*model.ObjectLockConfig, error)
+	PutBucketObjectLock(ctx context.Context, bucketName string, cfg model.ObjectLockConfig) error
+	GetObjectRetention(ctx context.Context, bucketName, objectKey string) (*model.ObjectRetention, error)
+	PutObjectRetention(ctx context.Context, bucketName, objectKey string, retention model.ObjectRetention, bypassGovernance bool) error
+	GetObjectLegalHold(ctx context.Context, bucketName, objectKey string) (*model.LegalHold, error)
+	PutObjectLegalHold(ctx context.Context, bucketName, objectKey string, hold model.LegalHold) error
+	BucketStats(ctx context.Context, bucketName, prefix string, refresh bool) (*model.BucketStats, error)
+	CancelBucketStats(ctx context.Context, bucketName, prefix string) error
+	SearchObjects(ctx context.Context, bucketName string, opts model.SearchOptions, fn func(model.Object) error) (*model.SearchResult, error)
 }
 
 type Handler struct {
 	cfg     config.Config
 	service Service
+	metrics *metrics.Registry
 }
 
+// NewServer serves the API and the UI. Requests are recorded in reg, which is
+// served at /metrics unless metrics have their own address; nil records
+// nothing.
 func NewServer(
-	cfg config.Config, svc Service,
+	cfg config.Config, svc Service, reg *metrics.Registry,
 ) (*http.Server, error) {
-	h := &Handler{cfg: cfg, service: svc}
+	h := &Handler{cfg: cfg, service: svc, metrics: reg}
 
 	uiFS, err := ui.FileSystem()
 	if err != nil {
@@ -47,12 +89,21 @@ func NewServer(
 
 func newRouter(h *Handler, ui http.FileSystem, disableUI bool) *grape.Router {
 	r := grape.NewRouter()
-	r.UseAll(
+	middlewares := []func(http.Handler) http.Handler{
 		grape.RequestIDMiddleware,
 		grape.LoggerMiddleware,
 		grape.RecoverMiddleware,
 		grape.CORSMiddleware,
-	)
+	}
+	if h.metrics != nil {
+		// Last, so the route the request matched is known once it returns.
+		middlewares = append(middlewares, metrics.NewHTTP(h.metrics).Middleware)
+	}
+	r.UseAll(middlewares...)
+
+	if h.metrics != nil && h.cfg.Metrics.Address == "" {
+		r.Get("/metrics", h.metrics.ServeHTTP)
+	}
 
 	if !disableUI {
 		r.Get("/", toHandlerFunc(http.FileServer(ui)))
@@ -61,9 +112,37 @@ func newRouter(h *Handler, ui http.FileSystem, disableUI bool) *grape.Router {
 	r.Post("/api/buckets", h.CreateBucketHandler)
 	r.Get("/api/buckets/{bucket}", h.ListObjectsHandler)
 	r.Delete("/api/buckets/{bucket}", h.DeleteBucketHandler)
+	r.Get("/api/buckets/{bucket}/lock", h.GetBucketObjectLockHandler)
+	r.Put("/api/buckets/{bucket}/lock", h.PutBucketObjectLockHandler)
+	r.Get("/api/buckets/{bucket}/stats", h.GetBucketStatsHandler)
+	r.Delete("/api/buckets/{bucket}/stats", h.CancelBucketStatsHandler)
+	r.Get("/api/buckets/{bucket}/search", h.SearchObjectsHandler)
+	r.Post("/api/buckets/{bucket}/delete", h.BulkDeleteHandler)
+	r.Get("/api/buckets/{bucket}/sync", h.SyncDiffHandler)
+	r.Get("/api/buckets/{bucket}/trash", h.ListTrashHandler)
+	r.Delete("/api/buckets/{bucket}/trash", h.EmptyTrashHandler)
+	r.Post("/api/buckets/{bucket}/trash/{id}/restore", h.RestoreTrashHandler)
+	r.Delete("/api/buckets/{bucket}/trash/{id}", h.PurgeTrashHandler)
 	r.Put("/api/buckets/{bucket}/objects", h.PutObjectHandler)
+	r.Post("/api/buckets/{bucket}/upload", h.UploadObjectsHandler)
+	r.Post("/api/buckets/{bucket}/fetch", h.FetchObjectHandler)
+	r.Post("/api/buckets/{bucket}/folders", h.CreateFolderHandler)
 	r.Get("/api/buckets/{bucket}/objects/{object}", h.GetObjectHandler)
+	r.Put("/api/buckets/{bucket}/objects/{object}", h.PutObjectContentHandler)
+	r.Get("/api/buckets/{bucket}/objects/{object}/content", h.GetObjectContentHandler)
+	r.Get("/api/buckets/{bucket}/objects/{object}/thumbnail", h.GetThumbnailHandler)
+	r.Get("/api/buckets/{bucket}/objects/{object}/checksum", h.GetObjectChecksumHandler)
+	r.Post("/api/buckets/{bucket}/objects/{object}/restore", h.RestoreObjectHandler)
 	r.Delete("/api/buckets/{bucket}/objects/{object}", h.DeleteObjectHandle)
+	r.Get("/api/buckets/{bucket}/objects/{object}/retention", h.GetObjectRetentionHandler)
+	r.Put("/api/buckets/{bucket}/objects/{object}/retention", h.PutObjectRetentionHandler)
+	r.Get("/api/buckets/{bucket}/objects/{object}/legal-hold", h.GetObjectLegalHoldHandler)
+	r.Put("/api/buckets/{bucket}/objects/{object}/legal-hold", h.PutObjectLegalHoldHandler)
+	r.Get("/api/diff", h.DiffObjectsHandler)
+	r.Get("/api/jobs", h.ListJobsHandler)
+	r.Post("/api/jobs", h.SubmitJobHandler)
+	r.Get("/api/jobs/{id}", h.GetJobHandler)
+	r.Delete("/api/jobs/{id}", h.CancelJobHandler)
 
 	return r
 }
@@ -73,3 +152,117 @@ func toHandlerFunc(h http.Handler) http.HandlerFunc {
 		h.ServeHTTP(w, r)
 	}
 }
+
+func validateBucket(bucketName string) (*validator.Validator, bool) {
+	v := validator.New()
+	v.Check(
+		"bucket",
+		validator.Case{
+			Cond: !validator.Empty(bucketName), Msg: "bucket name is required",
+		},
+		validator.Case{
+			Cond: validator.LengthMin(bucketName, 3),
+			Msg:  "Bucket name cannot be shorter than 3 characters",
+		},
+		validator.Case{
+			Cond: !validator.Contains(bucketName, "/"),
+			Msg:  "Bucket name cannot contain invalid characters",
+		},
+	)
+	return v, v.Validate()
+}
+
+func validateObject(bucketName, objectName string) (*validator.Validator, bool) {
+	v, _ := validateBucket(bucketName)
+	v.Check(
+		"key",
+		validator.Case{
+			Cond: !validator.Empty(objectName), Msg: "object name is required",
+		},
+	)
+	return v, v.Validate()
+}
+
+// querySize parses a non-negative byte size query param, recording any
+// problem on v. It returns nil when the param is missing or invalid.
+func querySize(query url.Values, key string, v *validator.Validator) *int64 {
+	size, err := grape.Query(query, key, grape.ParseInt[int64]())
+	switch {
+	case err == nil:
+		v.Check(key, validator.Case{
+			Cond: size >= 0, Msg: "Size cannot be negative",
+		})
+		return &size
+	case !errors.Is(err, grape.ErrMissingQuery):
+		v.Check(key, validator.Case{Cond: false, Msg: "Invalid size"})
+	}
+	return nil
+}
+
+// queryTime parses an RFC 3339 timestamp query param, recording any problem
+// on v. It returns nil when the param is missing or invalid.
+func queryTime(query url.Values, key string, v *validator.Validator) *time.Time {
+	raw := query.Get(key)
+	if raw == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	v.Check(key, validator.Case{
+		Cond: err == nil, Msg: "Date must be in RFC 3339 format",
+	})
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// checkSizeRange makes sure the minimum size doesn't exceed the maximum.
+func checkSizeRange(v *validator.Validator, minSize, maxSize *int64) {
+	if minSize != nil && maxSize != nil {
+		v.Check("max_size", validator.Case{
+			Cond: *minSize <= *maxSize,
+			Msg:  "Maximum size cannot be less than the minimum size",
+		})
+	}
+}
+
+// checkStorageClass makes sure class, when set, is one objects can be stored
+// in.
+func checkStorageClass(v *validator.Validator, class string) {
+	v.Check("storage_class", validator.Case{
+		Cond: class == "" || slices.Contains(model.StorageClasses, class),
+		Msg:  "Storage class must be one of " + strings.Join(model.StorageClasses, ", "),
+	})
+}
+
+// writeError responds with err. Failed S3 requests carry what S3 answered,
+// so they can be looked up on its side; other errors are left to grape.
+func writeError(ctx context.Context, w http.ResponseWriter, err error) {
+	var s3Err *model.S3Error
+	if !errors.As(err, &s3Err) {
+		grape.ExtractFromErr(ctx, w, err)
+		return
+	}
+	if s3Err.Status >= http.StatusInternalServerError {
+		slogger.Error(ctx, "s3 request failed", slogger.Err("error", err))
+	}
+	resp := grape.Response{Message: s3Err.Message, Data: s3Err}
+	grape.WriteJSON(
+		ctx, w, grape.WithStatus(s3Err.Status), grape.WithData(resp),
+	)
+}
+
+// requestUser names who sent the request, as told by an authenticating proxy
+// in front of the server, or else by the client's address.
+func requestUser(r *http.Request) string {
+	for _, header := range []string{"X-Forwarded-User", "X-Remote-User"} {
+		if user := r.Header.Get(header); user != "" {
+			return user
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
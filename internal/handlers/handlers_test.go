@@ -5,16 +5,25 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"path"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/hossein1376/grape/errs"
 	"github.com/hossein1376/s3manager/internal/config"
+	"github.com/hossein1376/s3manager/internal/metrics"
 	"github.com/hossein1376/s3manager/internal/model"
 	"github.com/stretchr/testify/assert"
 )
@@ -30,11 +39,41 @@ func setupHandler(svc *mockService) *Handler {
 type mockService struct {
 	listBucketsFunc  func(ctx context.Context, count int32, opts model.ListBucketsOptions) ([]model.Bucket, *string, error)
 	listObjectsFunc  func(ctx context.Context, bucketName string, maxKeys int32, opt model.ListObjectsOption) ([]model.Object, *string, error)
-	createBucketFunc func(ctx context.Context, name string) error
+	diskUsageFunc    func(ctx context.Context, bucketName, path string) ([]model.Object, bool, error)
+	createBucketFunc func(ctx context.Context, name string, opts model.CreateBucketOptions) error
 	deleteBucketFunc func(ctx context.Context, name string, recursive bool) error
-	putObjectFunc    func(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader) (*model.Object, error)
-	deleteObjectFunc func(ctx context.Context, bucketName, objectKey string, recursive bool) error
+	putObjectFunc    func(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader, opts model.PutObjectOptions) (*model.Object, error)
+	createFolderFunc func(ctx context.Context, bucketName, path string) (*model.Object, error)
+	getContentFunc   func(ctx context.Context, bucketName, objectKey string, maxSize int64) (*model.ObjectContent, error)
+	updateObjectFunc func(ctx context.Context, bucketName, objectKey, mimeType, etag string, r io.Reader) (*model.Object, error)
+	deleteObjectFunc func(ctx context.Context, bucketName, objectKey string, opts model.DeleteObjectOptions) error
 	getObjectFunc    func(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, *string, error)
+	thumbnailFunc    func(ctx context.Context, bucketName, objectKey string, size int) (*model.Thumbnail, error)
+	diffObjectsFunc  func(ctx context.Context, left, right model.ObjectRef, maxSize int64) (*model.ObjectDiff, error)
+	checksumFunc     func(ctx context.Context, bucketName, objectKey, algorithm string, compute bool) (*model.ObjectChecksum, error)
+	restoreFunc      func(ctx context.Context, bucketName, objectKey string, opts model.RestoreObjectOptions) error
+
+	getBucketObjectLockFunc func(ctx context.Context, bucketName string) (*model.ObjectLockConfig, error)
+	putBucketObjectLockFunc func(ctx context.Context, bucketName string, cfg model.ObjectLockConfig) error
+	getObjectRetentionFunc  func(ctx context.Context, bucketName, objectKey string) (*model.ObjectRetention, error)
+	putObjectRetentionFunc  func(ctx context.Context, bucketName, objectKey string, retention model.ObjectRetention, bypassGovernance bool) error
+	getObjectLegalHoldFunc  func(ctx context.Context, bucketName, objectKey string) (*model.LegalHold, error)
+	putObjectLegalHoldFunc  func(ctx context.Context, bucketName, objectKey string, hold model.LegalHold) error
+	bucketStatsFunc         func(ctx context.Context, bucketName, prefix string, refresh bool) (*model.BucketStats, error)
+	cancelBucketStatsFunc   func(ctx context.Context, bucketName, prefix string) error
+	deleteObjectsFunc       func(ctx context.Context, bucketName string, keys, prefixes []string, opts model.DeleteObjectsOptions) ([]model.DeleteResult, error)
+	previewDeleteFunc       func(ctx context.Context, bucketName string, prefixes []string) (*model.DeletePreview, error)
+	confirmDeleteFunc       func(bucketName string, prefixes []string, token string) error
+	listTrashFunc           func(ctx context.Context, bucketName string, count int32, token *string) ([]model.TrashItem, *string, error)
+	restoreTrashFunc        func(ctx context.Context, bucketName, id string) error
+	purgeTrashFunc          func(ctx context.Context, bucketName, id string) error
+	emptyTrashFunc          func(ctx context.Context, bucketName string) error
+	submitJobFunc           func(ctx context.Context, spec model.JobSpec) (*model.Job, error)
+	listJobsFunc            func(ctx context.Context) ([]model.Job, error)
+	getJobFunc              func(ctx context.Context, id string) (*model.Job, error)
+	cancelJobFunc           func(ctx context.Context, id string) error
+	syncDiffFunc            func(ctx context.Context, spec model.JobSpec) (*model.SyncDiff, error)
+	searchObjectsFunc       func(ctx context.Context, bucketName string, opts model.SearchOptions, fn func(model.Object) error) (*model.SearchResult, error)
 }
 
 func (m *mockService) ListBuckets(ctx context.Context, count int32, opts model.ListBucketsOptions) ([]model.Bucket, *string, error) {
@@ -45,32 +84,148 @@ func (m *mockService) ListObjects(ctx context.Context, bucketName string, maxKey
 	return m.listObjectsFunc(ctx, bucketName, maxKeys, opt)
 }
 
-func (m *mockService) CreateBucket(ctx context.Context, name string) error {
-	return m.createBucketFunc(ctx, name)
+func (m *mockService) DiskUsage(ctx context.Context, bucketName, path string) ([]model.Object, bool, error) {
+	return m.diskUsageFunc(ctx, bucketName, path)
+}
+
+func (m *mockService) CreateBucket(ctx context.Context, name string, opts model.CreateBucketOptions) error {
+	return m.createBucketFunc(ctx, name, opts)
 }
 
 func (m *mockService) DeleteBucket(ctx context.Context, name string, recursive bool) error {
 	return m.deleteBucketFunc(ctx, name, recursive)
 }
 
-func (m *mockService) PutObject(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader) (*model.Object, error) {
-	return m.putObjectFunc(ctx, bucketName, objectKey, mimeType, r)
+func (m *mockService) PutObject(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader, opts model.PutObjectOptions) (*model.Object, error) {
+	return m.putObjectFunc(ctx, bucketName, objectKey, mimeType, r, opts)
+}
+
+func (m *mockService) CreateFolder(ctx context.Context, bucketName, path string) (*model.Object, error) {
+	return m.createFolderFunc(ctx, bucketName, path)
+}
+
+func (m *mockService) GetObjectContent(ctx context.Context, bucketName, objectKey string, maxSize int64) (*model.ObjectContent, error) {
+	return m.getContentFunc(ctx, bucketName, objectKey, maxSize)
+}
+
+func (m *mockService) UpdateObject(ctx context.Context, bucketName, objectKey, mimeType, etag string, r io.Reader) (*model.Object, error) {
+	return m.updateObjectFunc(ctx, bucketName, objectKey, mimeType, etag, r)
 }
 
-func (m *mockService) DeleteObject(ctx context.Context, bucketName, objectKey string, recursive bool) error {
-	return m.deleteObjectFunc(ctx, bucketName, objectKey, recursive)
+func (m *mockService) DeleteObject(ctx context.Context, bucketName, objectKey string, opts model.DeleteObjectOptions) error {
+	return m.deleteObjectFunc(ctx, bucketName, objectKey, opts)
 }
 
 func (m *mockService) GetObject(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, *string, error) {
 	return m.getObjectFunc(ctx, bucketName, objectKey)
 }
 
+func (m *mockService) Thumbnail(ctx context.Context, bucketName, objectKey string, size int) (*model.Thumbnail, error) {
+	return m.thumbnailFunc(ctx, bucketName, objectKey, size)
+}
+
+func (m *mockService) DiffObjects(ctx context.Context, left, right model.ObjectRef, maxSize int64) (*model.ObjectDiff, error) {
+	return m.diffObjectsFunc(ctx, left, right, maxSize)
+}
+
+func (m *mockService) ObjectChecksum(ctx context.Context, bucketName, objectKey, algorithm string, compute bool) (*model.ObjectChecksum, error) {
+	return m.checksumFunc(ctx, bucketName, objectKey, algorithm, compute)
+}
+
+func (m *mockService) RestoreObject(ctx context.Context, bucketName, objectKey string, opts model.RestoreObjectOptions) error {
+	return m.restoreFunc(ctx, bucketName, objectKey, opts)
+}
+
+func (m *mockService) GetBucketObjectLock(ctx context.Context, bucketName string) (*model.ObjectLockConfig, error) {
+	return m.getBucketObjectLockFunc(ctx, bucketName)
+}
+
+func (m *mockService) PutBucketObjectLock(ctx context.Context, bucketName string, cfg model.ObjectLockConfig) error {
+	return m.putBucketObjectLockFunc(ctx, bucketName, cfg)
+}
+
+func (m *mockService) GetObjectRetention(ctx context.Context, bucketName, objectKey string) (*model.ObjectRetention, error) {
+	return m.getObjectRetentionFunc(ctx, bucketName, objectKey)
+}
+
+func (m *mockService) PutObjectRetention(ctx context.Context, bucketName, objectKey string, retention model.ObjectRetention, bypassGovernance bool) error {
+	return m.putObjectRetentionFunc(ctx, bucketName, objectKey, retention, bypassGovernance)
+}
+
+func (m *mockService) GetObjectLegalHold(ctx context.Context, bucketName, objectKey string) (*model.LegalHold, error) {
+	return m.getObjectLegalHoldFunc(ctx, bucketName, objectKey)
+}
+
+func (m *mockService) PutObjectLegalHold(ctx context.Context, bucketName, objectKey string, hold model.LegalHold) error {
+	return m.putObjectLegalHoldFunc(ctx, bucketName, objectKey, hold)
+}
+
+func (m *mockService) BucketStats(ctx context.Context, bucketName, prefix string, refresh bool) (*model.BucketStats, error) {
+	return m.bucketStatsFunc(ctx, bucketName, prefix, refresh)
+}
+
+func (m *mockService) CancelBucketStats(ctx context.Context, bucketName, prefix string) error {
+	return m.cancelBucketStatsFunc(ctx, bucketName, prefix)
+}
+
+func (m *mockService) DeleteObjects(ctx context.Context, bucketName string, keys, prefixes []string, opts model.DeleteObjectsOptions) ([]model.DeleteResult, error) {
+	return m.deleteObjectsFunc(ctx, bucketName, keys, prefixes, opts)
+}
+
+func (m *mockService) PreviewDelete(ctx context.Context, bucketName string, prefixes []string) (*model.DeletePreview, error) {
+	return m.previewDeleteFunc(ctx, bucketName, prefixes)
+}
+
+func (m *mockService) ConfirmDelete(bucketName string, prefixes []string, token string) error {
+	return m.confirmDeleteFunc(bucketName, prefixes, token)
+}
+
+func (m *mockService) ListTrash(ctx context.Context, bucketName string, count int32, token *string) ([]model.TrashItem, *string, error) {
+	return m.listTrashFunc(ctx, bucketName, count, token)
+}
+
+func (m *mockService) RestoreTrash(ctx context.Context, bucketName, id string) error {
+	return m.restoreTrashFunc(ctx, bucketName, id)
+}
+
+func (m *mockService) PurgeTrash(ctx context.Context, bucketName, id string) error {
+	return m.purgeTrashFunc(ctx, bucketName, id)
+}
+
+func (m *mockService) EmptyTrash(ctx context.Context, bucketName string) error {
+	return m.emptyTrashFunc(ctx, bucketName)
+}
+
+func (m *mockService) SyncDiff(ctx context.Context, spec model.JobSpec) (*model.SyncDiff, error) {
+	return m.syncDiffFunc(ctx, spec)
+}
+
+func (m *mockService) SubmitJob(ctx context.Context, spec model.JobSpec) (*model.Job, error) {
+	return m.submitJobFunc(ctx, spec)
+}
+
+func (m *mockService) ListJobs(ctx context.Context) ([]model.Job, error) {
+	return m.listJobsFunc(ctx)
+}
+
+func (m *mockService) GetJob(ctx context.Context, id string) (*model.Job, error) {
+	return m.getJobFunc(ctx, id)
+}
+
+func (m *mockService) CancelJob(ctx context.Context, id string) error {
+	return m.cancelJobFunc(ctx, id)
+}
+
+func (m *mockService) SearchObjects(ctx context.Context, bucketName string, opts model.SearchOptions, fn func(model.Object) error) (*model.SearchResult, error) {
+	return m.searchObjectsFunc(ctx, bucketName, opts, fn)
+}
+
 func TestHandler_ListBucketsHandler(t *testing.T) {
 	t.Parallel()
 	a := assert.New(t)
 	mockBuckets := []model.Bucket{
-		{Name: aws.String("bucket-1"), CreatedAt: aws.String("2023-01-01 10:00:00")},
-		{Name: aws.String("bucket-2"), CreatedAt: aws.String("2023-01-02 11:00:00")},
+		{Name: aws.String("bucket-1"), CreatedAt: aws.String("2023-01-01T10:00:00Z")},
+		{Name: aws.String("bucket-2"), CreatedAt: aws.String("2023-01-02T11:00:00Z")},
 	}
 
 	svc := &mockService{
@@ -105,7 +260,7 @@ func TestHandler_CreateBucketHandler(t *testing.T) {
 	t.Parallel()
 	a := assert.New(t)
 	svc := &mockService{
-		createBucketFunc: func(ctx context.Context, name string) error {
+		createBucketFunc: func(ctx context.Context, name string, opts model.CreateBucketOptions) error {
 			if name == "new-bucket" {
 				return nil
 			}
@@ -158,6 +313,79 @@ func TestHandler_ListObjectsHandler(t *testing.T) {
 	a.Len(response.List, 1)
 }
 
+func TestHandler_ListObjectsHandler_Recursive(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		listObjectsFunc: func(ctx context.Context, bucketName string, maxKeys int32, opt model.ListObjectsOption) ([]model.Object, *string, error) {
+			if !opt.Recursive {
+				return nil, nil, errors.New("expected recursive listing")
+			}
+			return []model.Object{{Key: aws.String("nested/file.txt")}}, aws.String("next"), nil
+		},
+	}
+
+	h := setupHandler(svc)
+	r := newRouter(h, nil, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket?recursive=true", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+
+	var response struct {
+		List      []model.Object `json:"list"`
+		NextToken *string        `json:"next_token"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&response))
+	a.Equal("nested/file.txt", *response.List[0].Key)
+	a.Equal("next", *response.NextToken)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket?recursive=yes", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_ListObjectsHandler_SortAndFilters(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		listObjectsFunc: func(ctx context.Context, bucketName string, maxKeys int32, opt model.ListObjectsOption) ([]model.Object, *string, error) {
+			a.Equal(model.SortBySize, opt.SortBy)
+			a.True(opt.Descending)
+			a.Equal(int64(1024), *opt.MinSize)
+			a.Equal([]string{"csv", "parquet"}, opt.Extensions)
+			a.Equal([]string{"GLACIER"}, opt.StorageClasses)
+			return []model.Object{{Key: aws.String("big.csv")}}, nil, nil
+		},
+	}
+
+	h := setupHandler(svc)
+	r := newRouter(h, nil, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket?sort=size&order=desc&min_size=1024&ext=.CSV,parquet&storage_class=glacier", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+
+	for _, query := range []string{
+		"sort=color",
+		"order=up",
+		"min_size=-1",
+		"min_size=10&max_size=1",
+		"modified_before=2024-01-01",
+		"sort=size&count=0",
+		"sort=size&count=-1",
+		"count=1001",
+	} {
+		req = httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket?"+query, nil)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		a.Equal(http.StatusBadRequest, w.Result().StatusCode, query)
+	}
+}
+
 func TestHandler_DeleteBucketHandler(t *testing.T) {
 	t.Parallel()
 	a := assert.New(t)
@@ -186,7 +414,8 @@ func TestHandler_PutObjectHandler(t *testing.T) {
 	t.Parallel()
 	a := assert.New(t)
 	svc := &mockService{
-		putObjectFunc: func(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader) (*model.Object, error) {
+		putObjectFunc: func(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader, opts model.PutObjectOptions) (*model.Object, error) {
+			a.Equal("STANDARD_IA", opts.StorageClass)
 			return &model.Object{Key: &objectKey}, nil
 		},
 	}
@@ -205,6 +434,8 @@ func TestHandler_PutObjectHandler(t *testing.T) {
 	a.NoError(err)
 	err = writer.WriteField("key", "test.txt")
 	a.NoError(err)
+	err = writer.WriteField("storage_class", "STANDARD_IA")
+	a.NoError(err)
 	err = writer.Close()
 	a.NoError(err)
 
@@ -244,11 +475,85 @@ func TestHandler_GetObjectHandler(t *testing.T) {
 	a.Equal(mockContent, string(body))
 }
 
+func TestHandler_GetObjectHandler_Abort(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		getObjectFunc: func(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, *string, error) {
+			// Large enough for the status and part of the body to be sent
+			// before the download fails.
+			body := io.MultiReader(
+				strings.NewReader(strings.Repeat("a", 64*1024)),
+				iotest.ErrReader(errors.New("checksum did not match")),
+			)
+			return io.NopCloser(body), nil, nil
+		},
+	}
+
+	// The download is cut short on both protocols, instead of ending as if
+	// it were complete.
+	for _, http2 := range []bool{false, true} {
+		srv := httptest.NewUnstartedServer(newRouter(setupHandler(svc), nil, true))
+		srv.EnableHTTP2 = http2
+		srv.StartTLS()
+		res, err := srv.Client().Get(srv.URL + "/api/buckets/test-bucket/objects/test.txt")
+		if a.NoError(err) {
+			a.Equal(http2, res.ProtoMajor == 2)
+			_, err = io.ReadAll(res.Body)
+			a.Error(err)
+			_ = res.Body.Close()
+		}
+		srv.Close()
+	}
+}
+
+func TestHandler_S3Error(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		getObjectFunc: func(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, *string, error) {
+			return nil, nil, &model.S3Error{
+				Status:    http.StatusNotFound,
+				Message:   "object not found",
+				Code:      "NoSuchKey",
+				S3Message: "The specified key does not exist.",
+				S3Status:  http.StatusNotFound,
+				RequestID: "4442587FB7D0A2F9",
+				HostID:    "ef8yU9AS1ed4OpIszj7UDNEHGran",
+			}
+		},
+	}
+
+	h := setupHandler(svc)
+	r := newRouter(h, nil, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/objects/missing.txt", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	res := w.Result()
+	a.Equal(http.StatusNotFound, res.StatusCode)
+	var response struct {
+		Message string         `json:"message"`
+		Data    map[string]any `json:"data"`
+	}
+	a.NoError(json.NewDecoder(res.Body).Decode(&response))
+	a.Equal("object not found", response.Message)
+	a.Equal(map[string]any{
+		"code":       "NoSuchKey",
+		"message":    "The specified key does not exist.",
+		"status":     float64(http.StatusNotFound),
+		"request_id": "4442587FB7D0A2F9",
+		"host_id":    "ef8yU9AS1ed4OpIszj7UDNEHGran",
+	}, response.Data)
+}
+
 func TestHandler_DeleteObjectHandler(t *testing.T) {
 	t.Parallel()
 	a := assert.New(t)
 	svc := &mockService{
-		deleteObjectFunc: func(ctx context.Context, bucketName, objectKey string, recursive bool) error {
+		deleteObjectFunc: func(ctx context.Context, bucketName, objectKey string, opts model.DeleteObjectOptions) error {
 			return nil
 		},
 	}
@@ -265,6 +570,153 @@ func TestHandler_DeleteObjectHandler(t *testing.T) {
 	a.Equal(http.StatusNoContent, res.StatusCode)
 }
 
+func TestHandler_DeleteObjectHandler_DryRun(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var deleted bool
+	svc := &mockService{
+		previewDeleteFunc: func(ctx context.Context, bucketName string, prefixes []string) (*model.DeletePreview, error) {
+			a.Equal([]string{"logs/"}, prefixes)
+			return &model.DeletePreview{
+				Bucket:   bucketName,
+				Prefixes: prefixes,
+				Objects:  2,
+				Bytes:    30,
+				Sample:   []string{"logs/a", "logs/b"},
+				Token:    "token",
+			}, nil
+		},
+		confirmDeleteFunc: func(bucketName string, prefixes []string, token string) error {
+			if token != "token" {
+				return errs.New(http.StatusPreconditionRequired, errs.WithMsg("confirm"))
+			}
+			return nil
+		},
+		deleteObjectFunc: func(ctx context.Context, bucketName, objectKey string, opts model.DeleteObjectOptions) error {
+			deleted = true
+			return nil
+		},
+	}
+	r := newRouter(setupHandler(svc), nil, true)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/buckets/test-bucket/objects/logs%2F?recursive=true&dry_run=true", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+	var body struct {
+		Data model.DeletePreview `json:"data"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&body))
+	a.Equal(int64(2), body.Data.Objects)
+	a.Equal("token", body.Data.Token)
+	a.False(deleted)
+
+	// Without the token, nothing is deleted.
+	req = httptest.NewRequest(http.MethodDelete, "/api/buckets/test-bucket/objects/logs%2F?recursive=true", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusPreconditionRequired, w.Result().StatusCode)
+	a.False(deleted)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/buckets/test-bucket/objects/logs%2F?recursive=true&confirm=token", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusNoContent, w.Result().StatusCode)
+	a.True(deleted)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/buckets/test-bucket/objects/logs%2F?dry_run=maybe", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_DeleteObjectHandler_Trash(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var got model.DeleteObjectOptions
+	svc := &mockService{
+		deleteObjectFunc: func(ctx context.Context, bucketName, objectKey string, opts model.DeleteObjectOptions) error {
+			got = opts
+			return nil
+		},
+	}
+	r := newRouter(setupHandler(svc), nil, true)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/buckets/test-bucket/objects/a.txt", nil)
+	req.Header.Set("X-Forwarded-User", "alice")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusNoContent, w.Result().StatusCode)
+	a.Equal(model.DeleteObjectOptions{DeletedBy: "alice"}, got)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/buckets/test-bucket/objects/a.txt?permanent=true", nil)
+	req.RemoteAddr = "10.0.0.7:52100"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusNoContent, w.Result().StatusCode)
+	a.Equal(model.DeleteObjectOptions{Permanent: true, DeletedBy: "10.0.0.7"}, got)
+}
+
+func TestHandler_TrashHandlers(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var restored, purged string
+	var emptied bool
+	svc := &mockService{
+		listTrashFunc: func(ctx context.Context, bucketName string, count int32, token *string) ([]model.TrashItem, *string, error) {
+			a.Equal(int32(100), count)
+			return []model.TrashItem{{ID: "20240101T000000.000000000Z/a.txt", Key: "a.txt"}}, nil, nil
+		},
+		restoreTrashFunc: func(ctx context.Context, bucketName, id string) error {
+			restored = id
+			return nil
+		},
+		purgeTrashFunc: func(ctx context.Context, bucketName, id string) error {
+			purged = id
+			return nil
+		},
+		emptyTrashFunc: func(ctx context.Context, bucketName string) error {
+			emptied = true
+			return nil
+		},
+	}
+	r := newRouter(setupHandler(svc), nil, true)
+	id := url.PathEscape("20240101T000000.000000000Z/a.txt")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/trash", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+	var body struct {
+		List []model.TrashItem `json:"list"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&body))
+	a.Equal("a.txt", body.List[0].Key)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/trash?count=0", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusBadRequest, w.Result().StatusCode)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/buckets/test-bucket/trash/"+id+"/restore", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusNoContent, w.Result().StatusCode)
+	a.Equal("20240101T000000.000000000Z/a.txt", restored)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/buckets/test-bucket/trash/"+id, nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusNoContent, w.Result().StatusCode)
+	a.Equal("20240101T000000.000000000Z/a.txt", purged)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/buckets/test-bucket/trash", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusNoContent, w.Result().StatusCode)
+	a.True(emptied)
+}
+
 func TestHandler_ListBucketsHandler_InvalidJSON(t *testing.T) {
 	t.Parallel()
 	a := assert.New(t)
@@ -288,7 +740,7 @@ func TestHandler_CreateBucketHandler_InvalidJSON(t *testing.T) {
 	t.Parallel()
 	a := assert.New(t)
 	svc := &mockService{
-		createBucketFunc: func(ctx context.Context, name string) error {
+		createBucketFunc: func(ctx context.Context, name string, opts model.CreateBucketOptions) error {
 			return errors.New("invalid name")
 		},
 	}
@@ -309,7 +761,7 @@ func TestHandler_PutObjectHandler_TooLarge(t *testing.T) {
 	t.Parallel()
 	a := assert.New(t)
 	svc := &mockService{
-		putObjectFunc: func(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader) (*model.Object, error) {
+		putObjectFunc: func(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader, opts model.PutObjectOptions) (*model.Object, error) {
 			return nil, errs.New(http.StatusRequestEntityTooLarge, errs.WithMsg("file too large"))
 		},
 	}
@@ -340,3 +792,894 @@ func TestHandler_PutObjectHandler_TooLarge(t *testing.T) {
 	res := w.Result()
 	a.Equal(http.StatusRequestEntityTooLarge, res.StatusCode)
 }
+
+func TestHandler_UploadObjectsHandler(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var mu sync.Mutex
+	types := map[string]string{}
+	svc := &mockService{
+		putObjectFunc: func(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader, opts model.PutObjectOptions) (*model.Object, error) {
+			if strings.HasSuffix(objectKey, "broken.txt") {
+				return nil, errors.New("upload failed")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			types[objectKey] = mimeType
+			return &model.Object{Key: &objectKey}, nil
+		},
+	}
+	h := &Handler{
+		cfg:     config.Config{S3: config.S3{MaxSizeBytes: 1024 * 1024}},
+		service: svc,
+	}
+	r := newRouter(h, nil, true)
+
+	upload := func(prefix string, files map[string]string) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		a.NoError(writer.WriteField("prefix", prefix))
+		for _, rel := range slices.Sorted(maps.Keys(files)) {
+			a.NoError(writer.WriteField("path", rel))
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", fmt.Sprintf(
+				`form-data; name="file"; filename="%s"`, path.Base(rel),
+			))
+			if ct := files[rel]; ct != "" {
+				header.Set("Content-Type", ct)
+			}
+			part, err := writer.CreatePart(header)
+			a.NoError(err)
+			_, err = part.Write([]byte("<html><body>hi</body></html>"))
+			a.NoError(err)
+		}
+		a.NoError(writer.Close())
+		req := httptest.NewRequest(http.MethodPost, "/api/buckets/test-bucket/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload("site", map[string]string{
+		"dist/index.html":     "application/octet-stream",
+		"dist/css/style.css":  "text/css",
+		"dist/img/broken.txt": "text/plain",
+	})
+	a.Equal(http.StatusOK, w.Code)
+	var response struct {
+		Data uploadObjectsResponse `json:"data"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&response))
+	a.Equal(2, response.Data.Uploaded)
+	a.Equal(1, response.Data.Failed)
+	a.Len(response.Data.Results, 3)
+	a.Equal("site/dist/css/style.css", response.Data.Results[0].Key)
+	a.Equal("site/dist/img/broken.txt", response.Data.Results[1].Key)
+	a.False(response.Data.Results[1].Uploaded)
+	a.NotNil(response.Data.Results[1].Error)
+	a.Equal(map[string]string{
+		// The client's type is kept, unless it's the generic fallback.
+		"site/dist/css/style.css": "text/css",
+		"site/dist/index.html":    "text/html; charset=utf-8",
+	}, types)
+
+	w = upload("", map[string]string{"../escape.txt": "text/plain"})
+	a.Equal(http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_CreateBucketHandler_ObjectLock(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var got model.CreateBucketOptions
+	svc := &mockService{
+		createBucketFunc: func(ctx context.Context, name string, opts model.CreateBucketOptions) error {
+			got = opts
+			return nil
+		},
+	}
+
+	h := setupHandler(svc)
+
+	body := `{"name": "locked-bucket", "object_lock": {"enabled": true, "default_retention": {"mode": "COMPLIANCE", "days": 30}}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/buckets", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.CreateBucketHandler(w, req)
+
+	res := w.Result()
+	a.Equal(http.StatusNoContent, res.StatusCode)
+	a.NotNil(got.ObjectLock)
+	a.True(got.ObjectLock.Enabled)
+	a.Equal(model.RetentionCompliance, got.ObjectLock.DefaultRetention.Mode)
+	a.Equal(int32(30), *got.ObjectLock.DefaultRetention.Days)
+
+	body = `{"name": "locked-bucket", "object_lock": {"enabled": true, "default_retention": {"mode": "FOREVER", "days": 30, "years": 1}}}`
+	req = httptest.NewRequest(http.MethodPost, "/api/buckets", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+
+	h.CreateBucketHandler(w, req)
+
+	a.Equal(http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_ObjectRetentionHandlers(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var gotBypass bool
+	var gotRetention model.ObjectRetention
+	svc := &mockService{
+		getObjectRetentionFunc: func(ctx context.Context, bucketName, objectKey string) (*model.ObjectRetention, error) {
+			return &model.ObjectRetention{
+				Mode:        aws.String(model.RetentionGovernance),
+				RetainUntil: aws.String("2030-01-01T00:00:00Z"),
+			}, nil
+		},
+		putObjectRetentionFunc: func(ctx context.Context, bucketName, objectKey string, retention model.ObjectRetention, bypassGovernance bool) error {
+			gotRetention = retention
+			gotBypass = bypassGovernance
+			return nil
+		},
+		putObjectLegalHoldFunc: func(ctx context.Context, bucketName, objectKey string, hold model.LegalHold) error {
+			if !hold.Enabled {
+				return errors.New("expected legal hold to be enabled")
+			}
+			return nil
+		},
+	}
+
+	h := setupHandler(svc)
+	r := newRouter(h, nil, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/objects/file.txt/retention", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+
+	var response struct {
+		Data model.ObjectRetention `json:"data"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&response))
+	a.Equal(model.RetentionGovernance, *response.Data.Mode)
+
+	body := `{"mode": "GOVERNANCE", "retain_until": "2999-01-01T00:00:00Z"}`
+	req = httptest.NewRequest(http.MethodPut, "/api/buckets/test-bucket/objects/file.txt/retention?bypass_governance=true", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusNoContent, w.Result().StatusCode)
+	a.True(gotBypass)
+	a.Equal("2999-01-01T00:00:00Z", *gotRetention.RetainUntil)
+
+	body = `{"mode": "GOVERNANCE", "retain_until": "2000-01-01T00:00:00Z"}`
+	req = httptest.NewRequest(http.MethodPut, "/api/buckets/test-bucket/objects/file.txt/retention", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusBadRequest, w.Result().StatusCode)
+
+	req = httptest.NewRequest(http.MethodPut, "/api/buckets/test-bucket/objects/file.txt/legal-hold", strings.NewReader(`{"enabled": true}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusNoContent, w.Result().StatusCode)
+}
+
+func TestHandler_CreateBucketHandler_Options(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var got model.CreateBucketOptions
+	svc := &mockService{
+		createBucketFunc: func(ctx context.Context, name string, opts model.CreateBucketOptions) error {
+			got = opts
+			return nil
+		},
+	}
+
+	h := setupHandler(svc)
+
+	body := `{"name": "new-bucket", "region": "eu-west-1", "object_ownership": "ObjectWriter", "versioning": true, "tags": {"team": "data"}, "encryption": {"algorithm": "aws:kms", "kms_key_id": "key"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/buckets", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.CreateBucketHandler(w, req)
+
+	a.Equal(http.StatusNoContent, w.Result().StatusCode)
+	a.Equal("eu-west-1", got.Region)
+	a.Equal("ObjectWriter", got.ObjectOwnership)
+	a.True(got.Versioning)
+	a.Equal("data", got.Tags["team"])
+	a.Equal("key", *got.Encryption.KMSKeyID)
+
+	body = `{"name": "new-bucket", "encryption": {"algorithm": "AES256", "kms_key_id": "key"}}`
+	req = httptest.NewRequest(http.MethodPost, "/api/buckets", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+
+	h.CreateBucketHandler(w, req)
+
+	a.Equal(http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_BucketStatsHandlers(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		bucketStatsFunc: func(ctx context.Context, bucketName, prefix string, refresh bool) (*model.BucketStats, error) {
+			return &model.BucketStats{
+				Bucket:      bucketName,
+				Prefix:      prefix,
+				Status:      model.StatsRunning,
+				ObjectCount: 42,
+			}, nil
+		},
+		cancelBucketStatsFunc: func(ctx context.Context, bucketName, prefix string) error {
+			return errs.NotFound(errs.WithMsg("no statistics are being computed"))
+		},
+	}
+
+	h := setupHandler(svc)
+	r := newRouter(h, nil, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/stats?prefix=logs/&refresh=true", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+
+	var response struct {
+		Data model.BucketStats `json:"data"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&response))
+	a.Equal("logs/", response.Data.Prefix)
+	a.Equal(int64(42), response.Data.ObjectCount)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/stats?refresh=maybe", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusBadRequest, w.Result().StatusCode)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/buckets/test-bucket/stats", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_ListObjectsHandler_DiskUsage(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		diskUsageFunc: func(ctx context.Context, bucketName, path string) ([]model.Object, bool, error) {
+			if path != "artifacts" {
+				return nil, false, errors.New("wrong path")
+			}
+			return []model.Object{
+				{Key: aws.String("builds"), IsDir: true, Size: aws.Int64(2048), ObjectCount: aws.Int64(2)},
+			}, true, nil
+		},
+	}
+
+	h := setupHandler(svc)
+	r := newRouter(h, nil, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket?path=artifacts&du=true", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+
+	var response struct {
+		List      []model.Object `json:"list"`
+		Truncated bool           `json:"truncated"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&response))
+	a.Len(response.List, 1)
+	a.Equal(int64(2), *response.List[0].ObjectCount)
+	a.True(response.Truncated)
+}
+
+func TestHandler_SearchObjectsHandler(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		searchObjectsFunc: func(ctx context.Context, bucketName string, opts model.SearchOptions, fn func(model.Object) error) (*model.SearchResult, error) {
+			if opts.Query == "missing" {
+				return nil, errs.NotFound(errs.WithMsg("bucket not found"))
+			}
+			a.Equal(model.SearchGlob, opts.Mode)
+			a.Equal(int64(10), *opts.MinSize)
+			a.Equal(5, opts.Limit)
+			for _, key := range []string{"a/x.parquet", "b/y.parquet"} {
+				if err := fn(model.Object{Key: aws.String(key)}); err != nil {
+					return nil, err
+				}
+			}
+			return &model.SearchResult{Matched: 2, Scanned: 7}, nil
+		},
+	}
+
+	h := setupHandler(svc)
+	r := newRouter(h, nil, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/search?q=*.parquet&mode=glob&min_size=10&limit=5", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+	a.Equal("application/x-ndjson", w.Result().Header.Get("Content-Type"))
+
+	var events []searchEvent
+	dec := json.NewDecoder(w.Result().Body)
+	for dec.More() {
+		var event searchEvent
+		a.NoError(dec.Decode(&event))
+		events = append(events, event)
+	}
+	a.Len(events, 3)
+	a.Equal("b/y.parquet", *events[1].Object.Key)
+	a.Equal(7, events[2].Result.Scanned)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/search?q=missing", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusNotFound, w.Result().StatusCode)
+
+	for _, query := range []string{
+		"",
+		"q=a&mode=fuzzy",
+		"q=a&limit=0",
+		"q=a&min_size=10&max_size=5",
+		"q=a&modified_after=yesterday",
+	} {
+		req = httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/search?"+query, nil)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		a.Equal(http.StatusBadRequest, w.Result().StatusCode, query)
+	}
+}
+
+func TestHandler_BulkDeleteHandler(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var deleted bool
+	svc := &mockService{
+		previewDeleteFunc: func(ctx context.Context, bucketName string, prefixes []string) (*model.DeletePreview, error) {
+			a.Equal([]string{"logs/"}, prefixes)
+			return &model.DeletePreview{Bucket: bucketName, Prefixes: prefixes, Objects: 1, Token: "token"}, nil
+		},
+		confirmDeleteFunc: func(bucketName string, prefixes []string, token string) error {
+			switch token {
+			case "":
+				return errs.New(http.StatusPreconditionRequired, errs.WithMsg("confirm"))
+			case "token":
+				return nil
+			default:
+				return errs.New(http.StatusPreconditionFailed, errs.WithMsg("invalid"))
+			}
+		},
+		deleteObjectsFunc: func(ctx context.Context, bucketName string, keys, prefixes []string, opts model.DeleteObjectsOptions) ([]model.DeleteResult, error) {
+			deleted = true
+			a.Equal([]string{"a.txt", "b.txt"}, keys)
+			a.Equal([]string{"logs/"}, prefixes)
+			return []model.DeleteResult{
+				{Key: "a.txt", Deleted: true},
+				{Key: "b.txt", Error: aws.String("AccessDenied")},
+				{Key: "logs/1.log", Deleted: true},
+			}, nil
+		},
+	}
+
+	h := setupHandler(svc)
+	r := newRouter(h, nil, true)
+	post := func(target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Prefixes are only deleted with the token of a dry run.
+	body := `{"keys":["a.txt","b.txt"],"prefixes":["logs/"]}`
+	w := post("/api/buckets/test-bucket/delete?dry_run=true", body)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+	var preview struct {
+		Data model.DeletePreview `json:"data"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&preview))
+	a.Equal("token", preview.Data.Token)
+	a.Equal(http.StatusPreconditionRequired, post("/api/buckets/test-bucket/delete", body).Result().StatusCode)
+	a.Equal(http.StatusPreconditionFailed, post("/api/buckets/test-bucket/delete",
+		`{"keys":["a.txt","b.txt"],"prefixes":["logs/"],"confirm":"forged"}`).Result().StatusCode)
+	a.False(deleted)
+
+	w = post("/api/buckets/test-bucket/delete",
+		`{"keys":["a.txt","b.txt"],"prefixes":["logs/"],"confirm":"token"}`)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+	a.True(deleted)
+
+	var response struct {
+		Data bulkDeleteResponse `json:"data"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&response))
+	a.Equal(2, response.Data.Deleted)
+	a.Equal(1, response.Data.Failed)
+	a.Len(response.Data.Results, 3)
+
+	for _, body := range []string{
+		`{"keys":[]}`,
+		`{"keys":[""]}`,
+		`{"keys":["a"],"prefixes":[""]}`,
+	} {
+		a.Equal(http.StatusBadRequest, post("/api/buckets/test-bucket/delete", body).Result().StatusCode, body)
+	}
+}
+
+func TestHandler_JobHandlers(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		submitJobFunc: func(ctx context.Context, spec model.JobSpec) (*model.Job, error) {
+			// Who deleted an object is never taken from the body.
+			a.Equal("alice", spec.DeletedBy)
+			return &model.Job{ID: "job-1", Spec: spec, Status: model.JobQueued}, nil
+		},
+		listJobsFunc: func(ctx context.Context) ([]model.Job, error) {
+			return []model.Job{{ID: "job-1", Status: model.JobRunning}}, nil
+		},
+		getJobFunc: func(ctx context.Context, id string) (*model.Job, error) {
+			if id != "job-1" {
+				return nil, errs.NotFound(errs.WithMsg("job not found"))
+			}
+			return &model.Job{ID: id, Status: model.JobDone}, nil
+		},
+		cancelJobFunc: func(ctx context.Context, id string) error {
+			return errs.Conflict(errs.WithMsg("job has already finished"))
+		},
+	}
+
+	h := setupHandler(svc)
+	r := newRouter(h, nil, true)
+
+	body := `{"type":"move","bucket":"test-bucket","prefix":"docs/","dest_prefix":"archive/docs/","DeletedBy":"mallory"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-User", "alice")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusAccepted, w.Result().StatusCode)
+
+	var response struct {
+		Data model.Job `json:"data"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&response))
+	a.Equal("job-1", response.Data.ID)
+	a.Equal("archive/docs/", response.Data.Spec.DestPrefix)
+
+	for _, body := range []string{
+		`{"type":"explode","bucket":"test-bucket"}`,
+		`{"type":"delete","bucket":"test-bucket"}`,
+		`{"type":"delete","bucket":"test-bucket","prefixes":[""]}`,
+		`{"type":"copy","bucket":"test-bucket","prefix":"docs/","dest_prefix":"docs/old/"}`,
+		`{"type":"sync","bucket":"test-bucket","prefix":"docs/","dest_bucket":"backup","dest_connection":"offsite"}`,
+		`{"type":"delete_bucket","bucket":"ab"}`,
+	} {
+		req = httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		a.Equal(http.StatusBadRequest, w.Result().StatusCode, body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/jobs", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/jobs/missing", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusNotFound, w.Result().StatusCode)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/jobs/job-1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusConflict, w.Result().StatusCode)
+}
+
+func TestHandler_SyncDiffHandler(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		syncDiffFunc: func(ctx context.Context, spec model.JobSpec) (*model.SyncDiff, error) {
+			a.Equal(model.JobSpec{
+				Type:       model.JobSync,
+				Bucket:     "test-bucket",
+				Prefix:     "site/",
+				DestBucket: "backup",
+				DestPrefix: "site/",
+				Delete:     true,
+			}, spec)
+			return &model.SyncDiff{
+				New:       model.SyncChanges{Count: 1, Bytes: 5, Entries: []model.SyncEntry{{Key: "a.html", Size: 5}}},
+				Unchanged: 3,
+			}, nil
+		},
+	}
+	r := newRouter(setupHandler(svc), nil, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/sync?prefix=site/&dest_bucket=backup&dest_prefix=site/&delete=true", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+	var body struct {
+		Data model.SyncDiff `json:"data"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&body))
+	a.Equal(int64(1), body.Data.New.Count)
+	a.Equal(int64(3), body.Data.Unchanged)
+
+	// Syncing a folder into its parent in the same bucket would delete the
+	// source itself.
+	for _, query := range []string{
+		"prefix=site/&dest_prefix=",
+		"prefix=site/&dest_prefix=site/old/",
+		"prefix=site&dest_prefix=site/old",
+		"prefix=site/&dest_bucket=backup&delete=maybe",
+		"prefix=site/&dest_bucket=backup&dest_connection=offsite",
+	} {
+		req = httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/sync?"+query, nil)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		a.Equal(http.StatusBadRequest, w.Result().StatusCode, query)
+	}
+}
+
+func TestHandler_FetchObjectHandler(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		submitJobFunc: func(ctx context.Context, spec model.JobSpec) (*model.Job, error) {
+			a.Equal(model.JobSpec{
+				Type:         model.JobFetch,
+				Bucket:       "test-bucket",
+				URL:          "https://github.com/org/repo/releases/download/v1/app.tar.gz",
+				Key:          "releases/app.tar.gz",
+				StorageClass: model.StorageGlacier,
+			}, spec)
+			return &model.Job{ID: "job-1", Spec: spec, Status: model.JobQueued}, nil
+		},
+	}
+	r := newRouter(setupHandler(svc), nil, true)
+
+	fetch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/buckets/test-bucket/fetch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := fetch(`{"url":"https://github.com/org/repo/releases/download/v1/app.tar.gz","key":"releases/app.tar.gz","storage_class":"GLACIER"}`)
+	a.Equal(http.StatusAccepted, w.Result().StatusCode)
+
+	for _, body := range []string{
+		`{"url":"not a url","key":"app.tar.gz"}`,
+		`{"url":"https://github.com/app.tar.gz"}`,
+		`{"url":"https://github.com/app.tar.gz","key":"app.tar.gz","storage_class":"COLD"}`,
+	} {
+		a.Equal(http.StatusBadRequest, fetch(body).Result().StatusCode, body)
+	}
+}
+
+func TestHandler_CreateFolderHandler(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		createFolderFunc: func(ctx context.Context, bucketName, path string) (*model.Object, error) {
+			a.Equal("docs/archive/", path)
+			return &model.Object{Key: aws.String("docs/archive"), IsDir: true}, nil
+		},
+	}
+	r := newRouter(setupHandler(svc), nil, true)
+
+	for body, status := range map[string]int{
+		`{"path":"docs/archive/"}`: http.StatusCreated,
+		`{"path":"/"}`:             http.StatusBadRequest,
+		`{"path":"docs//archive"}`: http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/buckets/test-bucket/folders", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		a.Equal(status, w.Result().StatusCode, body)
+	}
+}
+
+func TestHandler_PutObjectContentHandler(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var gotType, gotBody string
+	svc := &mockService{
+		putObjectFunc: func(ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader, opts model.PutObjectOptions) (*model.Object, error) {
+			a.Equal("notes/todo.md", objectKey)
+			body, err := io.ReadAll(r)
+			a.NoError(err)
+			gotType, gotBody = mimeType, string(body)
+			return &model.Object{Key: &objectKey}, nil
+		},
+	}
+	h := &Handler{
+		cfg:     config.Config{S3: config.S3{MaxSizeBytes: 16}},
+		service: svc,
+	}
+	r := newRouter(h, nil, true)
+
+	put := func(key, contentType, body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/buckets/test-bucket/objects/"+url.PathEscape(key), strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Result().StatusCode
+	}
+
+	a.Equal(http.StatusCreated, put("notes/todo.md", "text/markdown", "- [ ] write"))
+	a.Equal("text/markdown", gotType)
+	a.Equal("- [ ] write", gotBody)
+
+	a.Equal(http.StatusCreated, put("notes/todo.md", "", ""))
+	a.Equal(defaultTextContentType, gotType)
+	a.Empty(gotBody)
+
+	a.Equal(http.StatusRequestEntityTooLarge, put("notes/todo.md", "", strings.Repeat("a", 17)))
+	a.Equal(http.StatusBadRequest, put("notes/", "", ""))
+}
+
+func TestHandler_EditObjectContent(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		getContentFunc: func(ctx context.Context, bucketName, objectKey string, maxSize int64) (*model.ObjectContent, error) {
+			a.Equal("config/app.json", objectKey)
+			a.Equal(int64(defaultEditorMaxSize), maxSize)
+			return &model.ObjectContent{Key: objectKey, Content: `{"debug":false}`, ETag: `"v1"`}, nil
+		},
+		updateObjectFunc: func(ctx context.Context, bucketName, objectKey, mimeType, etag string, r io.Reader) (*model.Object, error) {
+			if etag != `"v1"` {
+				return nil, errs.New(http.StatusPreconditionFailed, errs.WithMsg("object was changed since it was opened"))
+			}
+			return &model.Object{Key: &objectKey, ETag: aws.String(`"v2"`)}, nil
+		},
+	}
+	h := &Handler{
+		cfg:     config.Config{S3: config.S3{MaxSizeBytes: 1024}},
+		service: svc,
+	}
+	r := newRouter(h, nil, true)
+	path := "/api/buckets/test-bucket/objects/" + url.PathEscape("config/app.json")
+
+	req := httptest.NewRequest(http.MethodGet, path+"/content", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+	a.Equal(`"v1"`, w.Result().Header.Get("ETag"))
+	var body struct {
+		Data model.ObjectContent `json:"data"`
+	}
+	a.NoError(json.NewDecoder(w.Result().Body).Decode(&body))
+	a.Equal(`{"debug":false}`, body.Data.Content)
+
+	for etag, status := range map[string]int{`"v1"`: http.StatusCreated, `"v0"`: http.StatusPreconditionFailed} {
+		req = httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"debug":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", etag)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		a.Equal(status, w.Result().StatusCode, etag)
+	}
+}
+
+func TestHandler_GetThumbnailHandler(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	h := setupHandler(&mockService{
+		thumbnailFunc: func(ctx context.Context, bucketName, objectKey string, size int) (*model.Thumbnail, error) {
+			a.Equal("photos/cat.png", objectKey)
+			if size != defaultThumbnailSize {
+				return nil, errs.New(http.StatusUnsupportedMediaType)
+			}
+			return &model.Thumbnail{Data: []byte("png"), ContentType: "image/png", ETag: `"v1"`}, nil
+		},
+	})
+	r := newRouter(h, nil, true)
+	get := func(query, etag string) *http.Response {
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/api/buckets/test-bucket/objects/"+url.PathEscape("photos/cat.png")+"/thumbnail"+query,
+			nil,
+		)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	resp := get("", "")
+	a.Equal(http.StatusOK, resp.StatusCode)
+	a.Equal("image/png", resp.Header.Get("Content-Type"))
+	a.Equal(`"v1"`, resp.Header.Get("ETag"))
+	body, _ := io.ReadAll(resp.Body)
+	a.Equal("png", string(body))
+
+	a.Equal(http.StatusNotModified, get("", `"v1"`).StatusCode)
+	a.Equal(http.StatusUnsupportedMediaType, get("?size=128", "").StatusCode)
+	a.Equal(http.StatusBadRequest, get("?size=4096", "").StatusCode)
+	a.Equal(http.StatusBadRequest, get("?size=big", "").StatusCode)
+}
+
+func TestHandler_DiffObjectsHandler(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	h := setupHandler(&mockService{
+		diffObjectsFunc: func(ctx context.Context, left, right model.ObjectRef, maxSize int64) (*model.ObjectDiff, error) {
+			a.Equal(model.ObjectRef{Bucket: "prod-bucket", Key: "config/app.yaml"}, left)
+			a.Equal(model.ObjectRef{Bucket: "staging-bucket", Key: "config/app.yaml"}, right)
+			a.Equal(int64(defaultDiffMaxSize), maxSize)
+			return &model.ObjectDiff{Text: true, Diff: "--- a\n+++ b\n"}, nil
+		},
+	})
+	r := newRouter(h, nil, true)
+	get := func(left, right string) *httptest.ResponseRecorder {
+		q := url.Values{"left": {left}, "right": {right}}
+		req := httptest.NewRequest(http.MethodGet, "/api/diff?"+q.Encode(), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("prod-bucket/config/app.yaml", "staging-bucket/config/app.yaml")
+	a.Equal(http.StatusOK, w.Code)
+	var body struct {
+		Data model.ObjectDiff `json:"data"`
+	}
+	a.NoError(json.NewDecoder(w.Body).Decode(&body))
+	a.True(body.Data.Text)
+
+	a.Equal(http.StatusBadRequest, get("prod-bucket", "staging-bucket/config/app.yaml").Code)
+	a.Equal(http.StatusBadRequest, get("prod-bucket/config/", "staging-bucket/config/app.yaml").Code)
+	a.Equal(http.StatusBadRequest, get("prod-bucket/config/app.yaml", "").Code)
+}
+
+func TestHandler_GetObjectChecksumHandler(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	h := setupHandler(&mockService{
+		checksumFunc: func(ctx context.Context, bucketName, objectKey, algorithm string, compute bool) (*model.ObjectChecksum, error) {
+			a.Equal("releases/app.tar.gz", objectKey)
+			source := model.ChecksumStored
+			if compute {
+				source = model.ChecksumComputed
+			}
+			return &model.ObjectChecksum{
+				Key: objectKey, Algorithm: algorithm, Checksum: "abc", Source: source,
+			}, nil
+		},
+	})
+	r := newRouter(h, nil, true)
+	get := func(query string) (int, model.ObjectChecksum) {
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/api/buckets/test-bucket/objects/"+url.PathEscape("releases/app.tar.gz")+"/checksum"+query,
+			nil,
+		)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var body struct {
+			Data model.ObjectChecksum `json:"data"`
+		}
+		_ = json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body.Data
+	}
+
+	code, sum := get("")
+	a.Equal(http.StatusOK, code)
+	a.Equal(model.ChecksumSHA256, sum.Algorithm)
+	a.Equal(model.ChecksumStored, sum.Source)
+
+	code, sum = get("?algorithm=MD5&compute=true")
+	a.Equal(http.StatusOK, code)
+	a.Equal(model.ChecksumMD5, sum.Algorithm)
+	a.Equal(model.ChecksumComputed, sum.Source)
+
+	code, _ = get("?algorithm=crc32")
+	a.Equal(http.StatusBadRequest, code)
+	code, _ = get("?compute=maybe")
+	a.Equal(http.StatusBadRequest, code)
+}
+
+func TestHandler_RestoreObjectHandler(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	h := setupHandler(&mockService{
+		restoreFunc: func(ctx context.Context, bucketName, objectKey string, opts model.RestoreObjectOptions) error {
+			a.Equal("logs/2019.tar", objectKey)
+			if opts.Tier == model.RestoreTierExpedited {
+				return errs.Conflict(errs.WithMsg("object is already being restored"))
+			}
+			a.Equal(model.RestoreTierStandard, opts.Tier)
+			return nil
+		},
+	})
+	r := newRouter(h, nil, true)
+	restore := func(body string) int {
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/api/buckets/test-bucket/objects/"+url.PathEscape("logs/2019.tar")+"/restore",
+			strings.NewReader(body),
+		)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	a.Equal(http.StatusAccepted, restore(`{"days": 7}`))
+	a.Equal(http.StatusConflict, restore(`{"days": 7, "tier": "Expedited"}`))
+	a.Equal(http.StatusBadRequest, restore(`{"days": 0}`))
+	a.Equal(http.StatusBadRequest, restore(`{"days": 7, "tier": "Instant"}`))
+}
+
+func TestHandler_Metrics(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	svc := &mockService{
+		getObjectFunc: func(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, *string, error) {
+			return nil, nil, &model.S3Error{Status: http.StatusNotFound, Message: "object not found"}
+		},
+	}
+
+	h := setupHandler(svc)
+	h.metrics = metrics.NewRegistry()
+	r := newRouter(h, nil, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/objects/missing.txt", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+	a.Contains(w.Header().Get("Content-Type"), "version=0.0.4")
+	got := w.Body.String()
+	for _, line := range []string{
+		`s3manager_http_requests_total{method="GET",route="/api/buckets/{bucket}/objects/{object}",status="404"} 1`,
+		`s3manager_http_request_duration_seconds_count{method="GET",route="/api/buckets/{bucket}/objects/{object}"} 1`,
+		`s3manager_http_requests_in_flight 1`,
+	} {
+		a.Contains(got, line+"\n")
+	}
+
+	// Metrics with an address of their own aren't served with the API.
+	h = setupHandler(svc)
+	h.cfg.Metrics.Address = "127.0.0.1:9090"
+	h.metrics = metrics.NewRegistry()
+	r = newRouter(h, nil, true)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	a.Equal(http.StatusNotFound, w.Result().StatusCode)
+
+	// Aborted downloads are counted as well.
+	svc.getObjectFunc = func(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, *string, error) {
+		body := io.MultiReader(strings.NewReader("a"), iotest.ErrReader(errors.New("boom")))
+		return io.NopCloser(body), nil, nil
+	}
+	h = setupHandler(svc)
+	h.metrics = metrics.NewRegistry()
+	r = newRouter(h, nil, true)
+	a.PanicsWithValue(http.ErrAbortHandler, func() {
+		req := httptest.NewRequest(http.MethodGet, "/api/buckets/test-bucket/objects/test.txt", nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	a.Contains(w.Body.String(), `s3manager_http_requests_total{method="GET",route="/api/buckets/{bucket}/objects/{object}",status="200"} 1`+"\n")
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const maxJobItems = 100000
+
+var jobTypes = []string{
+	model.JobDelete,
+	model.JobDeleteBucket,
+	model.JobCopy,
+	model.JobMove,
+	model.JobSync,
+	model.JobFetch,
+}
+
+func (h *Handler) SubmitJobHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req, err := grape.ReadJSON[SubmitJobRequest](w, r)
+	if err != nil {
+		grape.ExtractFromErr(ctx, w, errs.BadRequest(errs.WithMsg(err.Error())))
+		return
+	}
+
+	spec := model.JobSpec(req)
+	spec.DeletedBy = requestUser(r)
+	job, err := h.service.SubmitJob(ctx, spec)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("submitting job: %w", err))
+		return
+	}
+
+	grape.WriteJSON(
+		ctx, w,
+		grape.WithStatus(http.StatusAccepted),
+		grape.WithData(grape.Response{Data: job}),
+	)
+}
+
+func (h *Handler) ListJobsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	jobs, err := h.service.ListJobs(ctx)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("listing jobs: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: jobs}))
+}
+
+func (h *Handler) GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	job, err := h.service.GetJob(ctx, r.PathValue("id"))
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("getting job: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: job}))
+}
+
+func (h *Handler) CancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	err := h.service.CancelJob(ctx, r.PathValue("id"))
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("cancelling job: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithStatus(http.StatusNoContent))
+}
+
+// SubmitJobRequest has the same shape as model.JobSpec. Delete jobs take keys
+// and prefixes, copy, move and sync jobs a source prefix and a destination,
+// fetch jobs a URL and a key. Copies and fetches can go to another storage
+// class. Jobs that delete a bucket or prefixes need the
+// token of a matching dry run, syncs that delete the token of their diff.
+type SubmitJobRequest struct {
+	Type           string   `json:"type"`
+	Bucket         string   `json:"bucket"`
+	Keys           []string `json:"keys,omitempty"`
+	Prefixes       []string `json:"prefixes,omitempty"`
+	Prefix         string   `json:"prefix,omitempty"`
+	DestBucket     string   `json:"dest_bucket,omitempty"`
+	DestPrefix     string   `json:"dest_prefix,omitempty"`
+	DestConnection string   `json:"dest_connection,omitempty"`
+	Delete         bool     `json:"delete,omitempty"`
+	Permanent      bool     `json:"permanent,omitempty"`
+	DeletedBy      string   `json:"-"`
+	URL            string   `json:"url,omitempty"`
+	Key            string   `json:"key,omitempty"`
+	StorageClass   string   `json:"storage_class,omitempty"`
+	Confirm        string   `json:"confirm,omitempty"`
+}
+
+func (s SubmitJobRequest) Validate() error {
+	v, _ := validateBucket(s.Bucket)
+	v.Check(
+		"type",
+		validator.Case{
+			Cond: slices.Contains(jobTypes, s.Type),
+			Msg:  "Type must be one of " + strings.Join(jobTypes, ", "),
+		},
+	)
+	switch s.Type {
+	case model.JobDelete:
+		total := len(s.Keys) + len(s.Prefixes)
+		v.Check(
+			"keys",
+			validator.Case{
+				Cond: total > 0, Msg: "At least one key or prefix is required",
+			},
+			validator.Case{
+				Cond: total <= maxJobItems,
+				Msg: fmt.Sprintf(
+					"Cannot delete more than %d keys and prefixes at once",
+					maxJobItems,
+				),
+			},
+			validator.Case{
+				Cond: !slices.Contains(s.Keys, "") &&
+					!slices.Contains(s.Prefixes, ""),
+				Msg: "Keys and prefixes cannot be empty",
+			},
+		)
+	case model.JobCopy, model.JobMove, model.JobSync:
+		checkDestination(v, model.JobSpec(s))
+	case model.JobFetch:
+		u, err := url.Parse(s.URL)
+		v.Check("url", validator.Case{
+			Cond: err == nil && u.IsAbs() && u.Host != "", Msg: "A valid URL is required",
+		})
+		v.Check("key", validator.Case{
+			Cond: !validator.Empty(s.Key), Msg: "Key is required",
+		})
+	}
+	checkStorageClass(v, s.StorageClass)
+	if ok := v.Validate(); !ok {
+		return errs.BadRequest(errs.WithMsg(v.Errors.Error()))
+	}
+	return nil
+}
+
+// checkDestination makes sure a job doesn't write into its own source. A sync
+// that deletes could also remove a source inside its destination, so the two
+// can't overlap either way. Jobs only run against the configured S3
+// endpoint, so naming another connection is rejected.
+func checkDestination(v *validator.Validator, spec model.JobSpec) {
+	v.Check("dest_connection", validator.Case{
+		Cond: spec.DestConnection == "",
+		Msg:  "Only the configured S3 endpoint is supported, not other connections",
+	})
+	if spec.DestBucket != "" {
+		dv, _ := validateBucket(spec.DestBucket)
+		v.Check("dest_bucket", validator.Case{
+			Cond: dv.Validate(), Msg: "Invalid destination bucket",
+		})
+	}
+	sameBucket := spec.DestBucket == "" || spec.DestBucket == spec.Bucket
+	if spec.Type == model.JobSync {
+		// Syncs compare whole folders, the service adds the missing "/".
+		spec.Prefix, spec.DestPrefix = dirPrefix(spec.Prefix), dirPrefix(spec.DestPrefix)
+	}
+	v.Check(
+		"dest_prefix",
+		validator.Case{
+			Cond: !sameBucket || !strings.HasPrefix(spec.DestPrefix, spec.Prefix),
+			Msg:  "Destination cannot be inside the source",
+		},
+	)
+	if spec.Type == model.JobSync {
+		v.Check(
+			"prefix",
+			validator.Case{
+				Cond: !sameBucket || !strings.HasPrefix(spec.Prefix, spec.DestPrefix),
+				Msg:  "Source cannot be inside the destination",
+			},
+		)
+	}
+}
+
+// dirPrefix makes a non-empty prefix end in "/".
+func dirPrefix(prefix string) string {
+	if prefix == "" || strings.HasSuffix(prefix, "/") {
+		return prefix
+	}
+	return prefix + "/"
+}
@@ -40,7 +40,7 @@ func (h *Handler) ListBucketsHandler(w http.ResponseWriter, r *http.Request) {
 
 	buckets, next, err := h.service.ListBuckets(ctx, count, opts)
 	if err != nil {
-		grape.ExtractFromErr(ctx, w, err)
+		writeError(ctx, w, err)
 		return
 	}
 	resp := listBucketsResponse{Buckets: buckets, NextToken: next}
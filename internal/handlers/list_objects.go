@@ -4,12 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
 	"github.com/hossein1376/grape/validator"
 	"github.com/hossein1376/s3manager/internal/model"
 )
 
+// maxListPage caps the objects listed at once, the most S3 returns in one
+// request.
+const maxListPage = 1000
+
 func (h *Handler) ListObjectsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	bucketName := r.PathValue("bucket")
@@ -41,13 +49,42 @@ func (h *Handler) ListObjectsHandler(w http.ResponseWriter, r *http.Request) {
 	token := query.Get("token")
 	path := query.Get("path")
 
-	count, err := grape.Query(query, "count", grape.ParseInt[int32]())
+	du, err := grape.Query(query, "du", strconv.ParseBool)
 	switch {
 	case err == nil:
 		// continue
 	case errors.Is(err, grape.ErrMissingQuery):
-		count = 50 // default value
+		du = false
 	default:
+		grape.ExtractFromErr(
+			ctx, w, errs.BadRequest(errs.WithMsg("Invalid du param")),
+		)
+		return
+	}
+	// Disk usage mode returns every child of path at once, with folder sizes
+	// aggregated over their whole subtree, or over the part of it read before
+	// the walk was cut short.
+	if du {
+		list, truncated, err := h.service.DiskUsage(ctx, bucketName, path)
+		if err != nil {
+			writeError(ctx, w, err)
+			return
+		}
+		resp := listObjectsResponse{List: list, Truncated: truncated}
+		grape.WriteJSON(ctx, w, grape.WithData(resp))
+		return
+	}
+
+	count, err := grape.Query(query, "count", grape.ParseInt[int32]())
+	switch {
+	case err == nil:
+		if count < 1 || count > maxListPage {
+			err = fmt.Errorf("must be between 1 and %d", maxListPage)
+		}
+	case errors.Is(err, grape.ErrMissingQuery):
+		count, err = 50, nil // default value
+	}
+	if err != nil {
 		resp := grape.Response{
 			Message: "Bad input", Data: fmt.Sprintf("parse count: %s", err),
 		}
@@ -57,23 +94,91 @@ func (h *Handler) ListObjectsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recursive, err := grape.Query(query, "recursive", strconv.ParseBool)
+	switch {
+	case err == nil:
+		// continue
+	case errors.Is(err, grape.ErrMissingQuery):
+		recursive = false
+	default:
+		grape.ExtractFromErr(
+			ctx, w, errs.BadRequest(errs.WithMsg("Invalid recursive param")),
+		)
+		return
+	}
+
 	opts := model.ListObjectsOption{
-		Path:   path,
-		Filter: filter,
+		Path:      path,
+		Filter:    filter,
+		Recursive: recursive,
 	}
 	if token != "" {
 		opts.ContinuationToken = &token
 	}
+	if v := parseListSortAndFilters(query, &opts); !v.Validate() {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
 	list, next, err := h.service.ListObjects(ctx, bucketName, int32(count), opts)
 	if err != nil {
-		grape.ExtractFromErr(ctx, w, err)
+		writeError(ctx, w, err)
 		return
 	}
 	resp := listObjectsResponse{List: list, NextToken: next}
 	grape.WriteJSON(ctx, w, grape.WithData(resp))
 }
 
+// parseListSortAndFilters reads the sort order and the column filters. Any of
+// them makes the service read the whole directory before paginating.
+func parseListSortAndFilters(
+	query url.Values, opts *model.ListObjectsOption,
+) *validator.Validator {
+	v := validator.New()
+	opts.SortBy = query.Get("sort")
+	v.Check(
+		"sort",
+		validator.Case{
+			Cond: opts.SortBy == "" || opts.SortBy == model.SortByName ||
+				opts.SortBy == model.SortBySize || opts.SortBy == model.SortByModified,
+			Msg: "Sort must be one of name, size or modified",
+		},
+	)
+	order := query.Get("order")
+	v.Check(
+		"order",
+		validator.Case{
+			Cond: order == "" || order == "asc" || order == "desc",
+			Msg:  "Order must be either asc or desc",
+		},
+	)
+	opts.Descending = order == "desc"
+
+	opts.MinSize = querySize(query, "min_size", v)
+	opts.MaxSize = querySize(query, "max_size", v)
+	checkSizeRange(v, opts.MinSize, opts.MaxSize)
+	opts.ModifiedAfter = queryTime(query, "modified_after", v)
+	opts.ModifiedBefore = queryTime(query, "modified_before", v)
+
+	for ext := range strings.SplitSeq(query.Get("ext"), ",") {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext != "" {
+			opts.Extensions = append(opts.Extensions, ext)
+		}
+	}
+	for class := range strings.SplitSeq(query.Get("storage_class"), ",") {
+		class = strings.ToUpper(strings.TrimSpace(class))
+		if class != "" {
+			opts.StorageClasses = append(opts.StorageClasses, class)
+		}
+	}
+	return v
+}
+
 type listObjectsResponse struct {
 	List      []model.Object `json:"list"`
 	NextToken *string        `json:"next_token,omitempty"`
+	Truncated bool           `json:"truncated,omitempty"`
 }
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	defaultTextContentType = "text/plain; charset=utf-8"
+	defaultEditorMaxSize   = 1024 * 1024
+)
+
+// PutObjectContentHandler creates or overwrites an object with the raw
+// request body, typically a small text or JSON file written in the UI. The
+// request's Content-Type is kept. With If-Match, the object is only
+// overwritten if it still has that ETag.
+func (h *Handler) PutObjectContentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	objectName := r.PathValue("object")
+	v, _ := validateBucket(bucketName)
+	v.Check(
+		"key",
+		validator.Case{
+			Cond: !validator.Empty(objectName), Msg: "object name is required",
+		},
+		validator.Case{
+			Cond: !strings.HasSuffix(objectName, "/"),
+			Msg:  "Object name cannot end with a slash, create a folder instead",
+		},
+	)
+	if ok := v.Validate(); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	// The body is read whole, so it can be retried and signed.
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.cfg.S3.MaxSizeBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			grape.ExtractFromErr(ctx, w, errs.New(
+				http.StatusRequestEntityTooLarge, errs.WithMsg("body is too large"),
+			))
+			return
+		}
+		writeError(ctx, w, fmt.Errorf("reading body: %w", err))
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = defaultTextContentType
+	}
+
+	var obj *model.Object
+	if etag := r.Header.Get("If-Match"); etag != "" {
+		obj, err = h.service.UpdateObject(
+			ctx, bucketName, objectName, contentType, etag, bytes.NewReader(body),
+		)
+	} else {
+		obj, err = h.service.PutObject(
+			ctx, bucketName, objectName, contentType, bytes.NewReader(body),
+			model.PutObjectOptions{},
+		)
+	}
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("putting object: %w", err))
+		return
+	}
+
+	grape.WriteJSON(
+		ctx,
+		w,
+		grape.WithStatus(http.StatusCreated),
+		grape.WithData(grape.Response{Data: obj}),
+	)
+}
+
+// GetObjectContentHandler returns a text object to edit, with its ETag.
+// Objects above the editor's size limit, or that aren't UTF-8, are refused.
+func (h *Handler) GetObjectContentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	objectName := r.PathValue("object")
+	v, _ := validateBucket(bucketName)
+	v.Check("key", validator.Case{
+		Cond: !validator.Empty(objectName), Msg: "object name is required",
+	})
+	if ok := v.Validate(); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+	maxSize := h.cfg.Editor.MaxSizeBytes
+	if maxSize <= 0 {
+		maxSize = defaultEditorMaxSize
+	}
+
+	content, err := h.service.GetObjectContent(ctx, bucketName, objectName, maxSize)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("getting object content: %w", err))
+		return
+	}
+
+	w.Header().Set("ETag", content.ETag)
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: content}))
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+func (h *Handler) GetObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	objectName := r.PathValue("object")
+	if v, ok := validateObject(bucketName, objectName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	retention, err := h.service.GetObjectRetention(ctx, bucketName, objectName)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("getting retention: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: retention}))
+}
+
+func (h *Handler) PutObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	objectName := r.PathValue("object")
+	bypass, err := grape.Query(
+		r.URL.Query(), "bypass_governance", strconv.ParseBool,
+	)
+	if err != nil {
+		if !errors.Is(err, grape.ErrMissingQuery) {
+			grape.ExtractFromErr(
+				ctx, w, errs.BadRequest(errs.WithMsg("Invalid bypass_governance param")),
+			)
+			return
+		}
+		bypass = false
+	}
+	req, err := grape.ReadJSON[PutObjectRetentionRequest](w, r)
+	if err != nil {
+		grape.ExtractFromErr(ctx, w, errs.BadRequest(errs.WithMsg(err.Error())))
+		return
+	}
+	if v, ok := validateObject(bucketName, objectName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	retention := model.ObjectRetention{
+		Mode: req.Mode, RetainUntil: req.RetainUntil,
+	}
+	err = h.service.PutObjectRetention(
+		ctx, bucketName, objectName, retention, bypass,
+	)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("putting retention: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithStatus(http.StatusNoContent))
+}
+
+// PutObjectRetentionRequest sets an object's retention. Both fields must be
+// set together, or both left out to clear a governance retention.
+type PutObjectRetentionRequest struct {
+	Mode        *string `json:"mode,omitempty"`
+	RetainUntil *string `json:"retain_until,omitempty"`
+}
+
+func (p PutObjectRetentionRequest) Validate() error {
+	v := validator.New()
+	v.Check(
+		"retention",
+		validator.Case{
+			Cond: (p.Mode == nil) == (p.RetainUntil == nil),
+			Msg:  "Mode and retain until date must be set together",
+		},
+	)
+	if p.Mode != nil {
+		v.Check(
+			"mode",
+			validator.Case{
+				Cond: validRetentionMode(*p.Mode),
+				Msg:  "Retention mode must be either GOVERNANCE or COMPLIANCE",
+			},
+		)
+	}
+	if p.RetainUntil != nil {
+		until, err := time.Parse(time.RFC3339, *p.RetainUntil)
+		v.Check(
+			"retain_until",
+			validator.Case{
+				Cond: err == nil, Msg: "Retain until must be an RFC 3339 date",
+			},
+			validator.Case{
+				Cond: err != nil || until.After(time.Now()),
+				Msg:  "Retain until must be in the future",
+			},
+		)
+	}
+	if ok := v.Validate(); !ok {
+		return errs.BadRequest(errs.WithMsg(v.Errors.Error()))
+	}
+	return nil
+}
+
+func (h *Handler) GetObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	objectName := r.PathValue("object")
+	if v, ok := validateObject(bucketName, objectName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	hold, err := h.service.GetObjectLegalHold(ctx, bucketName, objectName)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("getting legal hold: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: hold}))
+}
+
+func (h *Handler) PutObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	objectName := r.PathValue("object")
+	req, err := grape.ReadJSON[model.LegalHold](w, r)
+	if err != nil {
+		grape.ExtractFromErr(ctx, w, errs.BadRequest(errs.WithMsg(err.Error())))
+		return
+	}
+	if v, ok := validateObject(bucketName, objectName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	err = h.service.PutObjectLegalHold(ctx, bucketName, objectName, req)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("putting legal hold: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithStatus(http.StatusNoContent))
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+var restoreTiers = []string{
+	model.RestoreTierExpedited, model.RestoreTierStandard, model.RestoreTierBulk,
+}
+
+// RestoreObjectHandler asks for an archived object to be restored. It's
+// accepted right away, the object can be read once the restore is done.
+func (h *Handler) RestoreObjectHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	objectName := r.PathValue("object")
+	req, err := grape.ReadJSON[RestoreObjectRequest](w, r)
+	if err != nil {
+		grape.ExtractFromErr(ctx, w, errs.BadRequest(errs.WithMsg(err.Error())))
+		return
+	}
+	if v, ok := validateObject(bucketName, objectName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	opts := model.RestoreObjectOptions{Days: req.Days, Tier: req.Tier}
+	if opts.Tier == "" {
+		opts.Tier = model.RestoreTierStandard
+	}
+	err = h.service.RestoreObject(ctx, bucketName, objectName, opts)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("restoring object: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithStatus(http.StatusAccepted))
+}
+
+// RestoreObjectRequest asks for an archived object to be readable for Days.
+// Tier defaults to Standard.
+type RestoreObjectRequest struct {
+	Days int32  `json:"days"`
+	Tier string `json:"tier,omitempty"`
+}
+
+func (req RestoreObjectRequest) Validate() error {
+	v := validator.New()
+	v.Check("days", validator.Case{
+		Cond: req.Days > 0, Msg: "Days must be at least 1",
+	})
+	v.Check("tier", validator.Case{
+		Cond: req.Tier == "" || slices.Contains(restoreTiers, req.Tier),
+		Msg:  "Tier must be one of Expedited, Standard or Bulk",
+	})
+	if ok := v.Validate(); !ok {
+		return errs.BadRequest(errs.WithMsg(v.Errors.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/slogger"
+	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	defaultSearchLimit = 1000
+	maxSearchLimit     = 10000
+)
+
+// SearchObjectsHandler streams the matching objects as newline delimited
+// JSON, one event per line, flushing after each match. The last line carries
+// either the summary or the error that stopped the walk. Closing the
+// connection cancels the search.
+func (h *Handler) SearchObjectsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	v, _ := validateBucket(bucketName)
+	opts := parseSearchOptions(r, v)
+	if ok := v.Validate(); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	// The walk may outlive the server's write timeout, the client decides
+	// when to stop instead.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	stream := &searchStream{w: w, rc: rc}
+	result, err := h.service.SearchObjects(
+		ctx, bucketName, opts, func(obj model.Object) error {
+			return stream.send(searchEvent{Object: &obj})
+		},
+	)
+	if err != nil {
+		if !stream.started {
+			writeError(ctx, w, fmt.Errorf("searching objects: %w", err))
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		slogger.Error(ctx, "searching objects", slogger.Err("error", err))
+		msg := err.Error()
+		_ = stream.send(searchEvent{Error: &msg})
+		return
+	}
+	_ = stream.send(searchEvent{Result: result})
+}
+
+type searchEvent struct {
+	Object *model.Object       `json:"object,omitempty"`
+	Result *model.SearchResult `json:"result,omitempty"`
+	Error  *string             `json:"error,omitempty"`
+}
+
+// searchStream writes the headers lazily, so failures before the first match
+// can still be reported with a proper status code.
+type searchStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+func (s *searchStream) send(event searchEvent) error {
+	if !s.started {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+	if err := json.NewEncoder(s.w).Encode(event); err != nil {
+		return fmt.Errorf("writing search event: %w", err)
+	}
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("flushing search event: %w", err)
+	}
+	return nil
+}
+
+func parseSearchOptions(r *http.Request, v *validator.Validator) model.SearchOptions {
+	query := r.URL.Query()
+	opts := model.SearchOptions{
+		Prefix: query.Get("prefix"),
+		Query:  query.Get("q"),
+		Mode:   query.Get("mode"),
+		Limit:  defaultSearchLimit,
+	}
+	v.Check(
+		"q",
+		validator.Case{
+			Cond: !validator.Empty(opts.Query), Msg: "search query is required",
+		},
+	)
+	v.Check(
+		"mode",
+		validator.Case{
+			Cond: opts.Mode == "" || opts.Mode == model.SearchSubstring ||
+				opts.Mode == model.SearchGlob || opts.Mode == model.SearchRegex,
+			Msg: "Mode must be one of substring, glob or regex",
+		},
+	)
+
+	limit, err := grape.Query(query, "limit", grape.ParseInt[int]())
+	switch {
+	case err == nil:
+		opts.Limit = limit
+		v.Check(
+			"limit",
+			validator.Case{
+				Cond: limit > 0 && limit <= maxSearchLimit,
+				Msg:  fmt.Sprintf("Limit must be between 1 and %d", maxSearchLimit),
+			},
+		)
+	case !errors.Is(err, grape.ErrMissingQuery):
+		v.Check("limit", validator.Case{Cond: false, Msg: "Invalid limit"})
+	}
+
+	opts.MinSize = querySize(query, "min_size", v)
+	opts.MaxSize = querySize(query, "max_size", v)
+	checkSizeRange(v, opts.MinSize, opts.MaxSize)
+	opts.ModifiedAfter = queryTime(query, "modified_after", v)
+	opts.ModifiedBefore = queryTime(query, "modified_before", v)
+
+	return opts
+}
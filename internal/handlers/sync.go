@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+// SyncDiffHandler previews a sync job: what it would copy and delete. Both
+// sides of a sync are on the configured S3 endpoint, with its credentials.
+func (h *Handler) SyncDiffHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	del, err := grape.Query(query, "delete", strconv.ParseBool)
+	if err != nil {
+		if !errors.Is(err, grape.ErrMissingQuery) {
+			grape.ExtractFromErr(
+				ctx, w, errs.BadRequest(errs.WithMsg("Invalid delete param")),
+			)
+			return
+		}
+		del = false
+	}
+	spec := model.JobSpec{
+		Type:           model.JobSync,
+		Bucket:         r.PathValue("bucket"),
+		Prefix:         query.Get("prefix"),
+		DestBucket:     query.Get("dest_bucket"),
+		DestPrefix:     query.Get("dest_prefix"),
+		DestConnection: query.Get("dest_connection"),
+		Delete:         del,
+	}
+	if err = SubmitJobRequest(spec).Validate(); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	diff, err := h.service.SyncDiff(ctx, spec)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("comparing for sync: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: diff}))
+}
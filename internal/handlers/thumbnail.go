@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/validator"
+)
+
+const (
+	defaultThumbnailSize = 256
+	minThumbnailSize     = 32
+	maxThumbnailSize     = 1024
+)
+
+// GetThumbnailHandler returns a PNG, JPEG or GIF object scaled down to fit
+// in a square of the requested size. The response carries the object's
+// ETag, so browsers can revalidate it with If-None-Match.
+func (h *Handler) GetThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	objectName := r.PathValue("object")
+	size, err := grape.Query(r.URL.Query(), "size", strconv.Atoi)
+	switch {
+	case err == nil:
+		// continue
+	case errors.Is(err, grape.ErrMissingQuery):
+		size = defaultThumbnailSize
+	default:
+		grape.ExtractFromErr(
+			ctx, w, errs.BadRequest(errs.WithMsg("Invalid size param")),
+		)
+		return
+	}
+	v, _ := validateBucket(bucketName)
+	v.Check("key", validator.Case{
+		Cond: !validator.Empty(objectName), Msg: "object name is required",
+	})
+	v.Check("size", validator.Case{
+		Cond: size >= minThumbnailSize && size <= maxThumbnailSize,
+		Msg: fmt.Sprintf(
+			"Size must be between %d and %d", minThumbnailSize, maxThumbnailSize,
+		),
+	})
+	if ok := v.Validate(); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	thumb, err := h.service.Thumbnail(ctx, bucketName, objectName, size)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("getting thumbnail: %w", err))
+		return
+	}
+
+	w.Header().Set("ETag", thumb.ETag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if thumb.ETag != "" && r.Header.Get("If-None-Match") == thumb.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", thumb.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(thumb.Data)))
+	_, _ = w.Write(thumb.Data)
+}
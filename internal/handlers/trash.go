@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const maxTrashPage = 1000
+
+func (h *Handler) ListTrashHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	query := r.URL.Query()
+	v, _ := validateBucket(bucketName)
+	count, err := grape.Query(query, "count", grape.ParseInt[int32]())
+	switch {
+	case err == nil:
+		v.Check("count", validator.Case{
+			Cond: count > 0 && count <= maxTrashPage,
+			Msg:  fmt.Sprintf("Count must be between 1 and %d", maxTrashPage),
+		})
+	case errors.Is(err, grape.ErrMissingQuery):
+		count = 100
+	default:
+		v.Check("count", validator.Case{Cond: false, Msg: "Invalid count"})
+	}
+	if ok := v.Validate(); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+	var token *string
+	if t := query.Get("token"); t != "" {
+		token = &t
+	}
+
+	items, next, err := h.service.ListTrash(ctx, bucketName, count, token)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("listing trash: %w", err))
+		return
+	}
+
+	resp := listTrashResponse{List: items, NextToken: next}
+	grape.WriteJSON(ctx, w, grape.WithData(resp))
+}
+
+func (h *Handler) RestoreTrashHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	if v, ok := validateBucket(bucketName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	err := h.service.RestoreTrash(ctx, bucketName, r.PathValue("id"))
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("restoring from trash: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithStatus(http.StatusNoContent))
+}
+
+func (h *Handler) PurgeTrashHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	if v, ok := validateBucket(bucketName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	err := h.service.PurgeTrash(ctx, bucketName, r.PathValue("id"))
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("purging from trash: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithStatus(http.StatusNoContent))
+}
+
+func (h *Handler) EmptyTrashHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	if v, ok := validateBucket(bucketName); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	err := h.service.EmptyTrash(ctx, bucketName)
+	if err != nil {
+		writeError(ctx, w, fmt.Errorf("emptying trash: %w", err))
+		return
+	}
+
+	grape.WriteJSON(ctx, w, grape.WithStatus(http.StatusNoContent))
+}
+
+type listTrashResponse struct {
+	List      []model.TrashItem `json:"list"`
+	NextToken *string           `json:"next_token,omitempty"`
+}
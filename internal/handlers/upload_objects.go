@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/hossein1376/grape"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/slogger"
+	"github.com/hossein1376/grape/validator"
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	maxUploadFiles = 1000
+	// uploadWorkers is how many files of one request are put at once.
+	uploadWorkers = 4
+)
+
+// UploadObjectsHandler puts many files at once. Each "file" part is paired with
+// the "path" field of the same position, its path relative to "prefix"; the
+// multipart filename loses any directories, so it's only a fallback. All
+// files go to the "storage_class" field's class, if it's set.
+func (h *Handler) UploadObjectsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bucketName := r.PathValue("bucket")
+	v, _ := validateBucket(bucketName)
+
+	err := r.ParseMultipartForm(h.cfg.S3.MaxSizeBytes)
+	if err != nil {
+		grape.ExtractFromErr(ctx, w, errs.BadRequest(errs.WithMsg(err.Error())))
+		return
+	}
+	files := r.MultipartForm.File["file"]
+	paths := r.MultipartForm.Value["path"]
+	prefix := r.FormValue("prefix")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	v.Check(
+		"file",
+		validator.Case{Cond: len(files) > 0, Msg: "At least one file is required"},
+		validator.Case{
+			Cond: len(files) <= maxUploadFiles,
+			Msg:  fmt.Sprintf("Cannot upload more than %d files at once", maxUploadFiles),
+		},
+	)
+	opts := model.PutObjectOptions{StorageClass: r.FormValue("storage_class")}
+	checkStorageClass(v, opts.StorageClass)
+	v.Check("path", validator.Case{
+		Cond: len(paths) == 0 || len(paths) == len(files),
+		Msg:  "There must be one path for every file",
+	})
+	keys := make([]string, len(files))
+	for i, fh := range files {
+		rel := fh.Filename
+		if i < len(paths) {
+			rel = paths[i]
+		}
+		key, ok := uploadKey(prefix, rel)
+		v.Check("path", validator.Case{
+			Cond: ok, Msg: fmt.Sprintf("Invalid relative path %q", rel),
+		})
+		keys[i] = key
+	}
+	if ok := v.Validate(); !ok {
+		resp := grape.Response{Message: "Bad input", Data: v.Errors}
+		grape.WriteJSON(
+			ctx, w, grape.WithStatus(http.StatusBadRequest), grape.WithData(resp),
+		)
+		return
+	}
+
+	results := make([]model.UploadResult, len(files))
+	sem := make(chan struct{}, uploadWorkers)
+	var wg sync.WaitGroup
+	for i, fh := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = h.uploadFile(ctx, bucketName, keys[i], fh, opts)
+		}()
+	}
+	wg.Wait()
+
+	resp := uploadObjectsResponse{Results: results}
+	for _, res := range results {
+		if res.Uploaded {
+			resp.Uploaded++
+		} else {
+			resp.Failed++
+		}
+	}
+	grape.WriteJSON(ctx, w, grape.WithData(grape.Response{Data: resp}))
+}
+
+func (h *Handler) uploadFile(
+	ctx context.Context,
+	bucketName, key string,
+	fh *multipart.FileHeader,
+	opts model.PutObjectOptions,
+) model.UploadResult {
+	res := model.UploadResult{Key: key, Size: fh.Size}
+	fail := func(err error) model.UploadResult {
+		msg := err.Error()
+		res.Error = &msg
+		return res
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		return fail(fmt.Errorf("opening file: %w", err))
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			slogger.Error(ctx, "closing file", slogger.Err("error", err))
+		}
+	}()
+	res.ContentType, err = contentType(file, fh)
+	if err != nil {
+		return fail(err)
+	}
+	if _, err = h.service.PutObject(ctx, bucketName, key, res.ContentType, file, opts); err != nil {
+		return fail(err)
+	}
+	res.Uploaded = true
+	return res
+}
+
+// uploadKey joins prefix with a path relative to it. The path must stay
+// below the prefix.
+func uploadKey(prefix, rel string) (string, bool) {
+	rel = strings.ReplaceAll(rel, "\\", "/")
+	if rel == "" || strings.HasPrefix(rel, "/") {
+		return "", false
+	}
+	clean := path.Clean(rel)
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", false
+	}
+	return prefix + clean, true
+}
+
+type uploadObjectsResponse struct {
+	Uploaded int                  `json:"uploaded"`
+	Failed   int                  `json:"failed"`
+	Results  []model.UploadResult `json:"results"`
+}
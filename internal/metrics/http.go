@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP records the requests a server handles, by route and status.
+type HTTP struct {
+	requests *Counter
+	duration *Histogram
+	inFlight *Gauge
+}
+
+func NewHTTP(r *Registry) *HTTP {
+	return &HTTP{
+		requests: r.Counter(
+			"s3manager_http_requests_total",
+			"HTTP requests handled, by method, route and status.",
+			"method", "route", "status",
+		),
+		duration: r.Histogram(
+			"s3manager_http_request_duration_seconds",
+			"Time taken to handle HTTP requests, by method and route.",
+			DefaultBuckets, "method", "route",
+		),
+		inFlight: r.Gauge(
+			"s3manager_http_requests_in_flight",
+			"HTTP requests being handled.",
+		),
+	}
+}
+
+// Middleware records the requests passed to next. The route of a request is
+// only known once the router's mux has matched it, so it has to be the last
+// middleware; requests that match no route are counted as "unmatched".
+func (m *HTTP) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		// Deferred, so responses aborted with a panic are counted too.
+		defer func() { m.record(r, sw.status, start) }()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// statusWriter keeps the status written to the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach flushing and hijacking.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// record counts a handled request. Its route is only known once the router's
+// mux has matched it.
+func (m *HTTP) record(r *http.Request, status int, start time.Time) {
+	route := "unmatched"
+	if r.Pattern != "" {
+		_, path, ok := strings.Cut(r.Pattern, " ")
+		if !ok {
+			path = r.Pattern
+		}
+		route = path
+	}
+	m.duration.Observe(time.Since(start).Seconds(), r.Method, route)
+	if status == 0 {
+		status = http.StatusOK
+	}
+	m.requests.Inc(r.Method, route, strconv.Itoa(status))
+}
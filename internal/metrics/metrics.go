@@ -0,0 +1,264 @@
+// Package metrics keeps counters, gauges and histograms and serves them in
+// the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds the metrics served at /metrics, in the order they were
+// added.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) add(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// ServeHTTP writes every metric in the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	_ = bw.Flush()
+}
+
+// desc names a metric and its labels.
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (d desc) writeHeader(w io.Writer) {
+	help := strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(d.help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, help, d.name, d.kind)
+}
+
+// writeSample writes a line of the metric, suffix added to its name. The le
+// label of histogram buckets comes after the metric's own, when it's set.
+func (d desc) writeSample(
+	w io.Writer, suffix string, values []string, le string, v float64,
+) {
+	var pairs []string
+	for i, name := range d.labels {
+		pairs = append(pairs, name+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	labels := ""
+	if len(pairs) > 0 {
+		labels = "{" + strings.Join(pairs, ",") + "}"
+	}
+	fmt.Fprintf(w, "%s%s%s %s\n", d.name, suffix, labels, formatFloat(v))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// series keeps one value per combination of label values.
+type series[T any] struct {
+	mu     sync.Mutex
+	values map[string]*T
+	labels map[string][]string
+}
+
+func newSeries[T any]() series[T] {
+	return series[T]{values: make(map[string]*T), labels: make(map[string][]string)}
+}
+
+// with runs fn on the value of the given labels, creating it with init.
+func (s *series[T]) with(values []string, init func() *T, fn func(*T)) {
+	key := strings.Join(values, "\xff")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	if !ok {
+		v = init()
+		s.values[key] = v
+		s.labels[key] = slices.Clone(values)
+	}
+	fn(v)
+}
+
+// each runs fn on every value, sorted by their labels.
+func (s *series[T]) each(fn func(labels []string, v *T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range slices.Sorted(maps.Keys(s.values)) {
+		fn(s.labels[key], s.values[key])
+	}
+}
+
+// Counter is a value that only goes up, per combination of labels.
+type Counter struct {
+	desc
+	series series[float64]
+}
+
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		desc:   desc{name: name, help: help, kind: "counter", labels: labels},
+		series: newSeries[float64](),
+	}
+	r.add(c)
+	return c
+}
+
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *Counter) Add(v float64, labels ...string) {
+	c.series.with(labels, newZero, func(n *float64) { *n += v })
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.writeHeader(w)
+	c.series.each(func(labels []string, v *float64) {
+		c.writeSample(w, "", labels, "", *v)
+	})
+}
+
+// Gauge is a value that goes up and down, per combination of labels.
+type Gauge struct {
+	desc
+	series series[float64]
+}
+
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{
+		desc:   desc{name: name, help: help, kind: "gauge", labels: labels},
+		series: newSeries[float64](),
+	}
+	r.add(g)
+	return g
+}
+
+func (g *Gauge) Add(v float64, labels ...string) {
+	g.series.with(labels, newZero, func(n *float64) { *n += v })
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.writeHeader(w)
+	g.series.each(func(labels []string, v *float64) {
+		g.writeSample(w, "", labels, "", *v)
+	})
+}
+
+// gaugeFunc reads its value when the metrics are served.
+type gaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+// GaugeFunc adds a gauge without labels whose value is read by fn.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.add(&gaugeFunc{desc: desc{name: name, help: help, kind: "gauge"}, fn: fn})
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	g.writeHeader(w)
+	g.writeSample(w, "", nil, "", g.fn())
+}
+
+// DefaultBuckets suit latencies from a millisecond to a minute, in seconds.
+var DefaultBuckets = []float64{
+	0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60,
+}
+
+// Histogram counts observations in buckets of their upper bounds, per
+// combination of labels.
+type Histogram struct {
+	desc
+	buckets []float64
+	series  series[histogramValue]
+}
+
+type histogramValue struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (r *Registry) Histogram(
+	name, help string, buckets []float64, labels ...string,
+) *Histogram {
+	h := &Histogram{
+		desc:    desc{name: name, help: help, kind: "histogram", labels: labels},
+		buckets: slices.Sorted(slices.Values(buckets)),
+		series:  newSeries[histogramValue](),
+	}
+	r.add(h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, labels ...string) {
+	init := func() *histogramValue {
+		return &histogramValue{counts: make([]uint64, len(h.buckets))}
+	}
+	h.series.with(labels, init, func(hv *histogramValue) {
+		if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+			hv.counts[i]++
+		}
+		hv.count++
+		hv.sum += v
+	})
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.writeHeader(w)
+	h.series.each(func(labels []string, hv *histogramValue) {
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += hv.counts[i]
+			h.writeSample(w, "_bucket", labels, formatFloat(bound), float64(cumulative))
+		}
+		h.writeSample(w, "_bucket", labels, "+Inf", float64(hv.count))
+		h.writeSample(w, "_sum", labels, "", hv.sum)
+		h.writeSample(w, "_count", labels, "", float64(hv.count))
+	})
+}
+
+func newZero() *float64 {
+	return new(float64)
+}
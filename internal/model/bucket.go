@@ -3,9 +3,25 @@ package model
 type Bucket struct {
 	Name      *string `json:"name"`
 	CreatedAt *string `json:"created_at"`
+	Region    *string `json:"region,omitempty"`
+	Owner     *Owner  `json:"owner,omitempty"`
 }
 
 type ListBucketsOptions struct {
 	Filter            *string
 	ContinuationToken *string
 }
+
+type CreateBucketOptions struct {
+	Region          string
+	ObjectLock      *ObjectLockConfig
+	ObjectOwnership string
+	Versioning      bool
+	Tags            map[string]string
+	Encryption      *BucketEncryption
+}
+
+type BucketEncryption struct {
+	Algorithm string  `json:"algorithm"`
+	KMSKeyID  *string `json:"kms_key_id,omitempty"`
+}
@@ -0,0 +1,27 @@
+package model
+
+// ObjectRef names an object in any bucket.
+type ObjectRef struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+}
+
+// DiffSide is one of the two compared objects.
+type DiffSide struct {
+	ObjectRef
+	Size         int64   `json:"size"`
+	ETag         *string `json:"etag,omitempty"`
+	ContentType  *string `json:"content_type,omitempty"`
+	LastModified *string `json:"last_modified,omitempty"`
+	SHA256       string  `json:"sha256"`
+}
+
+// ObjectDiff compares two objects. When both are text, Diff holds a unified
+// line diff of them; it's empty when they're identical or binary.
+type ObjectDiff struct {
+	Left      DiffSide `json:"left"`
+	Right     DiffSide `json:"right"`
+	Text      bool     `json:"text"`
+	Identical bool     `json:"identical"`
+	Diff      string   `json:"diff,omitempty"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "net/http"
+
+// S3Error is a failed S3 request, as it's reported to clients. Status and
+// Message are what the request means to them, the rest is what S3 answered,
+// so the request can be looked up on its side.
+type S3Error struct {
+	Status    int    `json:"-"`
+	Message   string `json:"-"`
+	Code      string `json:"code,omitempty"`
+	S3Message string `json:"message,omitempty"`
+	S3Status  int    `json:"status,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	HostID    string `json:"host_id,omitempty"`
+	Err       error  `json:"-"`
+}
+
+func (e *S3Error) Error() string {
+	msg := http.StatusText(e.Status)
+	if e.Message != msg {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *S3Error) Unwrap() error {
+	return e.Err
+}
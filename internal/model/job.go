@@ -0,0 +1,72 @@
+package model
+
+const (
+	JobDelete       = "delete"
+	JobDeleteBucket = "delete_bucket"
+	JobCopy         = "copy"
+	JobMove         = "move"
+	JobSync         = "sync"
+	JobFetch        = "fetch"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// JobSpec describes the operation a job runs. Keys and Prefixes are used by
+// delete jobs, which move them to the trash unless Permanent is set, Prefix and the destination by copy, move and sync jobs. Sync
+// jobs also remove what's missing from the source when Delete is set. Fetch
+// jobs download URL into Key. Copies and fetches are stored in StorageClass,
+// when it's set.
+// Deleting a bucket or prefixes needs the Confirm token from a dry run, a
+// sync that deletes the one from its diff. It's not kept once the job is
+// accepted. DestConnection is always the configured
+// S3 endpoint, other connections are rejected.
+type JobSpec struct {
+	Type           string   `json:"type"`
+	Bucket         string   `json:"bucket"`
+	Keys           []string `json:"keys,omitempty"`
+	Prefixes       []string `json:"prefixes,omitempty"`
+	Prefix         string   `json:"prefix,omitempty"`
+	DestBucket     string   `json:"dest_bucket,omitempty"`
+	DestPrefix     string   `json:"dest_prefix,omitempty"`
+	DestConnection string   `json:"dest_connection,omitempty"`
+	Delete         bool     `json:"delete,omitempty"`
+	Permanent      bool     `json:"permanent,omitempty"`
+	DeletedBy      string   `json:"-"`
+	URL            string   `json:"url,omitempty"`
+	Key            string   `json:"key,omitempty"`
+	StorageClass   string   `json:"storage_class,omitempty"`
+	Confirm        string   `json:"confirm,omitempty"`
+}
+
+type Job struct {
+	ID         string       `json:"id"`
+	Spec       JobSpec      `json:"spec"`
+	Status     string       `json:"status"`
+	Progress   JobProgress  `json:"progress"`
+	Failures   []JobFailure `json:"failures,omitempty"`
+	Error      *string      `json:"error,omitempty"`
+	CreatedAt  string       `json:"created_at"`
+	StartedAt  *string      `json:"started_at,omitempty"`
+	FinishedAt *string      `json:"finished_at,omitempty"`
+}
+
+// JobProgress counts the objects handled so far. The totals are set once the
+// job knows how many objects or bytes it has to go through.
+type JobProgress struct {
+	ObjectsTotal  *int64 `json:"objects_total,omitempty"`
+	ObjectsDone   int64  `json:"objects_done"`
+	ObjectsFailed int64  `json:"objects_failed"`
+	BytesTotal    *int64 `json:"bytes_total,omitempty"`
+	BytesDone     int64  `json:"bytes_done"`
+}
+
+type JobFailure struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
@@ -1,14 +1,179 @@
 package model
 
+import "time"
+
+const (
+	SortByName     = "name"
+	SortBySize     = "size"
+	SortByModified = "modified"
+)
+
+// StorageClasses are the classes objects can be uploaded or copied into.
+// Objects in GLACIER and DEEP_ARCHIVE have to be restored before they can be
+// read.
+var StorageClasses = []string{
+	"STANDARD",
+	"REDUCED_REDUNDANCY",
+	"STANDARD_IA",
+	"ONEZONE_IA",
+	"INTELLIGENT_TIERING",
+	"GLACIER_IR",
+	StorageGlacier,
+	StorageDeepArchive,
+}
+
+const (
+	StorageGlacier     = "GLACIER"
+	StorageDeepArchive = "DEEP_ARCHIVE"
+)
+
+// RestoreTiers are how fast an archived object is restored. DEEP_ARCHIVE
+// objects can't be restored with the expedited tier.
+const (
+	RestoreTierExpedited = "Expedited"
+	RestoreTierStandard  = "Standard"
+	RestoreTierBulk      = "Bulk"
+)
+
 type Object struct {
-	Key          *string `json:"key"`
-	IsDir        bool    `json:"is_dir"`
-	Size         *int64  `json:"size,omitempty"`
-	LastModified *string `json:"last_modified,omitempty"`
+	Key                *string           `json:"key"`
+	IsDir              bool              `json:"is_dir"`
+	Size               *int64            `json:"size,omitempty"`
+	LastModified       *string           `json:"last_modified,omitempty"`
+	ETag               *string           `json:"etag,omitempty"`
+	StorageClass       *string           `json:"storage_class,omitempty"`
+	ChecksumAlgorithms []string          `json:"checksum_algorithms,omitempty"`
+	Owner              *Owner            `json:"owner,omitempty"`
+	ObjectCount        *int64            `json:"object_count,omitempty"`
+	ContentType        *string           `json:"content_type,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+	// Checksums are base64 encoded by algorithm, as S3 keeps them. Those of
+	// multipart uploads can be of the parts, ending with the parts count.
+	Checksums map[string]string `json:"checksums,omitempty"`
+	// Restore is set for archived objects that were asked to be restored.
+	Restore *RestoreStatus `json:"restore,omitempty"`
+}
+
+// RestoreStatus tells whether a restore of an archived object is still
+// running, and until when the restored copy can be read once it's done.
+type RestoreStatus struct {
+	InProgress bool    `json:"in_progress"`
+	ExpiresAt  *string `json:"expires_at,omitempty"`
+}
+
+type Owner struct {
+	ID          *string `json:"id,omitempty"`
+	DisplayName *string `json:"display_name,omitempty"`
 }
 
 type ListObjectsOption struct {
 	Path              string
 	Filter            string
 	ContinuationToken *string
+	Recursive         bool
+	SortBy            string
+	Descending        bool
+	MinSize           *int64
+	MaxSize           *int64
+	ModifiedAfter     *time.Time
+	ModifiedBefore    *time.Time
+	Extensions        []string
+	StorageClasses    []string
+}
+
+// DeleteObjectOptions controls how an object is deleted. Unless Permanent is
+// set, the object, or every object under it when Recursive is set, goes to
+// the trash when it's enabled; DeletedBy is kept along with it.
+type DeleteObjectOptions struct {
+	Recursive bool
+	Permanent bool
+	DeletedBy string
+}
+
+// DeleteObjectsOptions controls how the objects of a bulk delete are deleted.
+// Unless Permanent is set, the keys and the objects under the prefixes go to
+// the trash when it's enabled, the same as single objects.
+type DeleteObjectsOptions struct {
+	Permanent bool
+	DeletedBy string
+}
+
+// PutObjectOptions controls how an object is stored. An empty StorageClass
+// leaves it to the bucket's default.
+type PutObjectOptions struct {
+	StorageClass string
+}
+
+// RestoreObjectOptions asks for an archived object to be readable for Days,
+// restored with Tier.
+type RestoreObjectOptions struct {
+	Days int32  `json:"days"`
+	Tier string `json:"tier"`
+}
+
+// DeleteResult is the outcome of deleting a single key in a bulk delete.
+type DeleteResult struct {
+	Key     string  `json:"key"`
+	Deleted bool    `json:"deleted"`
+	Error   *string `json:"error,omitempty"`
+}
+
+// ObjectContent is a text object read whole, to be edited. ETag is what it
+// has to still match when it's saved back.
+type ObjectContent struct {
+	Key          string  `json:"key"`
+	Content      string  `json:"content"`
+	Size         int64   `json:"size"`
+	ETag         string  `json:"etag"`
+	ContentType  string  `json:"content_type,omitempty"`
+	LastModified *string `json:"last_modified,omitempty"`
+}
+
+// Thumbnail is a scaled down image, made from the object version with ETag.
+type Thumbnail struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+}
+
+const (
+	ChecksumSHA256 = "sha256"
+	ChecksumMD5    = "md5"
+
+	ChecksumStored   = "stored"
+	ChecksumComputed = "computed"
+)
+
+// ObjectChecksum is a hex encoded checksum of an object's content. Source
+// tells whether it was stored with the object or computed by reading it.
+// Matches compares a computed checksum with the stored one, when there's one.
+type ObjectChecksum struct {
+	Key       string  `json:"key"`
+	Algorithm string  `json:"algorithm"`
+	Checksum  string  `json:"checksum"`
+	Source    string  `json:"source"`
+	Size      int64   `json:"size"`
+	ETag      *string `json:"etag,omitempty"`
+	Matches   *bool   `json:"matches,omitempty"`
+}
+
+// UploadResult is the outcome of uploading a single file in a folder upload.
+type UploadResult struct {
+	Key         string  `json:"key"`
+	Uploaded    bool    `json:"uploaded"`
+	Size        int64   `json:"size"`
+	ContentType string  `json:"content_type,omitempty"`
+	Error       *string `json:"error,omitempty"`
+}
+
+// DeletePreview describes what a recursive delete would remove. Token has to
+// be presented before ExpiresAt to carry out the deletion.
+type DeletePreview struct {
+	Bucket    string   `json:"bucket"`
+	Prefixes  []string `json:"prefixes"`
+	Objects   int64    `json:"objects"`
+	Bytes     int64    `json:"bytes"`
+	Sample    []string `json:"sample"`
+	Token     string   `json:"token"`
+	ExpiresAt string   `json:"expires_at"`
 }
@@ -0,0 +1,26 @@
+package model
+
+const (
+	RetentionGovernance = "GOVERNANCE"
+	RetentionCompliance = "COMPLIANCE"
+)
+
+type ObjectLockConfig struct {
+	Enabled          bool              `json:"enabled"`
+	DefaultRetention *DefaultRetention `json:"default_retention,omitempty"`
+}
+
+type DefaultRetention struct {
+	Mode  string `json:"mode"`
+	Days  *int32 `json:"days,omitempty"`
+	Years *int32 `json:"years,omitempty"`
+}
+
+type ObjectRetention struct {
+	Mode        *string `json:"mode,omitempty"`
+	RetainUntil *string `json:"retain_until,omitempty"`
+}
+
+type LegalHold struct {
+	Enabled bool `json:"enabled"`
+}
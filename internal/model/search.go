@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+const (
+	SearchSubstring = "substring"
+	SearchGlob      = "glob"
+	SearchRegex     = "regex"
+)
+
+type SearchOptions struct {
+	Prefix         string
+	Query          string
+	Mode           string
+	MinSize        *int64
+	MaxSize        *int64
+	ModifiedAfter  *time.Time
+	ModifiedBefore *time.Time
+	Limit          int
+}
+
+// SearchResult summarizes a finished search.
+type SearchResult struct {
+	Matched   int  `json:"matched"`
+	Scanned   int  `json:"scanned"`
+	Truncated bool `json:"truncated"`
+}
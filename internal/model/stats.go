@@ -0,0 +1,36 @@
+package model
+
+const (
+	StatsRunning   = "running"
+	StatsDone      = "done"
+	StatsCancelled = "cancelled"
+	StatsFailed    = "failed"
+)
+
+type BucketStats struct {
+	Bucket         string                  `json:"bucket"`
+	Prefix         string                  `json:"prefix"`
+	Status         string                  `json:"status"`
+	Error          *string                 `json:"error,omitempty"`
+	ObjectCount    int64                   `json:"object_count"`
+	TotalBytes     int64                   `json:"total_bytes"`
+	Largest        []Object                `json:"largest"`
+	Histogram      []SizeRange             `json:"histogram"`
+	StorageClasses map[string]StorageUsage `json:"storage_classes"`
+	StartedAt      string                  `json:"started_at"`
+	FinishedAt     *string                 `json:"finished_at,omitempty"`
+}
+
+// SizeRange counts the objects whose size is below UpperBound and at least
+// the previous range's bound. The last range has no upper bound.
+type SizeRange struct {
+	Label      string `json:"label"`
+	UpperBound *int64 `json:"upper_bound,omitempty"`
+	Count      int64  `json:"count"`
+	Bytes      int64  `json:"bytes"`
+}
+
+type StorageUsage struct {
+	Count int64 `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
@@ -0,0 +1,29 @@
+package model
+
+// SyncEntry is an object a sync would copy or delete. Key is relative to the
+// synced prefixes.
+type SyncEntry struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+}
+
+// SyncChanges counts the objects of one kind of change. Entries lists the
+// first of them, in key order.
+type SyncChanges struct {
+	Count     int64       `json:"count"`
+	Bytes     int64       `json:"bytes"`
+	Entries   []SyncEntry `json:"entries"`
+	Truncated bool        `json:"truncated"`
+}
+
+// SyncDiff is what a sync would do: copy the new and changed objects, and
+// delete those missing from the source when asked to. A sync that deletes
+// needs Token to run.
+type SyncDiff struct {
+	New       SyncChanges `json:"new"`
+	Changed   SyncChanges `json:"changed"`
+	Deleted   SyncChanges `json:"deleted"`
+	Unchanged int64       `json:"unchanged"`
+	Token     string      `json:"token,omitempty"`
+	ExpiresAt string      `json:"expires_at,omitempty"`
+}
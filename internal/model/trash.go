@@ -0,0 +1,13 @@
+package model
+
+// TrashItem is an object that was moved to the trash. ID identifies it within
+// the trash of the bucket it was deleted from.
+type TrashItem struct {
+	ID        string  `json:"id"`
+	Bucket    string  `json:"bucket"`
+	Key       string  `json:"key"`
+	Size      int64   `json:"size"`
+	DeletedAt string  `json:"deleted_at"`
+	DeletedBy *string `json:"deleted_by,omitempty"`
+	PurgeAt   *string `json:"purge_at,omitempty"`
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+// applyBucketOptions applies the creation options which S3 only accepts on an
+// existing bucket.
+func (s *Services) applyBucketOptions(
+	ctx context.Context, name string, opts model.CreateBucketOptions,
+) error {
+	// Object lock turns versioning on by itself.
+	if opts.Versioning && (opts.ObjectLock == nil || !opts.ObjectLock.Enabled) {
+		_, err := s.s3Client.PutBucketVersioning(
+			ctx,
+			&s3.PutBucketVersioningInput{
+				Bucket: aws.String(name),
+				VersioningConfiguration: &types.VersioningConfiguration{
+					Status: types.BucketVersioningStatusEnabled,
+				},
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("enabling versioning: %w", mapS3ErrToAppErr(err))
+		}
+	}
+
+	if opts.Encryption != nil {
+		rule := types.ServerSideEncryptionRule{
+			ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{
+				SSEAlgorithm:   types.ServerSideEncryption(opts.Encryption.Algorithm),
+				KMSMasterKeyID: opts.Encryption.KMSKeyID,
+			},
+		}
+		_, err := s.s3Client.PutBucketEncryption(
+			ctx,
+			&s3.PutBucketEncryptionInput{
+				Bucket: aws.String(name),
+				ServerSideEncryptionConfiguration: &types.ServerSideEncryptionConfiguration{
+					Rules: []types.ServerSideEncryptionRule{rule},
+				},
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("setting encryption: %w", mapS3ErrToAppErr(err))
+		}
+	}
+
+	if len(opts.Tags) > 0 {
+		tags := make([]types.Tag, 0, len(opts.Tags))
+		for _, k := range slices.Sorted(maps.Keys(opts.Tags)) {
+			tags = append(tags, types.Tag{
+				Key: aws.String(k), Value: aws.String(opts.Tags[k]),
+			})
+		}
+		_, err := s.s3Client.PutBucketTagging(
+			ctx,
+			&s3.PutBucketTaggingInput{
+				Bucket:  aws.String(name),
+				Tagging: &types.Tagging{TagSet: tags},
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("setting tags: %w", mapS3ErrToAppErr(err))
+		}
+	}
+
+	if opts.ObjectLock != nil && opts.ObjectLock.DefaultRetention != nil {
+		err := s.PutBucketObjectLock(ctx, name, *opts.ObjectLock)
+		if err != nil {
+			return fmt.Errorf("setting object lock configuration: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	// deleteBatchSize is the most keys a single DeleteObjects call accepts.
+	deleteBatchSize   = 1000
+	deleteConcurrency = 4
+)
+
+// deleteTarget is a key to delete, with its size when it's known from a
+// listing.
+type deleteTarget struct {
+	key  string
+	size int64
+}
+
+// DeleteObjects deletes the given keys, along with every object under the
+// given prefixes, in batches. Objects the trash keeps are moved to it
+// instead, unless opts asks for a permanent delete. Failures are reported
+// per key rather than stopping the whole operation; a prefix that can't be
+// listed is reported under the prefix itself.
+func (s *Services) DeleteObjects(
+	ctx context.Context,
+	bucketName string,
+	keys, prefixes []string,
+	opts model.DeleteObjectsOptions,
+) ([]model.DeleteResult, error) {
+	targets, results := s.collectDeleteTargets(ctx, bucketName, keys, prefixes)
+	if !opts.Permanent {
+		var mu sync.Mutex
+		targets = s.trashTargets(ctx, bucketName, targets, opts.DeletedBy, func(r model.DeleteResult, _ int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, r)
+		})
+	}
+	batches := (len(targets) + deleteBatchSize - 1) / deleteBatchSize
+	batchResults := make([][]model.DeleteResult, batches)
+	s.deleteTargets(ctx, bucketName, targets, func(i int, batch []model.DeleteResult, _ int64) {
+		batchResults[i] = batch
+	})
+	for _, r := range batchResults {
+		results = append(results, r...)
+	}
+	return results, nil
+}
+
+// collectDeleteTargets expands the prefixes and drops duplicate keys. Prefixes
+// that can't be listed are returned as failures.
+func (s *Services) collectDeleteTargets(
+	ctx context.Context, bucketName string, keys, prefixes []string,
+) ([]deleteTarget, []model.DeleteResult) {
+	var failures []model.DeleteResult
+	seen := make(map[string]bool, len(keys))
+	var targets []deleteTarget
+	add := func(t deleteTarget) {
+		if !seen[t.key] {
+			seen[t.key] = true
+			targets = append(targets, t)
+		}
+	}
+	for _, key := range keys {
+		add(deleteTarget{key: key})
+	}
+	for _, prefix := range prefixes {
+		err := s.walkPrefix(ctx, bucketName, prefix, func(obj types.Object) {
+			add(deleteTarget{key: aws.ToString(obj.Key), size: aws.ToInt64(obj.Size)})
+		})
+		if err != nil {
+			failures = append(failures, failedDelete(prefix, mapS3ErrToAppErr(err)))
+		}
+	}
+	return targets, failures
+}
+
+// deleteTargets deletes the targets in batches, running up to
+// deleteConcurrency calls at once. onBatch is called, possibly concurrently,
+// with each batch's index, its results and the bytes it freed.
+func (s *Services) deleteTargets(
+	ctx context.Context,
+	bucketName string,
+	targets []deleteTarget,
+	onBatch func(i int, results []model.DeleteResult, bytes int64),
+) {
+	sem := make(chan struct{}, deleteConcurrency)
+	var wg sync.WaitGroup
+	for i, batch := range slices.Collect(slices.Chunk(targets, deleteBatchSize)) {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results := s.deleteBatch(ctx, bucketName, batch)
+			var bytes int64
+			for j, r := range results {
+				if r.Deleted {
+					bytes += batch[j].size
+				}
+			}
+			onBatch(i, results, bytes)
+		}()
+	}
+	wg.Wait()
+}
+
+// deleteBatch deletes up to deleteBatchSize keys in a single call.
+func (s *Services) deleteBatch(
+	ctx context.Context, bucketName string, targets []deleteTarget,
+) []model.DeleteResult {
+	results := make([]model.DeleteResult, len(targets))
+	if err := ctx.Err(); err != nil {
+		for i, t := range targets {
+			results[i] = failedDelete(t.key, err)
+		}
+		return results
+	}
+
+	ids := make([]types.ObjectIdentifier, len(targets))
+	for i, t := range targets {
+		ids[i] = types.ObjectIdentifier{Key: aws.String(t.key)}
+	}
+	out, err := s.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(bucketName),
+		Delete: &types.Delete{Objects: ids, Quiet: aws.Bool(true)},
+	})
+	if err != nil {
+		err = mapS3ErrToAppErr(err)
+		for i, t := range targets {
+			results[i] = failedDelete(t.key, err)
+		}
+		return results
+	}
+
+	// In quiet mode, only the failures are reported back.
+	failures := make(map[string]string, len(out.Errors))
+	for _, f := range out.Errors {
+		failures[aws.ToString(f.Key)] = aws.ToString(f.Message)
+	}
+	for i, t := range targets {
+		if msg, ok := failures[t.key]; ok {
+			results[i] = model.DeleteResult{Key: t.key, Error: aws.String(msg)}
+			continue
+		}
+		results[i] = model.DeleteResult{Key: t.key, Deleted: true}
+	}
+	return results
+}
+
+// walkPrefix calls fn for every object under prefix.
+func (s *Services) walkPrefix(
+	ctx context.Context, bucketName, prefix string, fn func(types.Object),
+) error {
+	params := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}
+	for {
+		list, err := s.s3Client.ListObjectsV2(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, obj := range list.Contents {
+			fn(obj)
+		}
+		if list.NextContinuationToken == nil {
+			return nil
+		}
+		params.ContinuationToken = list.NextContinuationToken
+	}
+}
+
+func failedDelete(key string, err error) model.DeleteResult {
+	return model.DeleteResult{Key: key, Error: aws.String(err.Error())}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/slogger"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+// noChecksum turns off the checksums sent with uploads.
+const noChecksum = "NONE"
+
+var (
+	ErrChecksumMismatch    = errors.New("object content does not match its checksum")
+	ErrChecksumAlgorithm   = errors.New("checksum algorithm must be sha256 or md5")
+	errUnsupportedChecksum = errors.New("unsupported checksum algorithm")
+)
+
+// ParseChecksumAlgorithm validates the algorithm of the checksums sent with
+// uploads. Empty keeps the default, CRC32C, and "none" sends none.
+func ParseChecksumAlgorithm(name string) (types.ChecksumAlgorithm, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	switch name {
+	case "":
+		return types.ChecksumAlgorithmCrc32c, nil
+	case noChecksum:
+		return "", nil
+	}
+	algo := types.ChecksumAlgorithm(name)
+	if !slices.Contains(algo.Values(), algo) {
+		return "", fmt.Errorf("%w: %s", errUnsupportedChecksum, name)
+	}
+	return algo, nil
+}
+
+// WithChecksums sets the algorithm of the checksums computed and sent with
+// every upload, for S3 to reject the content if it arrives changed. Empty
+// sends none.
+func WithChecksums(algo types.ChecksumAlgorithm) Option {
+	return func(s *Services) {
+		s.checksum = algo
+	}
+}
+
+// ObjectChecksum returns the SHA-256 or MD5 of an object. A SHA-256 that was
+// stored with the object is returned as is, unless compute is set; otherwise
+// the object is read through and hashed, and a computed SHA-256 is compared
+// with the stored one, if there is one.
+func (s *Services) ObjectChecksum(
+	ctx context.Context, bucketName, objectKey, algorithm string, compute bool,
+) (*model.ObjectChecksum, error) {
+	var h hash.Hash
+	switch algorithm {
+	case model.ChecksumSHA256:
+		h = sha256.New()
+	case model.ChecksumMD5:
+		h = md5.New()
+	default:
+		return nil, errs.BadRequest(errs.WithMsg(ErrChecksumAlgorithm.Error()))
+	}
+	obj, err := s.StatObject(ctx, bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	sum := &model.ObjectChecksum{
+		Key:       objectKey,
+		Algorithm: algorithm,
+		Size:      aws.ToInt64(obj.Size),
+		ETag:      obj.ETag,
+	}
+
+	// Checksums of multipart uploads can be of their parts, ending in -N.
+	var stored []byte
+	if algorithm == model.ChecksumSHA256 {
+		if b64, ok := obj.Checksums[string(types.ChecksumAlgorithmSha256)]; ok && !strings.Contains(b64, "-") {
+			stored, _ = base64.StdEncoding.DecodeString(b64)
+		}
+	}
+	if stored != nil && !compute {
+		sum.Source = model.ChecksumStored
+		sum.Checksum = hex.EncodeToString(stored)
+		return sum, nil
+	}
+
+	body, _, err := s.GetObject(ctx, bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			slogger.Error(ctx, "closing object body", slogger.Err("error", err))
+		}
+	}()
+	// Reading to the end also verifies any checksum S3 sent along.
+	if _, err = io.Copy(h, body); err != nil {
+		return nil, mapS3ErrToAppErr(fmt.Errorf("reading object: %w", err))
+	}
+	computed := h.Sum(nil)
+	sum.Source = model.ChecksumComputed
+	sum.Checksum = hex.EncodeToString(computed)
+	if stored != nil {
+		sum.Matches = aws.Bool(bytes.Equal(stored, computed))
+	}
+	return sum, nil
+}
+
+// checksums collects the checksums S3 returned for an object by algorithm,
+// as base64 like S3 has them. It's nil when there are none.
+func checksums(crc32, crc32c, crc64nvme, sha1Sum, sha256Sum *string) map[string]string {
+	var m map[string]string
+	for algo, v := range map[types.ChecksumAlgorithm]*string{
+		types.ChecksumAlgorithmCrc32:     crc32,
+		types.ChecksumAlgorithmCrc32c:    crc32c,
+		types.ChecksumAlgorithmCrc64nvme: crc64nvme,
+		types.ChecksumAlgorithmSha1:      sha1Sum,
+		types.ChecksumAlgorithmSha256:    sha256Sum,
+	} {
+		if aws.ToString(v) == "" {
+			continue
+		}
+		if m == nil {
+			m = make(map[string]string)
+		}
+		m[string(algo)] = *v
+	}
+	return m
+}
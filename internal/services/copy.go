@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	copyConcurrency = 8
+	// maxCopySize is the largest object a single CopyObject call can copy.
+	maxCopySize = 5 << 30
+)
+
+var ErrCopyTooLarge = errors.New("objects larger than 5 GiB can't be copied in a single request")
+
+// runCopy copies every object under the source prefix to the destination,
+// keeping the rest of the key. Moving deletes the sources once all copies are
+// done, and only those that were copied. The job fails if any object couldn't
+// be copied or moved.
+func (s *Services) runCopy(ctx context.Context, r *jobRun, move bool) error {
+	spec := r.spec()
+	var objects []types.Object
+	err := s.walkPrefix(ctx, spec.Bucket, spec.Prefix, func(obj types.Object) {
+		objects = append(objects, obj)
+	})
+	if err != nil {
+		return mapS3ErrToAppErr(err)
+	}
+	r.setTotal(len(objects))
+
+	var mu sync.Mutex
+	var copied []deleteTarget
+	s.copyObjects(ctx, r, objects, func(t deleteTarget) {
+		if !move {
+			r.done(1, t.size)
+			return
+		}
+		mu.Lock()
+		copied = append(copied, t)
+		mu.Unlock()
+	})
+
+	if move && len(copied) > 0 {
+		s.deleteTargets(ctx, spec.Bucket, copied, func(_ int, results []model.DeleteResult, bytes int64) {
+			r.report(results, bytes)
+		})
+	}
+	if n := r.failed(); n > 0 {
+		if move {
+			return fmt.Errorf("%d object(s) could not be moved", n)
+		}
+		return fmt.Errorf("%d object(s) could not be copied", n)
+	}
+	return nil
+}
+
+// copyObjects copies the job's source objects to their destination, running
+// up to copyConcurrency copies at once. Failures are recorded on the job,
+// onCopied is called, possibly concurrently, for each object that was copied.
+func (s *Services) copyObjects(
+	ctx context.Context,
+	r *jobRun,
+	objects []types.Object,
+	onCopied func(deleteTarget),
+) {
+	spec := r.spec()
+	sem := make(chan struct{}, copyConcurrency)
+	var wg sync.WaitGroup
+	for _, obj := range objects {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			key, size := aws.ToString(obj.Key), aws.ToInt64(obj.Size)
+			destBucket, destKey := copyDestination(spec, key)
+			err := s.copyObject(
+				ctx, spec.Bucket, key, size, destBucket, destKey, spec.StorageClass,
+			)
+			if err != nil {
+				r.fail(key, err.Error())
+				return
+			}
+			onCopied(deleteTarget{key: key, size: size})
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Services) copyObject(
+	ctx context.Context,
+	bucketName, key string,
+	size int64,
+	destBucket, destKey, storageClass string,
+) error {
+	if size > maxCopySize {
+		return errs.BadRequest(errs.WithMsg(ErrCopyTooLarge.Error()))
+	}
+	_, err := s.s3Client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:       aws.String(destBucket),
+		Key:          aws.String(destKey),
+		CopySource:   aws.String(copySource(bucketName, key)),
+		StorageClass: types.StorageClass(storageClass),
+	})
+	return mapS3ErrToAppErr(err)
+}
+
+// copyDestination maps a source key below the job's prefix to its
+// destination.
+func copyDestination(spec model.JobSpec, key string) (string, string) {
+	return destBucket(spec), spec.DestPrefix + strings.TrimPrefix(key, spec.Prefix)
+}
+
+// destBucket is the bucket a job writes to, the source bucket by default.
+func destBucket(spec model.JobSpec) string {
+	if spec.DestBucket == "" {
+		return spec.Bucket
+	}
+	return spec.DestBucket
+}
+
+// copySource builds the URL encoded "bucket/key" source of a copy.
+func copySource(bucketName, key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return bucketName + "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	// deleteTokenTTL is how long a confirmation token from a dry run stays
+	// valid.
+	deleteTokenTTL = 10 * time.Minute
+	// deletePreviewSample is how many keys a dry run lists.
+	deletePreviewSample = 20
+)
+
+var (
+	ErrConfirmationRequired = errors.New("recursive deletes must be confirmed with the token from a dry run")
+	ErrConfirmationInvalid  = errors.New("confirmation token is invalid or has expired, run the dry run again")
+)
+
+// PreviewDelete walks the prefixes and reports how many objects and bytes a
+// recursive delete of them would remove, along with a token that confirms
+// the deletion. An empty prefix stands for the whole bucket.
+func (s *Services) PreviewDelete(
+	ctx context.Context, bucketName string, prefixes []string,
+) (*model.DeletePreview, error) {
+	preview := &model.DeletePreview{
+		Bucket:   bucketName,
+		Prefixes: prefixes,
+		Sample:   []string{},
+	}
+	seen := make(map[string]bool)
+	for _, prefix := range prefixes {
+		err := s.walkPrefix(ctx, bucketName, prefix, func(obj types.Object) {
+			key := aws.ToString(obj.Key)
+			if seen[key] {
+				return
+			}
+			seen[key] = true
+			preview.Objects++
+			preview.Bytes += aws.ToInt64(obj.Size)
+			if len(preview.Sample) < deletePreviewSample {
+				preview.Sample = append(preview.Sample, key)
+			}
+		})
+		if err != nil {
+			return nil, mapS3ErrToAppErr(err)
+		}
+	}
+
+	expires := time.Now().Add(deleteTokenTTL).Truncate(time.Second)
+	preview.Token = s.deleteToken(bucketName, prefixes, expires)
+	preview.ExpiresAt = aws.ToString(formatTime(&expires))
+	return preview, nil
+}
+
+// ConfirmDelete checks that token came from a dry run of deleting the same
+// prefixes in the same bucket, and that it hasn't expired yet.
+func (s *Services) ConfirmDelete(
+	bucketName string, prefixes []string, token string,
+) error {
+	return checkToken(token, func(expires time.Time) string {
+		return s.deleteToken(bucketName, prefixes, expires)
+	})
+}
+
+// checkToken checks token against the one sign returns for its expiry time,
+// and that it hasn't expired yet.
+func checkToken(token string, sign func(expires time.Time) string) error {
+	if token == "" {
+		return errs.New(
+			http.StatusPreconditionRequired,
+			errs.WithMsg(ErrConfirmationRequired.Error()),
+		)
+	}
+	invalid := errs.New(
+		http.StatusPreconditionFailed,
+		errs.WithMsg(ErrConfirmationInvalid.Error()),
+	)
+	raw, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return invalid
+	}
+	unix, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return invalid
+	}
+	expires := time.Unix(unix, 0)
+	want := sign(expires)
+	if !hmac.Equal([]byte(token), []byte(want)) || time.Now().After(expires) {
+		return invalid
+	}
+	return nil
+}
+
+// deleteToken signs the bucket, the prefixes and the expiry time. The order
+// of the prefixes doesn't matter.
+func (s *Services) deleteToken(
+	bucketName string, prefixes []string, expires time.Time,
+) string {
+	sorted := slices.Sorted(slices.Values(prefixes))
+	return s.signToken("delete", expires, append([]string{bucketName}, sorted...)...)
+}
+
+// signToken signs what a token confirms: its kind, so tokens of one kind of
+// operation can't confirm another, the parts and the expiry time.
+func (s *Services) signToken(kind string, expires time.Time, parts ...string) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	mac := hmac.New(sha256.New, s.deleteKey)
+	mac.Write([]byte(kind))
+	for _, part := range parts {
+		mac.Write([]byte{0})
+		mac.Write([]byte(part))
+	}
+	mac.Write([]byte{0})
+	mac.Write([]byte(exp))
+	return exp + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newDeleteKey() []byte {
+	key := make([]byte, 32)
+	// crypto/rand.Read never returns an error.
+	_, _ = rand.Read(key)
+	return key
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/slogger"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	// diffContext is how many unchanged lines surround each change.
+	diffContext = 3
+	// maxDiffEdits caps the work spent on finding the shortest diff. Past
+	// it, the differing lines are shown as removed and then added.
+	maxDiffEdits = 2000
+)
+
+var ErrDiffTooLarge = errors.New("object is too large to be compared")
+
+// DiffObjects compares two objects of up to maxSize bytes each. Text objects
+// get a unified line diff, binary ones are compared by size, ETag and their
+// SHA-256 checksum.
+func (s *Services) DiffObjects(
+	ctx context.Context, left, right model.ObjectRef, maxSize int64,
+) (*model.ObjectDiff, error) {
+	leftSide, leftData, err := s.readDiffSide(ctx, left, maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("reading left: %w", err)
+	}
+	rightSide, rightData, err := s.readDiffSide(ctx, right, maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("reading right: %w", err)
+	}
+
+	d := &model.ObjectDiff{
+		Left:      *leftSide,
+		Right:     *rightSide,
+		Text:      isText(leftData) && isText(rightData),
+		Identical: bytes.Equal(leftData, rightData),
+	}
+	if d.Text && !d.Identical {
+		d.Diff = unifiedDiff(
+			left.Bucket+"/"+left.Key,
+			right.Bucket+"/"+right.Key,
+			diffLines(splitLines(string(leftData)), splitLines(string(rightData))),
+		)
+	}
+	return d, nil
+}
+
+// readDiffSide reads an object whole, refusing it before the download when
+// it's larger than maxSize.
+func (s *Services) readDiffSide(
+	ctx context.Context, ref model.ObjectRef, maxSize int64,
+) (*model.DiffSide, []byte, error) {
+	obj, err := s.StatObject(ctx, ref.Bucket, ref.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if aws.ToInt64(obj.Size) > maxSize {
+		return nil, nil, errs.New(
+			http.StatusRequestEntityTooLarge, errs.WithMsg(ErrDiffTooLarge.Error()),
+		)
+	}
+	body, _, err := s.GetObject(ctx, ref.Bucket, ref.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			slogger.Error(ctx, "closing object body", slogger.Err("error", err))
+		}
+	}()
+	data, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading object: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, nil, errs.New(
+			http.StatusRequestEntityTooLarge, errs.WithMsg(ErrDiffTooLarge.Error()),
+		)
+	}
+
+	sum := sha256.Sum256(data)
+	return &model.DiffSide{
+		ObjectRef:    ref,
+		Size:         int64(len(data)),
+		ETag:         obj.ETag,
+		ContentType:  obj.ContentType,
+		LastModified: obj.LastModified,
+		SHA256:       hex.EncodeToString(sum[:]),
+	}, data, nil
+}
+
+// isText reports whether data is UTF-8 without NUL bytes, which text files
+// don't have.
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) == -1
+}
+
+// splitLines splits text after each newline, so a missing newline at the end
+// shows up as a change.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffOp is a line kept (' '), removed ('-') or added ('+').
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines finds the shortest edit from a to b with Myers' algorithm, after
+// setting aside the lines they start and end with.
+func diffLines(a, b []string) []diffOp {
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		start++
+	}
+	endA, endB := len(a), len(b)
+	for endA > start && endB > start && a[endA-1] == b[endB-1] {
+		endA--
+		endB--
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b)-start)
+	for _, line := range a[:start] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, myers(a[start:endA], b[start:endB])...)
+	for _, line := range a[endA:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// myers keeps the furthest reaching path of every diagonal after each edit,
+// and walks them back from the end once both texts are used up.
+func myers(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceAll(a, b)
+	}
+	limit := n + m
+	v := make([]int, 2*limit+2)
+	off := limit + 1
+	// trace[d] holds v[-d..d] as it was after the d-th edit.
+	var trace [][]int
+	done := false
+	for d := 0; d <= limit && !done; d++ {
+		if d > maxDiffEdits {
+			return replaceAll(a, b)
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+	}
+
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{'+', b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{'-', a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{' ', a[x]})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+func replaceAll(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+// unifiedDiff formats ops the way diff -u does, with diffContext lines of
+// context around each hunk.
+func unifiedDiff(leftName, rightName string, ops []diffOp) string {
+	// aAt and bAt are the line indexes of each op in a and b.
+	aAt := make([]int, len(ops)+1)
+	bAt := make([]int, len(ops)+1)
+	var changes []int
+	for i, op := range ops {
+		aAt[i+1], bAt[i+1] = aAt[i], bAt[i]
+		if op.kind != '+' {
+			aAt[i+1]++
+		}
+		if op.kind != '-' {
+			bAt[i+1]++
+		}
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", leftName, rightName)
+	for i := 0; i < len(changes); {
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j] <= 2*diffContext+1 {
+			j++
+		}
+		from := max(changes[i]-diffContext, 0)
+		to := min(changes[j]+diffContext+1, len(ops))
+		fmt.Fprintf(
+			&sb, "@@ -%s +%s @@\n",
+			hunkRange(aAt[from], aAt[to]-aAt[from]),
+			hunkRange(bAt[from], bAt[to]-bAt[from]),
+		)
+		for _, op := range ops[from:to] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = j + 1
+	}
+	return sb.String()
+}
+
+// hunkRange formats the start and length of a hunk, where an empty hunk
+// starts at the line before it.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	// maxDiskUsageObjects caps the objects DiskUsage reads below a path.
+	maxDiskUsageObjects = 100_000
+	// diskUsageBudget is how long DiskUsage keeps listing, well within the
+	// server's default write timeout.
+	diskUsageBudget = 20 * time.Second
+)
+
+// DiskUsage lists the direct children of path, like ListObjects, but with
+// the total size and object count of every folder. It walks everything below
+// path in a single flat listing, so its cost grows with the number of nested
+// objects rather than folders. The walk stops after maxDiskUsageObjects or
+// diskUsageBudget, the sizes then only cover the objects read so far and
+// truncated is set. The trash is left out, as it is from listings.
+func (s *Services) DiskUsage(
+	ctx context.Context, bucketName, path string,
+) (objects []model.Object, truncated bool, err error) {
+	prefix := path
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	params := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}
+
+	deadline := time.Now().Add(diskUsageBudget)
+	dirs := make(map[string]*model.Object)
+	var files []model.Object
+	read := 0
+	for {
+		list, err := s.s3Client.ListObjectsV2(ctx, params)
+		if err != nil {
+			return nil, false, mapS3ErrToAppErr(err)
+		}
+		read += len(list.Contents)
+		for _, obj := range list.Contents {
+			key := aws.ToString(obj.Key)
+			if s.trash.hides(key) {
+				continue
+			}
+			rel := strings.TrimPrefix(key, prefix)
+			if rel == "" {
+				// The folder marker of path itself.
+				continue
+			}
+			name, _, nested := strings.Cut(rel, "/")
+			if !nested {
+				files = append(files, toModelObject(obj, prefix))
+				continue
+			}
+			dir, ok := dirs[name]
+			if !ok {
+				dir = &model.Object{
+					Key:         aws.String(name),
+					IsDir:       true,
+					Size:        aws.Int64(0),
+					ObjectCount: aws.Int64(0),
+				}
+				dirs[name] = dir
+			}
+			// Markers of nested folders aren't counted, an empty one stays
+			// listed.
+			if !isFolderMarker(obj) {
+				*dir.Size += aws.ToInt64(obj.Size)
+				*dir.ObjectCount++
+			}
+		}
+		if list.NextContinuationToken == nil {
+			break
+		}
+		if read >= maxDiskUsageObjects || time.Now().After(deadline) {
+			truncated = true
+			break
+		}
+		params.ContinuationToken = list.NextContinuationToken
+	}
+
+	objects = make([]model.Object, 0, len(dirs)+len(files))
+	for _, dir := range dirs {
+		objects = append(objects, *dir)
+	}
+	slices.SortFunc(objects, func(a, b model.Object) int {
+		return cmp.Compare(*a.Key, *b.Key)
+	})
+	return append(objects, files...), truncated, nil
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/slogger"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	// fetchPartSize is how much of a fetched body is held in memory at once.
+	// Smaller bodies are put in one request, larger ones in parts of it.
+	fetchPartSize = 16 * 1024 * 1024
+	// maxFetchRedirects caps the redirects followed, each of them has to
+	// lead to an allowed host as well.
+	maxFetchRedirects = 5
+)
+
+var (
+	ErrFetchDisabled   = errors.New("fetching from URLs is not enabled")
+	ErrHostNotAllowed  = errors.New("host is not in the list of allowed hosts")
+	ErrFetchTooLarge   = errors.New("remote file is larger than the maximum size")
+	ErrFetchBadScheme  = errors.New("only http and https URLs can be fetched")
+	ErrFetchBadStatus  = errors.New("remote server did not return the file")
+	errTooManyRedirect = errors.New("too many redirects")
+)
+
+// fetcher downloads files from allowed hosts into buckets. Hosts are matched
+// by name, a "*." prefix matches any of its subdomains.
+type fetcher struct {
+	client   *http.Client
+	hosts    []fetchHost
+	maxSize  int64
+	partSize int64
+}
+
+// fetchHost is an entry of the allowed hosts. Without a port, only the
+// default port of the URL's scheme is allowed.
+type fetchHost struct {
+	name string
+	port string
+}
+
+func parseFetchHost(entry string) fetchHost {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if name, port, err := net.SplitHostPort(entry); err == nil {
+		return fetchHost{name: name, port: port}
+	}
+	return fetchHost{name: strings.Trim(entry, "[]")}
+}
+
+func (h fetchHost) matches(host, port string) bool {
+	if h.port != port {
+		return false
+	}
+	suffix, wildcard := strings.CutPrefix(h.name, "*")
+	return host == h.name || (wildcard && strings.HasSuffix(host, suffix))
+}
+
+// WithFetch lets fetch jobs download files of up to maxSize bytes from the
+// given hosts. Without it, fetch jobs are rejected.
+func WithFetch(allowedHosts []string, maxSize int64) Option {
+	return func(s *Services) {
+		f := &fetcher{maxSize: maxSize, partSize: fetchPartSize}
+		for _, h := range allowedHosts {
+			f.hosts = append(f.hosts, parseFetchHost(h))
+		}
+		f.client = &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxFetchRedirects {
+					return errTooManyRedirect
+				}
+				return f.check(req.URL)
+			},
+		}
+		s.fetcher = f
+	}
+}
+
+// check makes sure u may be fetched, so the server can't be made to reach
+// hosts it shouldn't.
+func (f *fetcher) check(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errs.BadRequest(errs.WithMsg(ErrFetchBadScheme.Error()))
+	}
+	host := strings.ToLower(u.Hostname())
+	// The default port is the same as none, so either form of the URL matches
+	// an entry without a port.
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	for _, allowed := range f.hosts {
+		if allowed.matches(host, port) {
+			return nil
+		}
+	}
+	return errs.Forbidden(errs.WithMsg(ErrHostNotAllowed.Error()))
+}
+
+// checkFetch validates the URL of a fetch job before it's queued.
+func (s *Services) checkFetch(rawURL string) error {
+	if s.fetcher == nil {
+		return errs.Forbidden(errs.WithMsg(ErrFetchDisabled.Error()))
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errs.BadRequest(errs.WithMsg(err.Error()))
+	}
+	return s.fetcher.check(u)
+}
+
+// runFetch streams the body of the job's URL into its key. It's never
+// written to disk; bodies larger than a part are put with a multipart upload.
+func (s *Services) runFetch(ctx context.Context, r *jobRun) error {
+	spec := r.spec()
+	if err := s.checkFetch(spec.URL); err != nil {
+		return err
+	}
+	f := s.fetcher
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, spec.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slogger.Error(ctx, "closing fetched body", slogger.Err("error", err))
+		}
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: %s", ErrFetchBadStatus, resp.Status)
+	}
+	if f.maxSize > 0 && resp.ContentLength > f.maxSize {
+		return ErrFetchTooLarge
+	}
+	r.setTotal(1)
+	if resp.ContentLength >= 0 {
+		r.setBytesTotal(resp.ContentLength)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	body := &fetchReader{r: resp.Body, limit: f.maxSize, run: r}
+	part, err := readPart(body, f.partSize)
+	if err != nil {
+		return err
+	}
+	if int64(len(part)) < f.partSize {
+		_, err = s.PutObject(
+			ctx, spec.Bucket, spec.Key, contentType, bytes.NewReader(part),
+			model.PutObjectOptions{StorageClass: spec.StorageClass},
+		)
+		if err != nil {
+			return err
+		}
+	} else if err = s.putParts(ctx, spec.Bucket, spec.Key, contentType, spec.StorageClass, part, body); err != nil {
+		return err
+	}
+	r.done(1, 0)
+	return nil
+}
+
+// putParts uploads first and then the rest of body as a multipart upload,
+// which is aborted if any part fails.
+func (s *Services) putParts(
+	ctx context.Context,
+	bucketName, key, contentType, storageClass string,
+	first []byte,
+	body io.Reader,
+) (err error) {
+	created, err := s.s3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:            aws.String(bucketName),
+		Key:               aws.String(key),
+		ContentType:       aws.String(contentType),
+		StorageClass:      types.StorageClass(storageClass),
+		ChecksumAlgorithm: s.checksum,
+	})
+	if err != nil {
+		return mapS3ErrToAppErr(err)
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// The request may have been cancelled, the abort still has to go out.
+		_, abortErr := s.s3Client.AbortMultipartUpload(
+			context.WithoutCancel(ctx),
+			&s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(bucketName),
+				Key:      aws.String(key),
+				UploadId: created.UploadId,
+			},
+		)
+		if abortErr != nil {
+			err = errors.Join(err, fmt.Errorf("aborting upload: %w", abortErr))
+		}
+	}()
+
+	var parts []types.CompletedPart
+	part := first
+	for n := int32(1); len(part) > 0; n++ {
+		out, err := s.s3Client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:            aws.String(bucketName),
+			Key:               aws.String(key),
+			UploadId:          created.UploadId,
+			PartNumber:        aws.Int32(n),
+			ChecksumAlgorithm: s.checksum,
+			Body:              bytes.NewReader(part),
+		})
+		if err != nil {
+			return mapS3ErrToAppErr(err)
+		}
+		// Each part's checksum has to be repeated to complete the upload.
+		parts = append(parts, types.CompletedPart{
+			ETag:              out.ETag,
+			PartNumber:        aws.Int32(n),
+			ChecksumCRC32:     out.ChecksumCRC32,
+			ChecksumCRC32C:    out.ChecksumCRC32C,
+			ChecksumCRC64NVME: out.ChecksumCRC64NVME,
+			ChecksumSHA1:      out.ChecksumSHA1,
+			ChecksumSHA256:    out.ChecksumSHA256,
+		})
+		if part, err = readPart(body, int64(len(first))); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucketName),
+		Key:             aws.String(key),
+		UploadId:        created.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return mapS3ErrToAppErr(err)
+	}
+	return nil
+}
+
+// readPart reads up to size bytes, fewer only at the end of r.
+func readPart(r io.Reader, size int64) ([]byte, error) {
+	buf := make([]byte, size)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("reading remote file: %w", err)
+	}
+	return buf[:n], nil
+}
+
+// fetchReader reports the bytes read from a fetched body as the job's
+// progress, and fails once more than limit bytes were read. The length a
+// server announces can't be trusted.
+type fetchReader struct {
+	r     io.Reader
+	read  int64
+	limit int64
+	run   *jobRun
+}
+
+func (f *fetchReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	f.read += int64(n)
+	f.run.done(0, int64(n))
+	if f.limit > 0 && f.read > f.limit {
+		return n, ErrFetchTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	defaultJobWorkers = 2
+	jobQueueSize      = 100
+	// maxFinishedJobs is how many finished jobs are kept around for their
+	// results, the oldest are dropped first.
+	maxFinishedJobs = 100
+	// maxJobFailures caps the failures kept per job, the count is exact.
+	maxJobFailures = 100
+)
+
+var (
+	ErrJobNotFound  = errors.New("job not found")
+	ErrJobFinished  = errors.New("job has already finished")
+	ErrJobQueueFull = errors.New("too many jobs are waiting to run")
+)
+
+type jobEntry struct {
+	job    model.Job
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// jobManager queues jobs and runs them on a fixed pool of workers, which are
+// started with the first submitted job. Only jobs still waiting to run are
+// kept in queue, cancelling one frees its slot right away.
+type jobManager struct {
+	mu      sync.Mutex
+	entries map[string]*jobEntry
+	order   []*jobEntry
+	queue   []*jobEntry
+	ready   chan struct{}
+	workers int
+	start   sync.Once
+	base    context.Context
+	stop    context.CancelFunc
+}
+
+func newJobManager() *jobManager {
+	base, stop := context.WithCancel(context.Background())
+	return &jobManager{
+		entries: make(map[string]*jobEntry),
+		workers: defaultJobWorkers,
+		base:    base,
+		stop:    stop,
+	}
+}
+
+// SubmitJob queues a job and returns it right away. Jobs outlive the request
+// that submitted them. Jobs that delete a bucket or prefixes have to carry
+// the token from PreviewDelete, syncs that delete the one from SyncDiff and
+// fetch jobs an allowed URL.
+func (s *Services) SubmitJob(
+	_ context.Context, spec model.JobSpec,
+) (*model.Job, error) {
+	if spec.Type == model.JobSync {
+		spec = syncSpec(spec)
+	}
+	if prefixes, ok := recursiveDeletePrefixes(spec); ok {
+		if err := s.ConfirmDelete(spec.Bucket, prefixes, spec.Confirm); err != nil {
+			return nil, err
+		}
+	}
+	if spec.Type == model.JobSync && spec.Delete {
+		if err := s.confirmSync(spec, spec.Confirm); err != nil {
+			return nil, err
+		}
+	}
+	spec.Confirm = ""
+	if spec.Type == model.JobFetch {
+		if err := s.checkFetch(spec.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	m := s.jobs
+	m.start.Do(func() {
+		m.ready = make(chan struct{}, m.workers)
+		for range m.workers {
+			go m.work(s.runJob)
+		}
+	})
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(m.base)
+	entry := &jobEntry{
+		job: model.Job{
+			ID:        id,
+			Spec:      spec,
+			Status:    model.JobQueued,
+			CreatedAt: aws.ToString(formatTime(aws.Time(time.Now()))),
+		},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.queue) >= jobQueueSize {
+		cancel()
+		return nil, errs.TooMany(errs.WithMsg(ErrJobQueueFull.Error()))
+	}
+	m.queue = append(m.queue, entry)
+	m.entries[id] = entry
+	m.order = append(m.order, entry)
+	select {
+	case m.ready <- struct{}{}:
+	default:
+	}
+	return entry.snapshot(), nil
+}
+
+// ListJobs returns every known job, newest first.
+func (s *Services) ListJobs(_ context.Context) ([]model.Job, error) {
+	m := s.jobs
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]model.Job, 0, len(m.order))
+	for _, entry := range slices.Backward(m.order) {
+		jobs = append(jobs, *entry.snapshot())
+	}
+	return jobs, nil
+}
+
+func (s *Services) GetJob(_ context.Context, id string) (*model.Job, error) {
+	m := s.jobs
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[id]
+	if !ok {
+		return nil, errs.NotFound(errs.WithMsg(ErrJobNotFound.Error()))
+	}
+	return entry.snapshot(), nil
+}
+
+// CancelJob stops a job. A queued job is cancelled right away, a running one
+// once its current step returns.
+func (s *Services) CancelJob(_ context.Context, id string) error {
+	m := s.jobs
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[id]
+	if !ok {
+		return errs.NotFound(errs.WithMsg(ErrJobNotFound.Error()))
+	}
+	switch entry.job.Status {
+	case model.JobQueued:
+		m.queue = slices.DeleteFunc(m.queue, func(e *jobEntry) bool {
+			return e == entry
+		})
+		entry.job.Status = model.JobCancelled
+		entry.job.FinishedAt = formatTime(aws.Time(time.Now()))
+		m.prune()
+	case model.JobRunning:
+	default:
+		return errs.Conflict(errs.WithMsg(ErrJobFinished.Error()))
+	}
+	entry.cancel()
+	return nil
+}
+
+// Close cancels every queued and running job and statistics walk, stops the
+// workers and the purging of the trash.
+func (s *Services) Close() {
+	s.jobs.stop()
+	s.stats.stop()
+	if s.trash != nil && s.trash.stop != nil {
+		s.trash.stop()
+	}
+}
+
+func (m *jobManager) work(run func(context.Context, *jobRun) error) {
+	for {
+		entry := m.next()
+		if entry == nil {
+			select {
+			case <-m.base.Done():
+				return
+			case <-m.ready:
+			}
+			continue
+		}
+		err := run(entry.ctx, &jobRun{m: m, entry: entry})
+		m.finish(entry, err)
+	}
+}
+
+// next takes the oldest queued job and marks it as running, it returns nil
+// when nothing is waiting or the manager is stopped.
+func (m *jobManager) next() *jobEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.base.Err() != nil || len(m.queue) == 0 {
+		return nil
+	}
+	entry := m.queue[0]
+	m.queue[0] = nil
+	m.queue = m.queue[1:]
+	entry.job.Status = model.JobRunning
+	entry.job.StartedAt = formatTime(aws.Time(time.Now()))
+	return entry
+}
+
+// queued returns how many jobs are waiting for a worker.
+func (m *jobManager) queued() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queue)
+}
+
+func (m *jobManager) finish(entry *jobEntry, err error) {
+	defer entry.cancel()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry.job.FinishedAt = formatTime(aws.Time(time.Now()))
+	switch {
+	case entry.ctx.Err() != nil:
+		entry.job.Status = model.JobCancelled
+	case err != nil:
+		entry.job.Status = model.JobFailed
+		entry.job.Error = aws.String(err.Error())
+	default:
+		entry.job.Status = model.JobDone
+	}
+	m.prune()
+}
+
+// prune drops the oldest finished jobs beyond maxFinishedJobs. Callers must
+// hold the lock.
+func (m *jobManager) prune() {
+	finished := 0
+	for _, entry := range m.order {
+		if entry.finished() {
+			finished++
+		}
+	}
+	m.order = slices.DeleteFunc(m.order, func(entry *jobEntry) bool {
+		if finished <= maxFinishedJobs || !entry.finished() {
+			return false
+		}
+		finished--
+		delete(m.entries, entry.job.ID)
+		return true
+	})
+}
+
+func (e *jobEntry) finished() bool {
+	return e.job.FinishedAt != nil
+}
+
+// snapshot returns a copy of the job that is safe to use after the lock has
+// been released. Callers must hold the lock.
+func (e *jobEntry) snapshot() *model.Job {
+	job := e.job
+	job.Failures = slices.Clone(e.job.Failures)
+	if e.job.Progress.ObjectsTotal != nil {
+		job.Progress.ObjectsTotal = aws.Int64(*e.job.Progress.ObjectsTotal)
+	}
+	if e.job.Progress.BytesTotal != nil {
+		job.Progress.BytesTotal = aws.Int64(*e.job.Progress.BytesTotal)
+	}
+	return &job
+}
+
+// jobRun reports the progress of a running job.
+type jobRun struct {
+	m     *jobManager
+	entry *jobEntry
+}
+
+func (r *jobRun) spec() model.JobSpec {
+	// The spec never changes after submission.
+	return r.entry.job.Spec
+}
+
+func (r *jobRun) setTotal(n int) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	r.entry.job.Progress.ObjectsTotal = aws.Int64(int64(n))
+}
+
+func (r *jobRun) setBytesTotal(n int64) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	r.entry.job.Progress.BytesTotal = aws.Int64(n)
+}
+
+func (r *jobRun) done(objects, bytes int64) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	r.entry.job.Progress.ObjectsDone += objects
+	r.entry.job.Progress.BytesDone += bytes
+}
+
+func (r *jobRun) fail(key, msg string) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	r.entry.job.Progress.ObjectsFailed++
+	if len(r.entry.job.Failures) < maxJobFailures {
+		r.entry.job.Failures = append(
+			r.entry.job.Failures, model.JobFailure{Key: key, Error: msg},
+		)
+	}
+}
+
+// report accounts the results of a delete batch.
+func (r *jobRun) report(results []model.DeleteResult, bytes int64) {
+	var deleted int64
+	for _, res := range results {
+		if res.Deleted {
+			deleted++
+			continue
+		}
+		r.fail(res.Key, aws.ToString(res.Error))
+	}
+	r.done(deleted, bytes)
+}
+
+func (r *jobRun) failed() int64 {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	return r.entry.job.Progress.ObjectsFailed
+}
+
+func (s *Services) runJob(ctx context.Context, r *jobRun) error {
+	spec := r.spec()
+	switch spec.Type {
+	case model.JobDelete:
+		targets, failures := s.collectDeleteTargets(ctx, spec.Bucket, spec.Keys, spec.Prefixes)
+		r.report(failures, 0)
+		r.setTotal(len(targets))
+		if !spec.Permanent {
+			targets = s.trashTargets(ctx, spec.Bucket, targets, spec.DeletedBy, func(res model.DeleteResult, bytes int64) {
+				r.report([]model.DeleteResult{res}, bytes)
+			})
+		}
+		s.deleteTargets(ctx, spec.Bucket, targets, func(_ int, results []model.DeleteResult, bytes int64) {
+			r.report(results, bytes)
+		})
+		if n := r.failed(); n > 0 {
+			return fmt.Errorf("%d object(s) could not be deleted", n)
+		}
+	case model.JobDeleteBucket:
+		var targets []deleteTarget
+		err := s.walkPrefix(ctx, spec.Bucket, "", func(obj types.Object) {
+			targets = append(targets, deleteTarget{
+				key: aws.ToString(obj.Key), size: aws.ToInt64(obj.Size),
+			})
+		})
+		if err != nil {
+			return mapS3ErrToAppErr(err)
+		}
+		r.setTotal(len(targets))
+		s.deleteTargets(ctx, spec.Bucket, targets, func(_ int, results []model.DeleteResult, bytes int64) {
+			r.report(results, bytes)
+		})
+		if n := r.failed(); n > 0 {
+			return fmt.Errorf("%d object(s) could not be deleted, keeping the bucket", n)
+		}
+		if ctx.Err() == nil {
+			if err = s.DeleteBucket(ctx, spec.Bucket, true); err != nil {
+				return err
+			}
+		}
+	case model.JobCopy, model.JobMove:
+		if err := s.runCopy(ctx, r, spec.Type == model.JobMove); err != nil {
+			return err
+		}
+	case model.JobSync:
+		if err := s.runSync(ctx, r); err != nil {
+			return err
+		}
+	case model.JobFetch:
+		if err := s.runFetch(ctx, r); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown job type %q", spec.Type)
+	}
+	return ctx.Err()
+}
+
+// recursiveDeletePrefixes returns the prefixes a job would wipe, if any. The
+// whole bucket is the empty prefix.
+func recursiveDeletePrefixes(spec model.JobSpec) ([]string, bool) {
+	switch {
+	case spec.Type == model.JobDeleteBucket:
+		return []string{""}, true
+	case spec.Type == model.JobDelete && len(spec.Prefixes) > 0:
+		return spec.Prefixes, true
+	}
+	return nil, false
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating job id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/hossein1376/s3manager/internal/metrics"
+)
+
+// WithMetrics records the calls made to S3, the bytes sent and received and
+// the number of queued jobs in reg.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(s *Services) {
+		s.s3Client = &instrumentedClient{next: s.s3Client, m: newS3Metrics(reg)}
+		reg.GaugeFunc(
+			"s3manager_jobs_queued", "Jobs waiting for a worker.",
+			func() float64 { return float64(s.jobs.queued()) },
+		)
+	}
+}
+
+type s3Metrics struct {
+	requests   *metrics.Counter
+	errors     *metrics.Counter
+	duration   *metrics.Histogram
+	uploaded   *metrics.Counter
+	downloaded *metrics.Counter
+}
+
+func newS3Metrics(reg *metrics.Registry) *s3Metrics {
+	return &s3Metrics{
+		requests: reg.Counter(
+			"s3manager_s3_requests_total",
+			"Calls made to S3, by operation.",
+			"operation",
+		),
+		errors: reg.Counter(
+			"s3manager_s3_errors_total",
+			"Calls to S3 that failed, by operation and S3 error code.",
+			"operation", "code",
+		),
+		duration: reg.Histogram(
+			"s3manager_s3_request_duration_seconds",
+			"Time taken by calls to S3, retries included, by operation.",
+			metrics.DefaultBuckets, "operation",
+		),
+		uploaded: reg.Counter(
+			"s3manager_s3_uploaded_bytes_total",
+			"Bytes of object content sent to S3.",
+		),
+		downloaded: reg.Counter(
+			"s3manager_s3_downloaded_bytes_total",
+			"Bytes of object content read from S3.",
+		),
+	}
+}
+
+// observe records a call to S3.
+func observe[I, O any](
+	m *s3Metrics,
+	operation string,
+	call func(context.Context, *I, ...func(*s3.Options)) (*O, error),
+	ctx context.Context,
+	params *I,
+	optFns []func(*s3.Options),
+) (*O, error) {
+	start := time.Now()
+	out, err := call(ctx, params, optFns...)
+	m.duration.Observe(time.Since(start).Seconds(), operation)
+	m.requests.Inc(operation)
+	if err != nil {
+		code := errorCode(err)
+		if code == "" {
+			code = "unknown"
+		}
+		m.errors.Inc(operation, code)
+	}
+	return out, err
+}
+
+// countUpload returns the size of body, which is sent with length bytes
+// unless it's nil, without reading it. Bodies that can't tell are counted
+// while they're read instead, seekable ones stay seekable so the SDK can
+// still hash and rewind them.
+func (m *s3Metrics) countUpload(length *int64, body io.Reader) (io.Reader, int64) {
+	switch b := body.(type) {
+	case nil:
+		return nil, 0
+	case io.ReadSeeker:
+		if length != nil {
+			return body, *length
+		}
+		cur, err := b.Seek(0, io.SeekCurrent)
+		if err == nil {
+			var end int64
+			if end, err = b.Seek(0, io.SeekEnd); err == nil {
+				if _, err = b.Seek(cur, io.SeekStart); err == nil {
+					return body, end - cur
+				}
+			}
+		}
+		return &countingReadSeeker{
+			r: b, counter: m.uploaded, pos: cur, seen: cur,
+		}, 0
+	}
+	return &countingReader{r: body, counter: m.uploaded}, 0
+}
+
+// countingReader adds the bytes read from r to counter.
+type countingReader struct {
+	r       io.Reader
+	counter *metrics.Counter
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.counter.Add(float64(n))
+	return n, err
+}
+
+type countingReadCloser struct {
+	countingReader
+	io.Closer
+}
+
+// countingReadSeeker adds the bytes read from r to counter and forwards
+// Seek. Bytes read again after seeking back, as on a retry, are only counted
+// once.
+type countingReadSeeker struct {
+	r       io.ReadSeeker
+	counter *metrics.Counter
+	pos     int64
+	seen    int64
+}
+
+func (c *countingReadSeeker) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.pos += int64(n)
+	if c.pos > c.seen {
+		c.counter.Add(float64(c.pos - c.seen))
+		c.seen = c.pos
+	}
+	return n, err
+}
+
+func (c *countingReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos, err := c.r.Seek(offset, whence)
+	if err == nil {
+		c.pos = pos
+	}
+	return pos, err
+}
+
+// instrumentedClient records every call to the S3 client it wraps.
+type instrumentedClient struct {
+	next S3Client
+	m    *s3Metrics
+}
+
+func (c *instrumentedClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return observe(c.m, "ListObjectsV2", c.next.ListObjectsV2, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+	return observe(c.m, "ListBuckets", c.next.ListBuckets, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	return observe(c.m, "CreateBucket", c.next.CreateBucket, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) DeleteBucket(ctx context.Context, params *s3.DeleteBucketInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error) {
+	return observe(c.m, "DeleteBucket", c.next.DeleteBucket, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return observe(c.m, "DeleteObjects", c.next.DeleteObjects, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	in := *params
+	var size int64
+	in.Body, size = c.m.countUpload(params.ContentLength, params.Body)
+	out, err := observe(c.m, "PutObject", c.next.PutObject, ctx, &in, optFns)
+	if err == nil {
+		c.m.uploaded.Add(float64(size))
+	}
+	return out, err
+}
+
+func (c *instrumentedClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return observe(c.m, "DeleteObject", c.next.DeleteObject, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := observe(c.m, "GetObject", c.next.GetObject, ctx, params, optFns)
+	if err == nil && out.Body != nil {
+		out.Body = &countingReadCloser{
+			countingReader: countingReader{r: out.Body, counter: c.m.downloaded},
+			Closer:         out.Body,
+		}
+	}
+	return out, err
+}
+
+func (c *instrumentedClient) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
+	return observe(c.m, "PutBucketVersioning", c.next.PutBucketVersioning, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) PutBucketTagging(ctx context.Context, params *s3.PutBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error) {
+	return observe(c.m, "PutBucketTagging", c.next.PutBucketTagging, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
+	return observe(c.m, "PutBucketEncryption", c.next.PutBucketEncryption, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) GetObjectLockConfiguration(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+	return observe(c.m, "GetObjectLockConfiguration", c.next.GetObjectLockConfiguration, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) PutObjectLockConfiguration(ctx context.Context, params *s3.PutObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutObjectLockConfigurationOutput, error) {
+	return observe(c.m, "PutObjectLockConfiguration", c.next.PutObjectLockConfiguration, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) GetObjectRetention(ctx context.Context, params *s3.GetObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.GetObjectRetentionOutput, error) {
+	return observe(c.m, "GetObjectRetention", c.next.GetObjectRetention, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) PutObjectRetention(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+	return observe(c.m, "PutObjectRetention", c.next.PutObjectRetention, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) GetObjectLegalHold(ctx context.Context, params *s3.GetObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.GetObjectLegalHoldOutput, error) {
+	return observe(c.m, "GetObjectLegalHold", c.next.GetObjectLegalHold, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) PutObjectLegalHold(ctx context.Context, params *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error) {
+	return observe(c.m, "PutObjectLegalHold", c.next.PutObjectLegalHold, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return observe(c.m, "CopyObject", c.next.CopyObject, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return observe(c.m, "HeadObject", c.next.HeadObject, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return observe(c.m, "CreateMultipartUpload", c.next.CreateMultipartUpload, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	in := *params
+	var size int64
+	in.Body, size = c.m.countUpload(params.ContentLength, params.Body)
+	out, err := observe(c.m, "UploadPart", c.next.UploadPart, ctx, &in, optFns)
+	if err == nil {
+		c.m.uploaded.Add(float64(size))
+	}
+	return out, err
+}
+
+func (c *instrumentedClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return observe(c.m, "CompleteMultipartUpload", c.next.CompleteMultipartUpload, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return observe(c.m, "AbortMultipartUpload", c.next.AbortMultipartUpload, ctx, params, optFns)
+}
+
+func (c *instrumentedClient) RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	return observe(c.m, "RestoreObject", c.next.RestoreObject, ctx, params, optFns)
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/slogger"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+var (
+	ErrNotText          = errors.New("object is not a text file")
+	ErrContentTooLarge  = errors.New("object is too large to be edited")
+	ErrObjectModified   = errors.New("object was changed since it was opened")
+	ErrConcurrentUpdate = errors.New("object is being changed by another request, try again")
+)
+
+// GetObjectContent reads a text object of up to maxSize bytes whole, along
+// with the ETag to update it with.
+func (s *Services) GetObjectContent(
+	ctx context.Context, bucketName, objectKey string, maxSize int64,
+) (*model.ObjectContent, error) {
+	out, err := s.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return nil, mapS3ErrToAppErr(err)
+	}
+	defer func() {
+		if err := out.Body.Close(); err != nil {
+			slogger.Error(ctx, "closing object body", slogger.Err("error", err))
+		}
+	}()
+	if aws.ToInt64(out.ContentLength) > maxSize {
+		return nil, errs.New(
+			http.StatusRequestEntityTooLarge, errs.WithMsg(ErrContentTooLarge.Error()),
+		)
+	}
+	data, err := io.ReadAll(io.LimitReader(out.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading object: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errs.New(
+			http.StatusRequestEntityTooLarge, errs.WithMsg(ErrContentTooLarge.Error()),
+		)
+	}
+	if !utf8.Valid(data) {
+		return nil, errs.New(
+			http.StatusUnsupportedMediaType, errs.WithMsg(ErrNotText.Error()),
+		)
+	}
+	return &model.ObjectContent{
+		Key:          objectKey,
+		Content:      string(data),
+		Size:         int64(len(data)),
+		ETag:         aws.ToString(out.ETag),
+		ContentType:  aws.ToString(out.ContentType),
+		LastModified: formatTime(out.LastModified),
+	}, nil
+}
+
+// UpdateObject overwrites an object only if its ETag still is etag. The ETag
+// is compared before the upload as well, for S3 implementations that ignore
+// If-Match on PutObject.
+func (s *Services) UpdateObject(
+	ctx context.Context, bucketName, objectKey, mimeType, etag string, r io.Reader,
+) (*model.Object, error) {
+	head, err := s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	switch {
+	case err != nil && errorCode(err) != "NotFound":
+		return nil, mapS3ErrToAppErr(err)
+	// Deleted in the meantime, there's nothing to match anymore.
+	case err != nil, !sameETag(aws.ToString(head.ETag), etag):
+		return nil, errs.New(
+			http.StatusPreconditionFailed, errs.WithMsg(ErrObjectModified.Error()),
+		)
+	}
+
+	// A put replaces the whole object, so everything but its content is
+	// carried over from the version being edited.
+	output, err := s.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(objectKey),
+		ContentType:          aws.String(mimeType),
+		Metadata:             head.Metadata,
+		StorageClass:         types.StorageClass(head.StorageClass),
+		CacheControl:         head.CacheControl,
+		ContentDisposition:   head.ContentDisposition,
+		ContentEncoding:      head.ContentEncoding,
+		ContentLanguage:      head.ContentLanguage,
+		ServerSideEncryption: head.ServerSideEncryption,
+		SSEKMSKeyId:          head.SSEKMSKeyId,
+		IfMatch:              aws.String(etag),
+		ChecksumAlgorithm:    s.checksum,
+		Body:                 r,
+	})
+	if err != nil {
+		return nil, mapS3ErrToAppErr(err)
+	}
+	return &model.Object{
+		Key:          &objectKey,
+		Size:         output.Size,
+		LastModified: formatTime(aws.Time(time.Now())),
+		ETag:         output.ETag,
+		Checksums: checksums(
+			output.ChecksumCRC32, output.ChecksumCRC32C, output.ChecksumCRC64NVME,
+			output.ChecksumSHA1, output.ChecksumSHA256,
+		),
+	}, nil
+}
+
+// sameETag compares ETags with or without their quotes.
+func sameETag(a, b string) bool {
+	return strings.Trim(a, `"`) == strings.Trim(b, `"`)
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+func (s *Services) GetBucketObjectLock(
+	ctx context.Context, bucketName string,
+) (*model.ObjectLockConfig, error) {
+	out, err := s.s3Client.GetObjectLockConfiguration(
+		ctx,
+		&s3.GetObjectLockConfigurationInput{Bucket: aws.String(bucketName)},
+	)
+	if err != nil {
+		// Buckets created without object lock have no configuration at all.
+		if errorCode(err) == "ObjectLockConfigurationNotFoundError" {
+			return &model.ObjectLockConfig{Enabled: false}, nil
+		}
+		return nil, mapS3ErrToAppErr(err)
+	}
+	cfg := &model.ObjectLockConfig{}
+	if out.ObjectLockConfiguration == nil {
+		return cfg, nil
+	}
+	lock := out.ObjectLockConfiguration
+	cfg.Enabled = lock.ObjectLockEnabled == types.ObjectLockEnabledEnabled
+	if lock.Rule != nil && lock.Rule.DefaultRetention != nil {
+		retention := lock.Rule.DefaultRetention
+		cfg.DefaultRetention = &model.DefaultRetention{
+			Mode:  string(retention.Mode),
+			Days:  retention.Days,
+			Years: retention.Years,
+		}
+	}
+	return cfg, nil
+}
+
+func (s *Services) PutBucketObjectLock(
+	ctx context.Context, bucketName string, cfg model.ObjectLockConfig,
+) error {
+	lock := &types.ObjectLockConfiguration{
+		ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+	}
+	if cfg.DefaultRetention != nil {
+		lock.Rule = &types.ObjectLockRule{
+			DefaultRetention: &types.DefaultRetention{
+				Mode:  types.ObjectLockRetentionMode(cfg.DefaultRetention.Mode),
+				Days:  cfg.DefaultRetention.Days,
+				Years: cfg.DefaultRetention.Years,
+			},
+		}
+	}
+	_, err := s.s3Client.PutObjectLockConfiguration(
+		ctx,
+		&s3.PutObjectLockConfigurationInput{
+			Bucket:                  aws.String(bucketName),
+			ObjectLockConfiguration: lock,
+		},
+	)
+	if err != nil {
+		return mapS3ErrToAppErr(err)
+	}
+	return nil
+}
+
+func (s *Services) GetObjectRetention(
+	ctx context.Context, bucketName, objectKey string,
+) (*model.ObjectRetention, error) {
+	out, err := s.s3Client.GetObjectRetention(
+		ctx,
+		&s3.GetObjectRetentionInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(objectKey),
+		},
+	)
+	if err != nil {
+		if errorCode(err) == "NoSuchObjectLockConfiguration" {
+			return &model.ObjectRetention{}, nil
+		}
+		return nil, mapS3ErrToAppErr(err)
+	}
+	retention := &model.ObjectRetention{}
+	if out.Retention == nil {
+		return retention, nil
+	}
+	if out.Retention.Mode != "" {
+		retention.Mode = aws.String(string(out.Retention.Mode))
+	}
+	if out.Retention.RetainUntilDate != nil {
+		retention.RetainUntil = aws.String(
+			out.Retention.RetainUntilDate.UTC().Format(time.RFC3339),
+		)
+	}
+	return retention, nil
+}
+
+// PutObjectRetention sets the retention of a single object. An empty
+// retention clears it, which is only allowed for governance mode along with
+// bypassGovernance.
+func (s *Services) PutObjectRetention(
+	ctx context.Context,
+	bucketName, objectKey string,
+	retention model.ObjectRetention,
+	bypassGovernance bool,
+) error {
+	params := &s3.PutObjectRetentionInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(objectKey),
+		Retention: &types.ObjectLockRetention{},
+	}
+	if bypassGovernance {
+		params.BypassGovernanceRetention = aws.Bool(true)
+	}
+	if retention.Mode != nil {
+		params.Retention.Mode = types.ObjectLockRetentionMode(*retention.Mode)
+	}
+	if retention.RetainUntil != nil {
+		until, err := time.Parse(time.RFC3339, *retention.RetainUntil)
+		if err != nil {
+			return errs.BadRequest(
+				errs.WithErr(err), errs.WithMsg("invalid retain until date"),
+			)
+		}
+		params.Retention.RetainUntilDate = &until
+	}
+	_, err := s.s3Client.PutObjectRetention(ctx, params)
+	if err != nil {
+		return mapS3ErrToAppErr(err)
+	}
+	return nil
+}
+
+func (s *Services) GetObjectLegalHold(
+	ctx context.Context, bucketName, objectKey string,
+) (*model.LegalHold, error) {
+	out, err := s.s3Client.GetObjectLegalHold(
+		ctx,
+		&s3.GetObjectLegalHoldInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(objectKey),
+		},
+	)
+	if err != nil {
+		if errorCode(err) == "NoSuchObjectLockConfiguration" {
+			return &model.LegalHold{Enabled: false}, nil
+		}
+		return nil, mapS3ErrToAppErr(err)
+	}
+	hold := &model.LegalHold{}
+	if out.LegalHold != nil {
+		hold.Enabled = out.LegalHold.Status == types.ObjectLockLegalHoldStatusOn
+	}
+	return hold, nil
+}
+
+func (s *Services) PutObjectLegalHold(
+	ctx context.Context, bucketName, objectKey string, hold model.LegalHold,
+) error {
+	status := types.ObjectLockLegalHoldStatusOff
+	if hold.Enabled {
+		status = types.ObjectLockLegalHoldStatusOn
+	}
+	_, err := s.s3Client.PutObjectLegalHold(
+		ctx,
+		&s3.PutObjectLegalHoldInput{
+			Bucket:    aws.String(bucketName),
+			Key:       aws.String(objectKey),
+			LegalHold: &types.ObjectLockLegalHold{Status: status},
+		},
+	)
+	if err != nil {
+		return mapS3ErrToAppErr(err)
+	}
+	return nil
+}
+
+func (s *Services) objectLockEnabled(ctx context.Context, bucketName string) bool {
+	cfg, err := s.GetBucketObjectLock(ctx, bucketName)
+	return err == nil && cfg.Enabled
+}
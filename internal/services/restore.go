@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+var (
+	ErrNotRestored      = errors.New("object is archived and has to be restored before it can be read")
+	ErrNotArchived      = errors.New("only GLACIER and DEEP_ARCHIVE objects can be restored")
+	ErrRestoreRunning   = errors.New("object is already being restored")
+	ErrExpeditedRestore = errors.New("DEEP_ARCHIVE objects can't be restored with the expedited tier")
+)
+
+// restoreStatusAttrs asks listings for the restore status of archived
+// objects, which they leave out otherwise.
+var restoreStatusAttrs = []types.OptionalObjectAttributes{
+	types.OptionalObjectAttributesRestoreStatus,
+}
+
+// restoreHeader matches the x-amz-restore header of HeadObject, such as
+// ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT".
+var restoreHeader = regexp.MustCompile(
+	`ongoing-request="(true|false)"(?:,\s*expiry-date="([^"]+)")?`,
+)
+
+// RestoreObject asks for a temporary copy of an archived object to be made
+// readable for opts.Days. The restore runs in the background, for minutes to
+// hours depending on the tier; asking again once it's done extends it.
+func (s *Services) RestoreObject(
+	ctx context.Context,
+	bucketName, objectKey string,
+	opts model.RestoreObjectOptions,
+) error {
+	obj, err := s.StatObject(ctx, bucketName, objectKey)
+	if err != nil {
+		return err
+	}
+	class := aws.ToString(obj.StorageClass)
+	switch {
+	case class != model.StorageGlacier && class != model.StorageDeepArchive:
+		return errs.Conflict(errs.WithMsg(ErrNotArchived.Error()))
+	case obj.Restore != nil && obj.Restore.InProgress:
+		return errs.Conflict(errs.WithMsg(ErrRestoreRunning.Error()))
+	case class == model.StorageDeepArchive && opts.Tier == model.RestoreTierExpedited:
+		return errs.BadRequest(errs.WithMsg(ErrExpeditedRestore.Error()))
+	}
+
+	_, err = s.s3Client.RestoreObject(ctx, &s3.RestoreObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+		RestoreRequest: &types.RestoreRequest{
+			Days: aws.Int32(opts.Days),
+			GlacierJobParameters: &types.GlacierJobParameters{
+				Tier: types.Tier(opts.Tier),
+			},
+		},
+	})
+	return mapS3ErrToAppErr(err)
+}
+
+// toRestoreStatus converts the restore status of a listed object. It's nil
+// unless the object was asked to be restored.
+func toRestoreStatus(status *types.RestoreStatus) *model.RestoreStatus {
+	if status == nil || status.IsRestoreInProgress == nil {
+		return nil
+	}
+	return &model.RestoreStatus{
+		InProgress: *status.IsRestoreInProgress,
+		ExpiresAt:  formatTime(status.RestoreExpiryDate),
+	}
+}
+
+// parseRestoreHeader reads the restore status HeadObject returns for archived
+// objects that were asked to be restored.
+func parseRestoreHeader(header *string) *model.RestoreStatus {
+	m := restoreHeader.FindStringSubmatch(aws.ToString(header))
+	if m == nil {
+		return nil
+	}
+	status := &model.RestoreStatus{InProgress: m[1] == "true"}
+	if expiry, err := time.Parse(time.RFC1123, m[2]); err == nil {
+		status.ExpiresAt = formatTime(&expiry)
+	}
+	return status
+}
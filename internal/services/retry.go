@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// Retry makes an S3 client retry failed requests in mode, "standard" or
+// "adaptive", which also slows the client down once S3 throttles it. Requests
+// are sent up to maxAttempts times, waiting at most maxBackoff in between.
+// Empty or zero values keep the SDK's defaults.
+func Retry(
+	mode string, maxAttempts int, maxBackoff time.Duration,
+) (func(*s3.Options), error) {
+	retryMode := aws.RetryModeStandard
+	if mode != "" {
+		var err error
+		if retryMode, err = aws.ParseRetryMode(mode); err != nil {
+			return nil, fmt.Errorf("retry mode: %w", err)
+		}
+	}
+	standard := func(o *retry.StandardOptions) {
+		if maxAttempts > 0 {
+			o.MaxAttempts = maxAttempts
+		}
+		if maxBackoff > 0 {
+			o.MaxBackoff = maxBackoff
+		}
+	}
+	return func(o *s3.Options) {
+		o.RetryMode = retryMode
+		switch retryMode {
+		case aws.RetryModeAdaptive:
+			o.Retryer = retry.NewAdaptiveMode(func(ao *retry.AdaptiveModeOptions) {
+				ao.StandardOptions = append(ao.StandardOptions, standard)
+			})
+		default:
+			o.Retryer = retry.NewStandard(standard)
+		}
+	}, nil
+}
+
+// RateLimit makes an S3 client send at most requestsPerSecond requests a
+// second, with bursts of up to burst, which defaults to requestsPerSecond.
+// Every attempt waits its turn, so retries are slowed down as well and bulk
+// operations don't run into SlowDown errors.
+func RateLimit(requestsPerSecond float64, burst int) func(*s3.Options) {
+	limiter := newTokenBucket(requestsPerSecond, burst)
+	return func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Finalize.Insert(limiter, "Retry", middleware.After)
+		})
+	}
+}
+
+// tokenBucket hands out rate tokens a second, holding up to burst of them.
+// Waiting for a token reserves it, so waiters are served in turn.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = max(1, int(math.Ceil(rate)))
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token, waiting for one to be added if there's none left. The
+// token is given back when ctx is done first.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (b *tokenBucket) ID() string {
+	return "RateLimit"
+}
+
+func (b *tokenBucket) HandleFinalize(
+	ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	if err := b.wait(ctx); err != nil {
+		return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+	}
+	return next.HandleFinalize(ctx, in)
+}
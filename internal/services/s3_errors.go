@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+// s3ErrMapping is what an S3 error code means to clients. An empty msg
+// keeps the message S3 answered with.
+type s3ErrMapping struct {
+	status int
+	msg    string
+}
+
+// s3ErrCodes maps the S3 error codes that mean something more specific than
+// the HTTP status they come with. Other codes are mapped by status alone.
+var s3ErrCodes = map[string]s3ErrMapping{
+	"NoSuchBucket": {http.StatusNotFound, ErrMissingBucket.Error()},
+	"NoSuchKey":    {http.StatusNotFound, ErrMissingObject.Error()},
+	// HeadObject answers without a body, so there's no better code.
+	"NotFound":                   {http.StatusNotFound, ErrMissingObject.Error()},
+	"NoSuchUpload":               {http.StatusNotFound, ""},
+	"InvalidBucketName":          {http.StatusBadRequest, ErrInvalidName.Error()},
+	"BucketAlreadyExists":        {http.StatusConflict, ErrExistingBucket.Error()},
+	"BucketAlreadyOwnedByYou":    {http.StatusConflict, ErrExistingBucket.Error()},
+	"BucketNotEmpty":             {http.StatusConflict, ErrBucketNotEmpty.Error()},
+	"PreconditionFailed":         {http.StatusPreconditionFailed, ErrObjectModified.Error()},
+	"ConditionalRequestConflict": {http.StatusConflict, ErrConcurrentUpdate.Error()},
+	// Reading an archived object that isn't restored is refused with a 403,
+	// which isn't about access at all.
+	"InvalidObjectState":       {http.StatusConflict, ErrNotRestored.Error()},
+	"RestoreAlreadyInProgress": {http.StatusConflict, ErrRestoreRunning.Error()},
+	"EntityTooLarge":           {http.StatusRequestEntityTooLarge, ""},
+	"SlowDown":                 {http.StatusTooManyRequests, ""},
+	"RequestTimeout":           {http.StatusRequestTimeout, ""},
+	// The request was built by the server, not by its client.
+	"InvalidArgument":           {http.StatusInternalServerError, ""},
+	"BadDigest":                 {http.StatusBadGateway, ErrChecksumMismatch.Error()},
+	"XAmzContentSHA256Mismatch": {http.StatusBadGateway, ErrChecksumMismatch.Error()},
+}
+
+// mapS3ErrToAppErr converts an error of the S3 client into a *model.S3Error,
+// by its error code first and the HTTP status of the response second. Errors
+// that never got a response are gateway errors, unless they timed out.
+func mapS3ErrToAppErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	var mapped *model.S3Error
+	if errors.As(err, &mapped) {
+		return err
+	}
+	s3Err := newS3Error(err)
+	if m, ok := s3ErrCodes[s3Err.Code]; ok {
+		return withStatus(s3Err, m.status, m.msg)
+	}
+	switch {
+	case s3Err.Code == "AccessDenied" && isObjectLockMsg(s3Err.S3Message):
+		return withStatus(s3Err, http.StatusConflict, ErrObjectLocked.Error())
+	case s3Err.S3Status == http.StatusServiceUnavailable:
+		return withStatus(s3Err, http.StatusServiceUnavailable, "")
+	case s3Err.S3Status >= http.StatusInternalServerError:
+		return withStatus(s3Err, http.StatusBadGateway, "")
+	case s3Err.S3Status >= http.StatusBadRequest:
+		return withStatus(s3Err, s3Err.S3Status, "")
+	case errors.Is(err, context.DeadlineExceeded):
+		return withStatus(s3Err, http.StatusRequestTimeout, "")
+	// The SDK checks the checksum of a body while it's read, its error type
+	// isn't exported.
+	case strings.Contains(err.Error(), "checksum did not match"):
+		return withStatus(s3Err, http.StatusBadGateway, ErrChecksumMismatch.Error())
+	default:
+		return withStatus(s3Err, http.StatusBadGateway, "")
+	}
+}
+
+// newS3Error collects what S3 answered from the chain of err. Errors of the
+// SDK wrap the API error in the response, which holds the status and IDs.
+func newS3Error(err error) *model.S3Error {
+	s3Err := &model.S3Error{Err: err}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		s3Err.Code = apiErr.ErrorCode()
+		s3Err.S3Message = apiErr.ErrorMessage()
+	}
+	var resp interface{ HTTPStatusCode() int }
+	if errors.As(err, &resp) {
+		s3Err.S3Status = resp.HTTPStatusCode()
+	}
+	var requestID interface{ ServiceRequestID() string }
+	if errors.As(err, &requestID) {
+		s3Err.RequestID = requestID.ServiceRequestID()
+	}
+	var hostID interface{ ServiceHostID() string }
+	if errors.As(err, &hostID) {
+		s3Err.HostID = hostID.ServiceHostID()
+	}
+	return s3Err
+}
+
+// withStatus sets what s3Err means to clients. Without msg, it's what S3
+// answered with, or else the status text.
+func withStatus(s3Err *model.S3Error, status int, msg string) *model.S3Error {
+	s3Err.Status = status
+	switch {
+	case msg != "":
+		s3Err.Message = msg
+	case s3Err.S3Message != "":
+		s3Err.Message = s3Err.S3Message
+	default:
+		s3Err.Message = http.StatusText(status)
+	}
+	return s3Err
+}
+
+// errorCode returns the S3 error code of err, if it's an API error.
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+// isObjectLockMsg reports whether an S3 error message stems from object lock
+// protection. AWS answers with a plain AccessDenied, so the message is the
+// only thing to go on.
+func isObjectLockMsg(msg string) bool {
+	msg = strings.ToLower(msg)
+	return strings.Contains(msg, "object lock") ||
+		strings.Contains(msg, "worm protected") ||
+		strings.Contains(msg, "legal hold")
+}
+
+// deleteErrsToAppErr converts the per-key failures of a DeleteObjects call
+// into a single application error, mapped by the code of the first failure
+// unless any of them is refused by object lock.
+func deleteErrsToAppErr(failures []types.Error) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	first := failures[0]
+	s3Err := &model.S3Error{
+		Code:      aws.ToString(first.Code),
+		S3Message: aws.ToString(first.Message),
+	}
+	msg := fmt.Sprintf(
+		"%d object(s) could not be deleted, first failure %q: %s",
+		len(failures), aws.ToString(first.Key), s3Err.S3Message,
+	)
+	for _, f := range failures {
+		if isObjectLockMsg(aws.ToString(f.Message)) {
+			s3Err.Code = aws.ToString(f.Code)
+			s3Err.S3Message = aws.ToString(f.Message)
+			return withStatus(
+				s3Err, http.StatusConflict, fmt.Sprintf("%s: %s", ErrObjectLocked, msg),
+			)
+		}
+	}
+	status := http.StatusBadGateway
+	if m, ok := s3ErrCodes[s3Err.Code]; ok {
+		status = m.status
+	}
+	return withStatus(s3Err, status, msg)
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+// SearchObjects walks every key under the prefix, without a delimiter, and
+// calls fn for each object matching the options. Keys are passed to fn in
+// full. The walk stops once the limit is reached, fn returns an error, or ctx
+// is cancelled.
+func (s *Services) SearchObjects(
+	ctx context.Context,
+	bucketName string,
+	opts model.SearchOptions,
+	fn func(model.Object) error,
+) (*model.SearchResult, error) {
+	match, err := newKeyMatcher(opts)
+	if err != nil {
+		return nil, err
+	}
+	params := &s3.ListObjectsV2Input{
+		Bucket:                   aws.String(bucketName),
+		FetchOwner:               aws.Bool(true),
+		OptionalObjectAttributes: restoreStatusAttrs,
+	}
+	if opts.Prefix != "" {
+		params.Prefix = aws.String(opts.Prefix)
+	}
+
+	result := &model.SearchResult{}
+	for {
+		list, err := s.s3Client.ListObjectsV2(ctx, params)
+		if err != nil {
+			return result, mapS3ErrToAppErr(err)
+		}
+		for _, obj := range list.Contents {
+			key := aws.ToString(obj.Key)
+			if s.trash.hides(key) {
+				continue
+			}
+			result.Scanned++
+			if !match(strings.TrimPrefix(key, opts.Prefix)) || !inRange(obj, opts) {
+				continue
+			}
+			if opts.Limit > 0 && result.Matched == opts.Limit {
+				result.Truncated = true
+				return result, nil
+			}
+			err = fn(toModelObject(obj, ""))
+			if err != nil {
+				return result, err
+			}
+			result.Matched++
+		}
+		if list.NextContinuationToken == nil {
+			break
+		}
+		params.ContinuationToken = list.NextContinuationToken
+	}
+	return result, nil
+}
+
+// newKeyMatcher returns a function reporting whether a key, relative to the
+// search prefix, matches the query. Glob patterns without a slash are matched
+// against the base name only, so "*.parquet" finds files in any folder.
+func newKeyMatcher(opts model.SearchOptions) (func(string) bool, error) {
+	switch opts.Mode {
+	case "", model.SearchSubstring:
+		query := strings.ToLower(opts.Query)
+		return func(key string) bool {
+			return strings.Contains(strings.ToLower(key), query)
+		}, nil
+	case model.SearchGlob:
+		if _, err := path.Match(opts.Query, ""); err != nil {
+			return nil, errs.BadRequest(
+				errs.WithErr(err), errs.WithMsg("invalid glob pattern"),
+			)
+		}
+		baseOnly := !strings.Contains(opts.Query, "/")
+		return func(key string) bool {
+			if baseOnly {
+				key = path.Base(key)
+			}
+			ok, _ := path.Match(opts.Query, key)
+			return ok
+		}, nil
+	case model.SearchRegex:
+		re, err := regexp.Compile(opts.Query)
+		if err != nil {
+			return nil, errs.BadRequest(
+				errs.WithErr(err), errs.WithMsg("invalid regular expression"),
+			)
+		}
+		return re.MatchString, nil
+	default:
+		return nil, errs.BadRequest(errs.WithMsg("unknown search mode"))
+	}
+}
+
+func inRange(obj types.Object, opts model.SearchOptions) bool {
+	size := aws.ToInt64(obj.Size)
+	if opts.MinSize != nil && size < *opts.MinSize {
+		return false
+	}
+	if opts.MaxSize != nil && size > *opts.MaxSize {
+		return false
+	}
+	if opts.ModifiedAfter != nil &&
+		(obj.LastModified == nil || obj.LastModified.Before(*opts.ModifiedAfter)) {
+		return false
+	}
+	if opts.ModifiedBefore != nil &&
+		(obj.LastModified == nil || obj.LastModified.After(*opts.ModifiedBefore)) {
+		return false
+	}
+	return true
+}
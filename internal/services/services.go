@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -13,7 +14,6 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
-	"github.com/aws/smithy-go"
 
 	"github.com/hossein1376/grape/errs"
 	"github.com/hossein1376/s3manager/internal/model"
@@ -23,10 +23,16 @@ var (
 	ErrBucketNotEmpty = errors.New("bucket is not empty")
 	ErrExistingBucket = errors.New("bucket already exists")
 	ErrMissingBucket  = errors.New("bucket not found")
+	ErrMissingObject  = errors.New("object not found")
 	ErrDirNotEmpty    = errors.New("directory is not empty")
 	ErrInvalidName    = errors.New("invalid bucket name")
+	ErrObjectLocked   = errors.New("object is protected by object lock")
+	ErrBucketLocked   = errors.New("bucket has object lock enabled and still holds protected object versions")
 )
 
+// folderContentType is set on folder markers, as other S3 tools do.
+const folderContentType = "application/x-directory"
+
 type S3Client interface {
 	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 	ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error)
@@ -36,14 +42,72 @@ type S3Client interface {
 	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
 	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error)
+	PutBucketTagging(ctx context.Context, params *s3.PutBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error)
+	PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error)
+	GetObjectLockConfiguration(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error)
+	PutObjectLockConfiguration(ctx context.Context, params *s3.PutObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutObjectLockConfigurationOutput, error)
+	GetObjectRetention(ctx context.Context, params *s3.GetObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.GetObjectRetentionOutput, error)
+	PutObjectRetention(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error)
+	GetObjectLegalHold(ctx context.Context, params *s3.GetObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.GetObjectLegalHoldOutput, error)
+	PutObjectLegalHold(ctx context.Context, params *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error)
 }
 
 type Services struct {
 	s3Client S3Client
+	stats    *statsTracker
+	jobs     *jobManager
+	// deleteKey signs the confirmation tokens of recursive deletes. It's
+	// created on start, so tokens don't survive a restart.
+	deleteKey []byte
+	// trash is nil unless deleted objects are kept in the trash.
+	trash *trash
+	// fetcher is nil unless files may be fetched from URLs.
+	fetcher     *fetcher
+	thumbs      *thumbnailer
+	sortedLists *sortedListCache
+	// checksum is the algorithm of the checksums sent with uploads, none
+	// when empty.
+	checksum types.ChecksumAlgorithm
+}
+
+type Option func(*Services)
+
+// WithJobWorkers sets how many background jobs may run at once.
+func WithJobWorkers(n int) Option {
+	return func(s *Services) {
+		if n > 0 {
+			s.jobs.workers = n
+		}
+	}
 }
 
-func New(s3Client S3Client) *Services {
-	return &Services{s3Client: s3Client}
+func New(s3Client S3Client, opts ...Option) *Services {
+	s := &Services{
+		s3Client:    s3Client,
+		stats:       newStatsTracker(),
+		jobs:        newJobManager(),
+		deleteKey:   newDeleteKey(),
+		thumbs:      newThumbnailer(),
+		sortedLists: newSortedListCache(),
+		checksum:    types.ChecksumAlgorithmCrc32c,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.trash != nil && s.trash.retention > 0 {
+		ctx, stop := context.WithCancel(context.Background())
+		s.trash.stop = stop
+		go s.purgeTrashLoop(ctx)
+	}
+	return s
 }
 
 func (s *Services) ListObjects(
@@ -58,19 +122,21 @@ func (s *Services) ListObjects(
 	}
 	pathPrefix := prefix
 	prefix += opt.Filter
+	if needsFullListing(opt) {
+		return s.listObjectsSorted(ctx, bucketName, maxKeys, opt, prefix, pathPrefix)
+	}
 	params := &s3.ListObjectsV2Input{
-		Bucket:            aws.String(bucketName),
-		MaxKeys:           aws.Int32(maxKeys),
-		ContinuationToken: opt.ContinuationToken,
-		Prefix:            &prefix,
-		Delimiter:         aws.String("/"),
+		Bucket:                   aws.String(bucketName),
+		MaxKeys:                  aws.Int32(maxKeys),
+		ContinuationToken:        opt.ContinuationToken,
+		Prefix:                   &prefix,
+		Delimiter:                listDelimiter(opt),
+		FetchOwner:               aws.Bool(true),
+		OptionalObjectAttributes: restoreStatusAttrs,
 	}
 	list, err := s.s3Client.ListObjectsV2(ctx, params)
 	if err != nil {
-		if strings.Contains(err.Error(), "NoSuchBucket") {
-			return nil, nil, errs.NotFound(errs.WithMsg(ErrMissingBucket.Error()))
-		}
-		return nil, nil, err
+		return nil, nil, mapS3ErrToAppErr(err)
 	}
 	keyCount := int32(0)
 	if list.KeyCount != nil {
@@ -78,6 +144,9 @@ func (s *Services) ListObjects(
 	}
 	objects := make([]model.Object, 0, keyCount)
 	for _, obj := range list.CommonPrefixes {
+		if s.trash.hides(aws.ToString(obj.Prefix)) {
+			continue
+		}
 		var key *string
 		if obj.Prefix != nil {
 			key = aws.String(
@@ -90,23 +159,83 @@ func (s *Services) ListObjects(
 		})
 	}
 	for _, obj := range list.Contents {
-		var lastModified *string
-		var key *string
-		if obj.LastModified != nil {
-			lastModified = aws.String(obj.LastModified.Format(time.DateTime))
-		}
-		if obj.Key != nil {
-			key = aws.String(strings.TrimPrefix(*obj.Key, pathPrefix))
+		// The folder's own marker isn't one of its children.
+		if aws.ToString(obj.Key) == pathPrefix || s.trash.hides(aws.ToString(obj.Key)) {
+			continue
 		}
-		objects = append(objects, model.Object{
-			Key:          key,
-			Size:         obj.Size,
-			LastModified: lastModified,
-		})
+		objects = append(objects, toModelObject(obj, pathPrefix))
 	}
 	return objects, list.NextContinuationToken, nil
 }
 
+// listDelimiter groups keys into folders, unless a flat listing of every
+// nested key was asked for.
+func listDelimiter(opt model.ListObjectsOption) *string {
+	if opt.Recursive {
+		return nil
+	}
+	return aws.String("/")
+}
+
+// toModelObject converts a listed object, trimming pathPrefix from its key.
+// The owner is only known if it was asked for in the listing. Folder markers
+// are turned into directories, named like the common prefixes of a listing.
+func toModelObject(obj types.Object, pathPrefix string) model.Object {
+	var key *string
+	if obj.Key != nil {
+		key = aws.String(strings.TrimPrefix(*obj.Key, pathPrefix))
+	}
+	if isFolderMarker(obj) {
+		return model.Object{
+			Key:          aws.String(strings.TrimSuffix(aws.ToString(key), "/")),
+			IsDir:        true,
+			LastModified: formatTime(obj.LastModified),
+		}
+	}
+	o := model.Object{
+		Key:          key,
+		Size:         obj.Size,
+		LastModified: formatTime(obj.LastModified),
+		ETag:         obj.ETag,
+		StorageClass: aws.String(storageClass(obj.StorageClass)),
+		Owner:        toModelOwner(obj.Owner),
+		Restore:      toRestoreStatus(obj.RestoreStatus),
+	}
+	for _, algo := range obj.ChecksumAlgorithm {
+		o.ChecksumAlgorithms = append(o.ChecksumAlgorithms, string(algo))
+	}
+	return o
+}
+
+// isFolderMarker reports whether obj only stands for a folder, which S3
+// doesn't have otherwise.
+func isFolderMarker(obj types.Object) bool {
+	return strings.HasSuffix(aws.ToString(obj.Key), "/")
+}
+
+func toModelOwner(owner *types.Owner) *model.Owner {
+	if owner == nil || (owner.ID == nil && owner.DisplayName == nil) {
+		return nil
+	}
+	return &model.Owner{ID: owner.ID, DisplayName: owner.DisplayName}
+}
+
+// storageClass names the class of an object, S3 leaves it empty for STANDARD.
+func storageClass(class types.ObjectStorageClass) string {
+	if class == "" {
+		return string(types.ObjectStorageClassStandard)
+	}
+	return string(class)
+}
+
+// formatTime formats t as an RFC 3339 timestamp in UTC.
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	return aws.String(t.UTC().Format(time.RFC3339))
+}
+
 func (s *Services) ListBuckets(
 	ctx context.Context, count int32, opts model.ListBucketsOptions,
 ) ([]model.Bucket, *string, error) {
@@ -119,16 +248,14 @@ func (s *Services) ListBuckets(
 		}
 		list, err := s.s3Client.ListBuckets(ctx, params)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, mapS3ErrToAppErr(err)
 		}
 		for _, bucket := range list.Buckets {
-			var createdAt *string
-			if bucket.CreationDate != nil {
-				createdAt = aws.String(bucket.CreationDate.Format(time.DateTime))
-			}
 			allBuckets = append(allBuckets, model.Bucket{
 				Name:      bucket.Name,
-				CreatedAt: createdAt,
+				CreatedAt: formatTime(bucket.CreationDate),
+				Region:    bucket.BucketRegion,
+				Owner:     toModelOwner(list.Owner),
 			})
 		}
 		if list.ContinuationToken == nil {
@@ -176,47 +303,45 @@ func (s *Services) ListBuckets(
 	return result, nextToken, nil
 }
 
-func mapS3ErrToAppErr(err error) error {
-	if err == nil {
-		return nil
-	}
-	msg := strings.TrimSpace(strings.ToLower(err.Error()))
-	switch {
-	case strings.Contains(msg, "nosuchbucket"), strings.Contains(msg, "nosuchkey"), strings.Contains(msg, "no such bucket"), strings.Contains(msg, "no such key"):
-		return errs.NotFound(errs.WithErr(err))
-	case strings.Contains(msg, "invalidbucketname"), strings.Contains(msg, "invalid bucket name"):
-		return errs.BadRequest(errs.WithErr(err))
-	case strings.Contains(msg, "bucketalreadyownedbyyou"), strings.Contains(msg, "bucketalreadyexists"):
-		return errs.Conflict(errs.WithMsg("Bucket already exists"))
-	case strings.Contains(msg, "bucketnotempty"), strings.Contains(msg, "bucket not empty"):
-		return errs.Conflict(errs.WithMsg("Bucket is not empty"))
-	case strings.Contains(msg, "accessdenied"), strings.Contains(msg, "access denied"):
-		return errs.Forbidden(errs.WithErr(err))
-	case strings.Contains(msg, "slowdown"):
-		return errs.TooMany(errs.WithErr(err))
-	case strings.Contains(msg, "timeout"), strings.Contains(msg, "context deadline exceeded"):
-		return errs.Timeout(errs.WithErr(err))
-	case strings.Contains(msg, "internalerror"), strings.Contains(msg, "internalservererror"):
-		return errs.BadGateway(errs.WithErr(err))
-	case strings.Contains(msg, "invalidargument"):
-		return errs.Internal(errs.WithErr(err))
-	case strings.Contains(msg, "file too large"), strings.Contains(msg, "filetoolarge"), strings.Contains(msg, "entitytoolarge"):
-		return errs.New(http.StatusRequestEntityTooLarge, errs.WithErr(err))
-	case strings.Contains(msg, "serviceunavailable"), strings.Contains(msg, "service unavailable"):
-		return errs.New(http.StatusServiceUnavailable, errs.WithErr(err))
-	default:
-		return errs.BadGateway(errs.WithErr(err))
-	}
-}
-
-func (s *Services) CreateBucket(ctx context.Context, name string) error {
+// CreateBucket creates a bucket and applies the given options to it. Options
+// that S3 can't set on creation are applied afterward, and the bucket is
+// removed again if any of them fails, so callers never end up with a
+// half-configured bucket.
+func (s *Services) CreateBucket(
+	ctx context.Context, name string, opts model.CreateBucketOptions,
+) error {
 	params := &s3.CreateBucketInput{
 		Bucket: aws.String(name),
 	}
+	// us-east-1 is the implicit default and is rejected as explicit constraint.
+	if opts.Region != "" && opts.Region != "us-east-1" {
+		params.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(opts.Region),
+		}
+	}
+	if opts.ObjectLock != nil && opts.ObjectLock.Enabled {
+		params.ObjectLockEnabledForBucket = aws.Bool(true)
+	}
+	if opts.ObjectOwnership != "" {
+		params.ObjectOwnership = types.ObjectOwnership(opts.ObjectOwnership)
+	}
 	_, err := s.s3Client.CreateBucket(ctx, params)
 	if err != nil {
 		return mapS3ErrToAppErr(err)
 	}
+
+	err = s.applyBucketOptions(ctx, name, opts)
+	if err != nil {
+		_, rbErr := s.s3Client.DeleteBucket(
+			ctx, &s3.DeleteBucketInput{Bucket: aws.String(name)},
+		)
+		if rbErr != nil {
+			return errors.Join(
+				err, fmt.Errorf("rolling back bucket creation: %w", rbErr),
+			)
+		}
+		return err
+	}
 	return nil
 }
 
@@ -232,7 +357,7 @@ func (s *Services) DeleteBucket(ctx context.Context, name string, recursive bool
 	}
 
 	// If bucket isn't empty and recursive flag set, attempt to delete objects then retry.
-	if strings.Contains(err.Error(), "BucketNotEmpty") && recursive {
+	if errorCode(err) == "BucketNotEmpty" && recursive {
 		if derr := s.deleteAllObjects(ctx, name); derr != nil {
 			return fmt.Errorf("deleting objects in bucket: %w", derr)
 		}
@@ -241,6 +366,13 @@ func (s *Services) DeleteBucket(ctx context.Context, name string, recursive bool
 		if err == nil {
 			return nil
 		}
+		// Locked buckets keep protected versions around even after every
+		// current object has been removed.
+		if errorCode(err) == "BucketNotEmpty" && s.objectLockEnabled(ctx, name) {
+			return withStatus(
+				newS3Error(err), http.StatusConflict, ErrBucketLocked.Error(),
+			)
+		}
 	}
 
 	// Map/convert S3 errors to application errs (including upstream mapping).
@@ -255,7 +387,7 @@ func (s *Services) deleteAllObjects(ctx context.Context, bucketName string) erro
 	for {
 		list, err := s.s3Client.ListObjectsV2(ctx, params)
 		if err != nil {
-			return fmt.Errorf("listing objects for deletion: %w", err)
+			return fmt.Errorf("listing objects for deletion: %w", mapS3ErrToAppErr(err))
 		}
 		// Delete objects in batches
 		if len(list.Contents) > 0 {
@@ -266,7 +398,7 @@ func (s *Services) deleteAllObjects(ctx context.Context, bucketName string) erro
 				})
 			}
 
-			_, err = s.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			out, err := s.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
 				Bucket: aws.String(bucketName),
 				Delete: &types.Delete{
 					Objects: objectsToDelete,
@@ -274,7 +406,10 @@ func (s *Services) deleteAllObjects(ctx context.Context, bucketName string) erro
 				},
 			})
 			if err != nil {
-				return fmt.Errorf("deleting objects: %w", err)
+				return fmt.Errorf("deleting objects: %w", mapS3ErrToAppErr(err))
+			}
+			if err = deleteErrsToAppErr(out.Errors); err != nil {
+				return err
 			}
 		}
 		if list.NextContinuationToken == nil {
@@ -286,13 +421,18 @@ func (s *Services) deleteAllObjects(ctx context.Context, bucketName string) erro
 }
 
 func (s *Services) PutObject(
-	ctx context.Context, bucketName, objectKey, mimeType string, r io.Reader,
+	ctx context.Context,
+	bucketName, objectKey, mimeType string,
+	r io.Reader,
+	opts model.PutObjectOptions,
 ) (*model.Object, error) {
 	params := &s3.PutObjectInput{
-		Bucket:      aws.String(bucketName),
-		Key:         aws.String(objectKey),
-		ContentType: aws.String(mimeType),
-		Body:        r,
+		Bucket:            aws.String(bucketName),
+		Key:               aws.String(objectKey),
+		ContentType:       aws.String(mimeType),
+		StorageClass:      types.StorageClass(opts.StorageClass),
+		ChecksumAlgorithm: s.checksum,
+		Body:              r,
 	}
 	output, err := s.s3Client.PutObject(ctx, params)
 	if err != nil {
@@ -302,15 +442,48 @@ func (s *Services) PutObject(
 	return &model.Object{
 		Key:          &objectKey,
 		Size:         output.Size,
-		LastModified: aws.String(time.Now().Format(time.DateTime)),
+		LastModified: formatTime(aws.Time(time.Now())),
+		ETag:         output.ETag,
+		StorageClass: aws.String(storageClass(types.ObjectStorageClass(opts.StorageClass))),
+		Checksums: checksums(
+			output.ChecksumCRC32, output.ChecksumCRC32C, output.ChecksumCRC64NVME,
+			output.ChecksumSHA1, output.ChecksumSHA256,
+		),
+	}, nil
+}
+
+// CreateFolder puts an empty marker at path, so the folder is listed before
+// anything is put in it.
+func (s *Services) CreateFolder(
+	ctx context.Context, bucketName, path string,
+) (*model.Object, error) {
+	path = strings.TrimSuffix(path, "/")
+	_, err := s.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucketName),
+		Key:         aws.String(path + "/"),
+		ContentType: aws.String(folderContentType),
+		Body:        bytes.NewReader(nil),
+	})
+	if err != nil {
+		return nil, mapS3ErrToAppErr(err)
+	}
+	return &model.Object{
+		Key:          aws.String(path),
+		IsDir:        true,
+		LastModified: formatTime(aws.Time(time.Now())),
 	}, nil
 }
 
 func (s *Services) DeleteObject(
-	ctx context.Context, bucketName, objectKey string, recursive bool,
+	ctx context.Context,
+	bucketName, objectKey string,
+	opts model.DeleteObjectOptions,
 ) error {
-	if recursive {
-		return s.deleteObjectsWithPrefix(ctx, bucketName, objectKey)
+	if opts.Recursive {
+		if opts.Permanent || s.trash == nil {
+			return s.deleteObjectsWithPrefix(ctx, bucketName, objectKey)
+		}
+		return s.trashPrefix(ctx, bucketName, objectKey, opts.DeletedBy)
 	}
 
 	// Check if attempting to delete a non-empty directory without recursive flag
@@ -326,17 +499,28 @@ func (s *Services) DeleteObject(
 	list, err := s.s3Client.ListObjectsV2(ctx, listParams)
 	switch {
 	case err != nil:
-		return fmt.Errorf("checking for objects: %w", err)
+		return fmt.Errorf("checking for objects: %w", mapS3ErrToAppErr(err))
 	case len(list.Contents) > 0:
 		return errs.BadRequest(errs.WithMsg(ErrDirNotEmpty.Error()))
 	}
 
+	if !opts.Permanent && s.trash.keeps(bucketName, objectKey) {
+		err = s.moveToTrash(ctx, bucketName, objectKey, opts.DeletedBy)
+		if !errors.Is(err, errTrashMissing) {
+			return err
+		}
+		// Nothing to keep, deleting a missing key still succeeds.
+	}
+
 	params := &s3.DeleteObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(objectKey),
 	}
 	_, err = s.s3Client.DeleteObject(ctx, params)
-	return err
+	if err != nil {
+		return mapS3ErrToAppErr(err)
+	}
+	return nil
 }
 
 func (s *Services) deleteObjectsWithPrefix(
@@ -349,7 +533,7 @@ func (s *Services) deleteObjectsWithPrefix(
 	for {
 		list, err := s.s3Client.ListObjectsV2(ctx, params)
 		if err != nil {
-			return fmt.Errorf("listing objects for deletion: %w", err)
+			return fmt.Errorf("listing objects for deletion: %w", mapS3ErrToAppErr(err))
 		}
 		// Delete objects in batches
 		if len(list.Contents) > 0 {
@@ -360,7 +544,7 @@ func (s *Services) deleteObjectsWithPrefix(
 				})
 			}
 
-			_, err = s.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			out, err := s.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
 				Bucket: aws.String(bucketName),
 				Delete: &types.Delete{
 					Objects: objectsToDelete,
@@ -368,7 +552,10 @@ func (s *Services) deleteObjectsWithPrefix(
 				},
 			})
 			if err != nil {
-				return fmt.Errorf("deleting objects: %w", err)
+				return fmt.Errorf("deleting objects: %w", mapS3ErrToAppErr(err))
+			}
+			if err = deleteErrsToAppErr(out.Errors); err != nil {
+				return err
 			}
 		}
 		if list.NextContinuationToken == nil {
@@ -382,19 +569,48 @@ func (s *Services) deleteObjectsWithPrefix(
 func (s *Services) GetObject(
 	ctx context.Context, bucketName, objectKey string,
 ) (io.ReadCloser, *string, error) {
+	// With the checksum mode on, the body fails at its end if it doesn't
+	// match the checksum it was uploaded with.
 	params := &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(objectKey),
+		Bucket:       aws.String(bucketName),
+		Key:          aws.String(objectKey),
+		ChecksumMode: types.ChecksumModeEnabled,
 	}
 	out, err := s.s3Client.GetObject(ctx, params)
 	if err != nil {
-		var opErr *smithy.OperationError
-		if errors.As(err, &opErr) {
-			return nil, nil, errs.NotFound(
-				errs.WithErr(opErr.Unwrap()), errs.WithMsg("object not found"),
-			)
-		}
-		return nil, nil, err
+		return nil, nil, mapS3ErrToAppErr(err)
 	}
 	return out.Body, out.ContentType, nil
 }
+
+// StatObject reads the properties and user metadata of an object, without
+// its content.
+func (s *Services) StatObject(
+	ctx context.Context, bucketName, objectKey string,
+) (*model.Object, error) {
+	out, err := s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(bucketName),
+		Key:          aws.String(objectKey),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return nil, mapS3ErrToAppErr(err)
+	}
+	o := &model.Object{
+		Key:          aws.String(objectKey),
+		Size:         out.ContentLength,
+		LastModified: formatTime(out.LastModified),
+		ETag:         out.ETag,
+		StorageClass: aws.String(storageClass(types.ObjectStorageClass(out.StorageClass))),
+		ContentType:  out.ContentType,
+		Checksums: checksums(
+			out.ChecksumCRC32, out.ChecksumCRC32C, out.ChecksumCRC64NVME,
+			out.ChecksumSHA1, out.ChecksumSHA256,
+		),
+		Restore: parseRestoreHeader(out.Restore),
+	}
+	if len(out.Metadata) > 0 {
+		o.Metadata = out.Metadata
+	}
+	return o, nil
+}
@@ -1,16 +1,39 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
 	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/hossein1376/s3manager/internal/metrics"
 	"github.com/hossein1376/s3manager/internal/model"
 	"github.com/stretchr/testify/assert"
 )
@@ -25,6 +48,21 @@ type mockS3Client struct {
 	putObjectFunc     func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 	deleteObjectFunc  func(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
 	getObjectFunc     func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+
+	putBucketVersioningFunc        func(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error)
+	putBucketTaggingFunc           func(ctx context.Context, params *s3.PutBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error)
+	putBucketEncryptionFunc        func(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error)
+	getObjectLockConfigurationFunc func(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error)
+	putObjectLockConfigurationFunc func(ctx context.Context, params *s3.PutObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutObjectLockConfigurationOutput, error)
+	getObjectRetentionFunc         func(ctx context.Context, params *s3.GetObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.GetObjectRetentionOutput, error)
+	putObjectRetentionFunc         func(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error)
+	copyObjectFunc                 func(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	headObjectFunc                 func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	createMultipartUploadFunc      func(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	uploadPartFunc                 func(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	completeMultipartUploadFunc    func(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	abortMultipartUploadFunc       func(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	restoreObjectFunc              func(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error)
 }
 
 func (m *mockS3Client) ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
@@ -59,6 +97,89 @@ func (m *mockS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput,
 	return m.getObjectFunc(ctx, params, optFns...)
 }
 
+func (m *mockS3Client) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
+	return m.putBucketVersioningFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) PutBucketTagging(ctx context.Context, params *s3.PutBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error) {
+	return m.putBucketTaggingFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
+	return m.putBucketEncryptionFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) GetObjectLockConfiguration(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+	return m.getObjectLockConfigurationFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) PutObjectLockConfiguration(ctx context.Context, params *s3.PutObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutObjectLockConfigurationOutput, error) {
+	return m.putObjectLockConfigurationFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) GetObjectRetention(ctx context.Context, params *s3.GetObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.GetObjectRetentionOutput, error) {
+	return m.getObjectRetentionFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) PutObjectRetention(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+	return m.putObjectRetentionFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return m.copyObjectFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return m.headObjectFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return m.createMultipartUploadFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return m.uploadPartFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return m.completeMultipartUploadFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return m.abortMultipartUploadFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	return m.restoreObjectFunc(ctx, params, optFns...)
+}
+
+// apiErr builds an error the way the SDK returns a failed S3 request.
+func apiErr(status int, code, msg string) error {
+	return &smithy.OperationError{
+		ServiceID:     "S3",
+		OperationName: "Test",
+		Err: &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{
+					Response: &http.Response{StatusCode: status},
+				},
+				Err: &smithy.GenericAPIError{Code: code, Message: msg},
+			},
+			RequestID: "4442587FB7D0A2F9",
+		},
+	}
+}
+
+// hostIDErr adds the host ID S3 answers with, as the SDK's internal S3
+// response error does.
+type hostIDErr struct {
+	error
+	hostID string
+}
+
+func (e hostIDErr) ServiceHostID() string { return e.hostID }
+func (e hostIDErr) Unwrap() error         { return e.error }
+
 func TestServices_ListObjects(t *testing.T) {
 	t.Parallel()
 	a := assert.New(t)
@@ -100,7 +221,7 @@ func TestServices_ListObjects(t *testing.T) {
 			bucket:  "missing-bucket",
 			maxKeys: 10,
 			opt:     model.ListObjectsOption{},
-			mockErr: errors.New("NoSuchBucket"),
+			mockErr: apiErr(http.StatusNotFound, "NoSuchBucket", ""),
 			wantErr: true,
 		},
 		{
@@ -117,7 +238,7 @@ func TestServices_ListObjects(t *testing.T) {
 			bucket:  "",
 			maxKeys: 10,
 			opt:     model.ListObjectsOption{},
-			mockErr: errors.New("InvalidBucketName"),
+			mockErr: apiErr(http.StatusBadRequest, "InvalidBucketName", ""),
 			wantErr: true,
 		},
 		{
@@ -149,6 +270,231 @@ func TestServices_ListObjects(t *testing.T) {
 	}
 }
 
+func TestServices_ListObjectsMetadata(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	tehran := time.FixedZone("IRST", 3*60*60+30*60)
+	modified := time.Date(2024, 5, 1, 12, 30, 0, 0, tehran)
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			a.True(*params.FetchOwner)
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{
+						Key:               aws.String("report.pdf"),
+						Size:              aws.Int64(10),
+						LastModified:      &modified,
+						ETag:              aws.String(`"9b2cf535f27731c974343645a3985328"`),
+						ChecksumAlgorithm: []types.ChecksumAlgorithm{types.ChecksumAlgorithmCrc32c},
+						Owner:             &types.Owner{ID: aws.String("owner-id"), DisplayName: aws.String("alice")},
+					},
+					{Key: aws.String("archive.tar"), StorageClass: types.ObjectStorageClassGlacier},
+				},
+			}, nil
+		},
+		listBucketsFunc: func(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+			return &s3.ListBucketsOutput{
+				Buckets: []types.Bucket{
+					{Name: aws.String("b"), CreationDate: &modified, BucketRegion: aws.String("eu-west-1")},
+				},
+				Owner: &types.Owner{ID: aws.String("owner-id")},
+			}, nil
+		},
+	}
+	s := New(mock)
+
+	got, _, err := s.ListObjects(context.Background(), "test-bucket", 10, model.ListObjectsOption{})
+	a.NoError(err)
+	a.Equal("2024-05-01T09:00:00Z", *got[0].LastModified)
+	a.Equal(`"9b2cf535f27731c974343645a3985328"`, *got[0].ETag)
+	a.Equal("STANDARD", *got[0].StorageClass)
+	a.Equal([]string{"CRC32C"}, got[0].ChecksumAlgorithms)
+	a.Equal("alice", *got[0].Owner.DisplayName)
+	a.Equal("GLACIER", *got[1].StorageClass)
+	a.Nil(got[1].Owner)
+
+	buckets, _, err := s.ListBuckets(context.Background(), 10, model.ListBucketsOptions{})
+	a.NoError(err)
+	a.Equal("2024-05-01T09:00:00Z", *buckets[0].CreatedAt)
+	a.Equal("eu-west-1", *buckets[0].Region)
+	a.Equal("owner-id", *buckets[0].Owner.ID)
+}
+
+func TestServices_ListObjectsRecursive(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			a.Nil(params.Delimiter)
+			a.Equal("logs/", *params.Prefix)
+			a.Equal("page-2", *params.ContinuationToken)
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String("logs/2024/01/app.log"), Size: aws.Int64(10)},
+					{Key: aws.String("logs/2024/02/app.log"), Size: aws.Int64(20)},
+				},
+				NextContinuationToken: aws.String("page-3"),
+			}, nil
+		},
+	}
+	s := New(mock)
+
+	got, next, err := s.ListObjects(context.Background(), "test-bucket", 2, model.ListObjectsOption{
+		Path:              "logs",
+		Recursive:         true,
+		ContinuationToken: aws.String("page-2"),
+	})
+	a.NoError(err)
+	a.Len(got, 2)
+	a.Equal("2024/01/app.log", *got[0].Key)
+	a.False(got[0].IsDir)
+	a.Equal("page-3", *next)
+}
+
+func TestServices_FolderMarkers(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var put *s3.PutObjectInput
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if params.Delimiter == nil {
+				return &s3.ListObjectsV2Output{
+					Contents: []types.Object{
+						{Key: aws.String("docs/"), Size: aws.Int64(0)},
+						{Key: aws.String("docs/drafts/"), Size: aws.Int64(0)},
+						{Key: aws.String("docs/guide.md"), Size: aws.Int64(10)},
+					},
+				}, nil
+			}
+			return &s3.ListObjectsV2Output{
+				CommonPrefixes: []types.CommonPrefix{{Prefix: aws.String("docs/drafts/")}},
+				Contents: []types.Object{
+					{Key: aws.String("docs/"), Size: aws.Int64(0)},
+					{Key: aws.String("docs/guide.md"), Size: aws.Int64(10)},
+				},
+			}, nil
+		},
+		putObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			put = params
+			return &s3.PutObjectOutput{}, nil
+		},
+	}
+	s := New(mock)
+
+	// The folder's own marker is left out, like the empty folder it stands
+	// for is listed as a directory.
+	got, _, err := s.ListObjects(context.Background(), "test-bucket", 10, model.ListObjectsOption{Path: "docs"})
+	a.NoError(err)
+	a.Len(got, 2)
+	a.Equal("drafts", *got[0].Key)
+	a.True(got[0].IsDir)
+	a.Equal("guide.md", *got[1].Key)
+
+	got, _, err = s.ListObjects(context.Background(), "test-bucket", 10, model.ListObjectsOption{Path: "docs", Recursive: true})
+	a.NoError(err)
+	a.Len(got, 2)
+	a.Equal("drafts", *got[0].Key)
+	a.True(got[0].IsDir)
+	a.Nil(got[0].Size)
+
+	got, _, err = s.ListObjects(context.Background(), "test-bucket", 10, model.ListObjectsOption{Path: "docs", SortBy: model.SortBySize})
+	a.NoError(err)
+	a.Len(got, 2)
+	a.True(got[0].IsDir)
+
+	folder, err := s.CreateFolder(context.Background(), "test-bucket", "docs/archive/")
+	a.NoError(err)
+	a.Equal("docs/archive", *folder.Key)
+	a.True(folder.IsDir)
+	a.Equal("docs/archive/", *put.Key)
+	a.Equal(folderContentType, *put.ContentType)
+}
+
+func TestServices_ListObjectsSorted(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	var listed atomic.Int32
+	contents := []types.Object{
+		{Key: aws.String("data/a.csv"), Size: aws.Int64(300), LastModified: aws.Time(day)},
+		{Key: aws.String("data/b.csv"), Size: aws.Int64(100), LastModified: aws.Time(day.Add(time.Hour))},
+		{Key: aws.String("data/c.json"), Size: aws.Int64(200), LastModified: aws.Time(day.Add(2 * time.Hour))},
+		{Key: aws.String("data/d.csv"), Size: aws.Int64(100), LastModified: aws.Time(day), StorageClass: types.ObjectStorageClassGlacier},
+	}
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			a.Nil(params.MaxKeys)
+			listed.Add(1)
+			if params.ContinuationToken == nil {
+				return &s3.ListObjectsV2Output{
+					CommonPrefixes:        []types.CommonPrefix{{Prefix: aws.String("data/sub/")}},
+					Contents:              contents[:2],
+					NextContinuationToken: aws.String("1"),
+				}, nil
+			}
+			return &s3.ListObjectsV2Output{Contents: contents[2:]}, nil
+		},
+	}
+	s := New(mock)
+	ctx := context.Background()
+
+	keys := func(list []model.Object) []string {
+		out := make([]string, 0, len(list))
+		for _, obj := range list {
+			out = append(out, *obj.Key)
+		}
+		return out
+	}
+
+	opt := model.ListObjectsOption{Path: "data", SortBy: model.SortBySize, Descending: true}
+	got, next, err := s.ListObjects(ctx, "test-bucket", 3, opt)
+	a.NoError(err)
+	a.Equal([]string{"sub", "a.csv", "c.json"}, keys(got))
+	a.NotNil(next)
+
+	opt.ContinuationToken = next
+	got, next, err = s.ListObjects(ctx, "test-bucket", 3, opt)
+	a.NoError(err)
+	a.Equal([]string{"d.csv", "b.csv"}, keys(got))
+	a.Nil(next)
+	// The later pages reuse the listing read for the first one.
+	a.Equal(int32(2), listed.Load())
+
+	opt.ContinuationToken = nil
+	_, next, err = s.ListObjects(ctx, "test-bucket", 3, opt)
+	a.NoError(err)
+	a.Equal(int32(4), listed.Load())
+	for _, list := range s.sortedLists.lists {
+		list.listed = list.listed.Add(-sortedListTTL)
+	}
+	opt.ContinuationToken = next
+	got, _, err = s.ListObjects(ctx, "test-bucket", 3, opt)
+	a.NoError(err)
+	a.Equal([]string{"d.csv", "b.csv"}, keys(got))
+	a.Equal(int32(6), listed.Load())
+
+	for _, count := range []int32{0, -1} {
+		_, _, err = s.ListObjects(ctx, "test-bucket", count, model.ListObjectsOption{
+			Path: "data", SortBy: model.SortBySize,
+		})
+		a.ErrorContains(err, ErrInvalidCount.Error(), count)
+	}
+
+	// The token only works with the options it was issued for.
+	opt.SortBy = model.SortByModified
+	_, _, err = s.ListObjects(ctx, "test-bucket", 3, opt)
+	a.Error(err)
+
+	got, _, err = s.ListObjects(ctx, "test-bucket", 10, model.ListObjectsOption{
+		Path:           "data",
+		SortBy:         model.SortByModified,
+		Extensions:     []string{"csv"},
+		StorageClasses: []string{"STANDARD"},
+	})
+	a.NoError(err)
+	a.Equal([]string{"a.csv", "b.csv"}, keys(got))
+}
+
 func TestServices_ListBuckets(t *testing.T) {
 	t.Parallel()
 	a := assert.New(t)
@@ -218,7 +564,7 @@ func TestServices_ListBuckets(t *testing.T) {
 			name:    "access denied",
 			count:   10,
 			opts:    model.ListBucketsOptions{},
-			mockErr: errors.New("AccessDenied"),
+			mockErr: apiErr(http.StatusForbidden, "AccessDenied", ""),
 			wantErr: true,
 		},
 	}
@@ -260,25 +606,25 @@ func TestServices_CreateBucket(t *testing.T) {
 		{
 			name:    "already exists",
 			bucket:  "existing-bucket",
-			mockErr: errors.New("BucketAlreadyOwnedByYou"),
+			mockErr: apiErr(http.StatusConflict, "BucketAlreadyOwnedByYou", ""),
 			wantErr: errors.New("Conflict"),
 		},
 		{
 			name:    "invalid name",
 			bucket:  "invalid_name",
-			mockErr: errors.New("InvalidBucketName"),
+			mockErr: apiErr(http.StatusBadRequest, "InvalidBucketName", ""),
 			wantErr: errors.New("Bad Request"),
 		},
 		{
 			name:    "empty name",
 			bucket:  "",
-			mockErr: errors.New("InvalidBucketName"),
+			mockErr: apiErr(http.StatusBadRequest, "InvalidBucketName", ""),
 			wantErr: errors.New("Bad Request"),
 		},
 		{
 			name:    "access denied",
 			bucket:  "denied-bucket",
-			mockErr: errors.New("AccessDenied"),
+			mockErr: apiErr(http.StatusForbidden, "AccessDenied", ""),
 			wantErr: errors.New("Forbidden"),
 		},
 	}
@@ -292,7 +638,7 @@ func TestServices_CreateBucket(t *testing.T) {
 				},
 			}
 			s := New(mock)
-			err := s.CreateBucket(context.Background(), tt.bucket)
+			err := s.CreateBucket(context.Background(), tt.bucket, model.CreateBucketOptions{})
 			if tt.wantErr != nil {
 				a.Error(err)
 				a.Contains(err.Error(), tt.wantErr.Error())
@@ -324,21 +670,21 @@ func TestServices_DeleteBucket(t *testing.T) {
 			name:      "not empty",
 			bucket:    "full-bucket",
 			recursive: false,
-			mockErr:   errors.New("BucketNotEmpty"),
+			mockErr:   apiErr(http.StatusConflict, "BucketNotEmpty", ""),
 			wantErr:   errors.New("Conflict"),
 		},
 		{
 			name:      "not found",
 			bucket:    "missing-bucket",
 			recursive: false,
-			mockErr:   errors.New("NoSuchBucket"),
+			mockErr:   apiErr(http.StatusNotFound, "NoSuchBucket", ""),
 			wantErr:   errors.New("Not Found"),
 		},
 		{
 			name:      "empty name",
 			bucket:    "",
 			recursive: false,
-			mockErr:   errors.New("InvalidBucketName"),
+			mockErr:   apiErr(http.StatusBadRequest, "InvalidBucketName", ""),
 			wantErr:   errors.New("Bad Request"),
 		},
 		{
@@ -412,7 +758,7 @@ func TestServices_PutObject(t *testing.T) {
 			key:         "test.txt",
 			contentType: "text/plain",
 			content:     "hello",
-			mockErr:     errors.New("NoSuchBucket"),
+			mockErr:     apiErr(http.StatusNotFound, "NoSuchBucket", ""),
 			wantErr:     true,
 		},
 		{
@@ -421,7 +767,7 @@ func TestServices_PutObject(t *testing.T) {
 			key:         "test.txt",
 			contentType: "text/plain",
 			content:     "hello",
-			mockErr:     errors.New("AccessDenied"),
+			mockErr:     apiErr(http.StatusForbidden, "AccessDenied", ""),
 			wantErr:     true,
 		},
 	}
@@ -435,7 +781,7 @@ func TestServices_PutObject(t *testing.T) {
 				},
 			}
 			s := New(mock)
-			got, err := s.PutObject(context.Background(), tt.bucket, tt.key, tt.contentType, strings.NewReader(tt.content))
+			got, err := s.PutObject(context.Background(), tt.bucket, tt.key, tt.contentType, strings.NewReader(tt.content), model.PutObjectOptions{})
 			a.Equal(tt.wantErr, err != nil)
 			if err == nil {
 				a.Equal(tt.key, *got.Key)
@@ -476,7 +822,7 @@ func TestServices_DeleteObject(t *testing.T) {
 			bucket:    "missing-bucket",
 			key:       "file.txt",
 			recursive: false,
-			mockErr:   errors.New("NoSuchBucket"),
+			mockErr:   apiErr(http.StatusNotFound, "NoSuchBucket", ""),
 			wantErr:   true,
 		},
 		{
@@ -484,7 +830,7 @@ func TestServices_DeleteObject(t *testing.T) {
 			bucket:    "test-bucket",
 			key:       "",
 			recursive: false,
-			mockErr:   errors.New("KeyTooLongError"), // or appropriate error
+			mockErr:   apiErr(http.StatusBadRequest, "KeyTooLongError", ""), // or appropriate error
 			wantErr:   true,
 		},
 		{
@@ -520,7 +866,7 @@ func TestServices_DeleteObject(t *testing.T) {
 				},
 			}
 			s := New(mock)
-			err := s.DeleteObject(context.Background(), tt.bucket, tt.key, tt.recursive)
+			err := s.DeleteObject(context.Background(), tt.bucket, tt.key, model.DeleteObjectOptions{Recursive: tt.recursive})
 			a.Equal(tt.wantErr, err != nil)
 		})
 	}
@@ -580,21 +926,21 @@ func TestServices_GetObject(t *testing.T) {
 			name:    "object not found",
 			bucket:  "test-bucket",
 			key:     "missing.txt",
-			mockErr: errors.New("NoSuchKey"),
+			mockErr: apiErr(http.StatusNotFound, "NoSuchKey", ""),
 			wantErr: true,
 		},
 		{
 			name:    "bucket not found",
 			bucket:  "missing-bucket",
 			key:     "test.txt",
-			mockErr: errors.New("NoSuchBucket"),
+			mockErr: apiErr(http.StatusNotFound, "NoSuchBucket", ""),
 			wantErr: true,
 		},
 		{
 			name:    "access denied",
 			bucket:  "denied-bucket",
 			key:     "test.txt",
-			mockErr: errors.New("AccessDenied"),
+			mockErr: apiErr(http.StatusForbidden, "AccessDenied", ""),
 			wantErr: true,
 		},
 	}
@@ -628,3 +974,2066 @@ func TestServices_GetObject(t *testing.T) {
 		})
 	}
 }
+
+func TestServices_StatObject(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock := &mockS3Client{
+		headObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			if *params.Key != "report.csv" {
+				return nil, apiErr(http.StatusNotFound, "NotFound", "")
+			}
+			return &s3.HeadObjectOutput{
+				ContentLength: aws.Int64(42),
+				LastModified:  &modified,
+				ETag:          aws.String(`"abc"`),
+				ContentType:   aws.String("text/csv"),
+				Metadata:      map[string]string{"owner": "ops"},
+			}, nil
+		},
+	}
+	s := New(mock)
+
+	obj, err := s.StatObject(context.Background(), "test-bucket", "report.csv")
+	a.NoError(err)
+	a.Equal("report.csv", *obj.Key)
+	a.Equal(int64(42), *obj.Size)
+	a.Equal("2024-05-01T12:00:00Z", *obj.LastModified)
+	a.Equal("STANDARD", *obj.StorageClass)
+	a.Equal("text/csv", *obj.ContentType)
+	a.Equal(map[string]string{"owner": "ops"}, obj.Metadata)
+
+	_, err = s.StatObject(context.Background(), "test-bucket", "missing.csv")
+	a.ErrorContains(err, "Not Found")
+}
+
+func TestServices_RestoreObject(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	heads := map[string]*s3.HeadObjectOutput{
+		"logs/2019.tar": {StorageClass: types.StorageClassGlacier},
+		"logs/2018.tar": {StorageClass: types.StorageClassDeepArchive},
+		"logs/2017.tar": {
+			StorageClass: types.StorageClassGlacier,
+			Restore:      aws.String(`ongoing-request="true"`),
+		},
+		"logs/2016.tar": {
+			StorageClass: types.StorageClassGlacier,
+			Restore:      aws.String(`ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`),
+		},
+		"logs/today.log": {},
+	}
+	var restored []*s3.RestoreObjectInput
+	mock := &mockS3Client{
+		headObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return heads[*params.Key], nil
+		},
+		restoreObjectFunc: func(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+			restored = append(restored, params)
+			return &s3.RestoreObjectOutput{}, nil
+		},
+		getObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			return nil, &smithy.OperationError{
+				ServiceID:     "S3",
+				OperationName: "GetObject",
+				Err: &types.InvalidObjectState{
+					Message: aws.String("The operation is not valid for the object's storage class"),
+				},
+			}
+		},
+	}
+	s := New(mock)
+	ctx := context.Background()
+	opts := model.RestoreObjectOptions{Days: 3, Tier: model.RestoreTierBulk}
+
+	a.NoError(s.RestoreObject(ctx, "test-bucket", "logs/2019.tar", opts))
+	a.Len(restored, 1)
+	a.Equal(int32(3), *restored[0].RestoreRequest.Days)
+	a.Equal(types.TierBulk, restored[0].RestoreRequest.GlacierJobParameters.Tier)
+	// Restoring again once it's done extends the restored copy.
+	a.NoError(s.RestoreObject(ctx, "test-bucket", "logs/2016.tar", opts))
+	a.Len(restored, 2)
+
+	err := s.RestoreObject(ctx, "test-bucket", "logs/2017.tar", opts)
+	a.ErrorContains(err, ErrRestoreRunning.Error())
+	err = s.RestoreObject(ctx, "test-bucket", "logs/today.log", opts)
+	a.ErrorContains(err, ErrNotArchived.Error())
+	opts.Tier = model.RestoreTierExpedited
+	err = s.RestoreObject(ctx, "test-bucket", "logs/2018.tar", opts)
+	a.ErrorContains(err, ErrExpeditedRestore.Error())
+	a.Len(restored, 2)
+
+	obj, err := s.StatObject(ctx, "test-bucket", "logs/2016.tar")
+	a.NoError(err)
+	a.Equal(&model.RestoreStatus{
+		InProgress: false, ExpiresAt: aws.String("2012-12-21T00:00:00Z"),
+	}, obj.Restore)
+
+	// An archived object isn't missing, it has to be restored first.
+	_, _, err = s.GetObject(ctx, "test-bucket", "logs/2019.tar")
+	a.ErrorContains(err, "Conflict")
+	a.ErrorContains(err, ErrNotRestored.Error())
+}
+
+func TestServices_GetBucketObjectLock(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	tests := []struct {
+		name        string
+		mockResp    *s3.GetObjectLockConfigurationOutput
+		mockErr     error
+		wantEnabled bool
+		wantMode    string
+		wantErr     bool
+	}{
+		{
+			name: "enabled with default retention",
+			mockResp: &s3.GetObjectLockConfigurationOutput{
+				ObjectLockConfiguration: &types.ObjectLockConfiguration{
+					ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+					Rule: &types.ObjectLockRule{
+						DefaultRetention: &types.DefaultRetention{
+							Mode: types.ObjectLockRetentionModeCompliance,
+							Days: aws.Int32(7),
+						},
+					},
+				},
+			},
+			wantEnabled: true,
+			wantMode:    model.RetentionCompliance,
+		},
+		{
+			name:        "not configured",
+			mockErr:     apiErr(http.StatusNotFound, "ObjectLockConfigurationNotFoundError", ""),
+			wantEnabled: false,
+		},
+		{
+			name:    "bucket not found",
+			mockErr: apiErr(http.StatusNotFound, "NoSuchBucket", ""),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockS3Client{
+				getObjectLockConfigurationFunc: func(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+					return tt.mockResp, tt.mockErr
+				},
+			}
+			s := New(mock)
+			got, err := s.GetBucketObjectLock(context.Background(), "test-bucket")
+			a.Equal(tt.wantErr, err != nil)
+			if err == nil {
+				a.Equal(tt.wantEnabled, got.Enabled)
+				if tt.wantMode != "" {
+					a.Equal(tt.wantMode, got.DefaultRetention.Mode)
+				}
+			}
+		})
+	}
+}
+
+func TestServices_ObjectRetention(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	var putParams *s3.PutObjectRetentionInput
+	mock := &mockS3Client{
+		getObjectRetentionFunc: func(ctx context.Context, params *s3.GetObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.GetObjectRetentionOutput, error) {
+			if *params.Key == "unlocked.txt" {
+				return nil, apiErr(http.StatusNotFound, "NoSuchObjectLockConfiguration", "")
+			}
+			return &s3.GetObjectRetentionOutput{
+				Retention: &types.ObjectLockRetention{
+					Mode:            types.ObjectLockRetentionModeGovernance,
+					RetainUntilDate: &until,
+				},
+			}, nil
+		},
+		putObjectRetentionFunc: func(ctx context.Context, params *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+			putParams = params
+			return &s3.PutObjectRetentionOutput{}, nil
+		},
+	}
+	s := New(mock)
+
+	got, err := s.GetObjectRetention(context.Background(), "test-bucket", "locked.txt")
+	a.NoError(err)
+	a.Equal(model.RetentionGovernance, *got.Mode)
+	a.Equal("2030-01-01T00:00:00Z", *got.RetainUntil)
+
+	got, err = s.GetObjectRetention(context.Background(), "test-bucket", "unlocked.txt")
+	a.NoError(err)
+	a.Nil(got.Mode)
+
+	err = s.PutObjectRetention(
+		context.Background(),
+		"test-bucket",
+		"locked.txt",
+		model.ObjectRetention{
+			Mode:        aws.String(model.RetentionGovernance),
+			RetainUntil: aws.String("2030-01-01T00:00:00Z"),
+		},
+		true,
+	)
+	a.NoError(err)
+	a.True(*putParams.BypassGovernanceRetention)
+	a.True(until.Equal(*putParams.Retention.RetainUntilDate))
+
+	err = s.PutObjectRetention(
+		context.Background(),
+		"test-bucket",
+		"locked.txt",
+		model.ObjectRetention{RetainUntil: aws.String("tomorrow")},
+		false,
+	)
+	a.Error(err)
+}
+
+func TestServices_DeleteLockedObjects(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{{Key: aws.String("dir/file1.txt")}},
+			}, nil
+		},
+		deleteObjectsFunc: func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			return &s3.DeleteObjectsOutput{
+				Errors: []types.Error{{
+					Key:     aws.String("dir/file1.txt"),
+					Code:    aws.String("AccessDenied"),
+					Message: aws.String("Access Denied because object protected by object lock."),
+				}},
+			}, nil
+		},
+		deleteObjectFunc: func(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+			return nil, apiErr(
+				http.StatusForbidden, "AccessDenied",
+				"Object is WORM protected and cannot be overwritten",
+			)
+		},
+		deleteBucketFunc: func(ctx context.Context, params *s3.DeleteBucketInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error) {
+			return nil, apiErr(http.StatusConflict, "BucketNotEmpty", "")
+		},
+	}
+	s := New(mock)
+
+	err := s.DeleteObject(context.Background(), "test-bucket", "dir/", model.DeleteObjectOptions{Recursive: true})
+	a.ErrorContains(err, "Conflict")
+	a.ErrorContains(err, ErrObjectLocked.Error())
+
+	mock.listObjectsV2Func = func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+		return &s3.ListObjectsV2Output{}, nil
+	}
+	err = s.DeleteObject(context.Background(), "test-bucket", "file.txt", model.DeleteObjectOptions{})
+	a.ErrorContains(err, ErrObjectLocked.Error())
+
+	mock.getObjectLockConfigurationFunc = func(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+		return &s3.GetObjectLockConfigurationOutput{
+			ObjectLockConfiguration: &types.ObjectLockConfiguration{
+				ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+			},
+		}, nil
+	}
+	err = s.DeleteBucket(context.Background(), "test-bucket", true)
+	a.ErrorContains(err, ErrBucketLocked.Error())
+}
+
+func TestServices_CreateBucketWithOptions(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	opts := model.CreateBucketOptions{
+		Region:          "eu-west-1",
+		ObjectOwnership: "BucketOwnerEnforced",
+		Versioning:      true,
+		Tags:            map[string]string{"team": "data", "env": "prod"},
+		Encryption:      &model.BucketEncryption{Algorithm: "AES256"},
+	}
+	tests := []struct {
+		name         string
+		taggingErr   error
+		rollbackErr  error
+		wantErr      bool
+		wantRollback bool
+	}{
+		{
+			name: "all options applied",
+		},
+		{
+			name:         "rollback on failure",
+			taggingErr:   apiErr(http.StatusForbidden, "AccessDenied", ""),
+			wantErr:      true,
+			wantRollback: true,
+		},
+		{
+			name:         "failed rollback",
+			taggingErr:   apiErr(http.StatusForbidden, "AccessDenied", ""),
+			rollbackErr:  apiErr(http.StatusInternalServerError, "InternalError", ""),
+			wantErr:      true,
+			wantRollback: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var createParams *s3.CreateBucketInput
+			var tagging *s3.PutBucketTaggingInput
+			rolledBack := false
+			mock := &mockS3Client{
+				createBucketFunc: func(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+					createParams = params
+					return &s3.CreateBucketOutput{}, nil
+				},
+				putBucketVersioningFunc: func(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
+					return &s3.PutBucketVersioningOutput{}, nil
+				},
+				putBucketEncryptionFunc: func(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
+					return &s3.PutBucketEncryptionOutput{}, nil
+				},
+				putBucketTaggingFunc: func(ctx context.Context, params *s3.PutBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error) {
+					tagging = params
+					return &s3.PutBucketTaggingOutput{}, tt.taggingErr
+				},
+				deleteBucketFunc: func(ctx context.Context, params *s3.DeleteBucketInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error) {
+					rolledBack = true
+					return &s3.DeleteBucketOutput{}, tt.rollbackErr
+				},
+			}
+			s := New(mock)
+			err := s.CreateBucket(context.Background(), "new-bucket", opts)
+			a.Equal(tt.wantErr, err != nil)
+			a.Equal(tt.wantRollback, rolledBack)
+			a.Equal(
+				types.BucketLocationConstraint("eu-west-1"),
+				createParams.CreateBucketConfiguration.LocationConstraint,
+			)
+			a.Equal(types.ObjectOwnershipBucketOwnerEnforced, createParams.ObjectOwnership)
+			a.Len(tagging.Tagging.TagSet, 2)
+			if tt.rollbackErr != nil {
+				a.ErrorContains(err, "rolling back bucket creation")
+			}
+		})
+	}
+}
+
+func TestServices_BucketStats(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	pages := []*s3.ListObjectsV2Output{
+		{
+			Contents: []types.Object{
+				{Key: aws.String("a.txt"), Size: aws.Int64(10)},
+				{Key: aws.String("b.bin"), Size: aws.Int64(5 << 20), StorageClass: types.ObjectStorageClassGlacier},
+			},
+			NextContinuationToken: aws.String("1"),
+		},
+		{
+			Contents: []types.Object{
+				{Key: aws.String("c.iso"), Size: aws.Int64(20 << 30)},
+			},
+		},
+	}
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if params.ContinuationToken == nil {
+				return pages[0], nil
+			}
+			return pages[1], nil
+		},
+	}
+	s := New(mock)
+
+	var stats *model.BucketStats
+	a.Eventually(func() bool {
+		var err error
+		stats, err = s.BucketStats(context.Background(), "test-bucket", "", false)
+		return err == nil && stats.Status == model.StatsDone
+	}, time.Second, 5*time.Millisecond)
+
+	a.Equal(int64(3), stats.ObjectCount)
+	a.Equal(int64(10+5<<20+20<<30), stats.TotalBytes)
+	a.Equal("c.iso", *stats.Largest[0].Key)
+	a.Equal(int64(1), stats.Histogram[0].Count)
+	a.Equal(int64(1), stats.Histogram[2].Count)
+	a.Equal(int64(1), stats.Histogram[len(stats.Histogram)-1].Count)
+	a.Equal(int64(1), stats.StorageClasses["GLACIER"].Count)
+	a.Equal(int64(2), stats.StorageClasses["STANDARD"].Count)
+
+	// Cached results are served until a refresh is requested.
+	cached, err := s.BucketStats(context.Background(), "test-bucket", "", false)
+	a.NoError(err)
+	a.Equal(model.StatsDone, cached.Status)
+	a.Equal(stats.StartedAt, cached.StartedAt)
+	a.Error(s.CancelBucketStats(context.Background(), "test-bucket", ""))
+}
+
+func TestServices_CancelBucketStats(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	s := New(mock)
+
+	stats, err := s.BucketStats(context.Background(), "test-bucket", "logs/", false)
+	a.NoError(err)
+	a.Equal(model.StatsRunning, stats.Status)
+	a.NoError(s.CancelBucketStats(context.Background(), "test-bucket", "logs/"))
+	a.Eventually(func() bool {
+		stats, err = s.BucketStats(context.Background(), "test-bucket", "logs/", false)
+		return err == nil && stats.Status == model.StatsCancelled
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestServices_BucketStatsLimits(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	s := New(mock)
+
+	// Only so many walks run at once, and closing the services stops them.
+	for i := range maxStatsWalks {
+		_, err := s.BucketStats(context.Background(), "test-bucket", fmt.Sprintf("%d/", i), false)
+		a.NoError(err)
+	}
+	_, err := s.BucketStats(context.Background(), "test-bucket", "more/", false)
+	a.ErrorContains(err, ErrTooManyStatsRuns.Error())
+	s.Close()
+	a.Eventually(func() bool {
+		stats, err := s.BucketStats(context.Background(), "test-bucket", "0/", false)
+		return err == nil && stats.Status == model.StatsCancelled
+	}, time.Second, 5*time.Millisecond)
+
+	// Expired walks are evicted first, then the ones used least recently.
+	tracker := newStatsTracker()
+	now := time.Now()
+	for i := range maxStatsEntries + 1 {
+		tracker.entries[statsKey{prefix: fmt.Sprint(i)}] = &statsEntry{
+			stats: model.BucketStats{Status: model.StatsDone},
+			done:  now,
+			used:  now.Add(time.Duration(i) * time.Second),
+		}
+	}
+	tracker.entries[statsKey{prefix: "expired"}] = &statsEntry{
+		stats: model.BucketStats{Status: model.StatsDone},
+		done:  now.Add(-statsTTL),
+		used:  now.Add(time.Hour),
+	}
+	tracker.entries[statsKey{prefix: "running"}] = &statsEntry{
+		stats: model.BucketStats{Status: model.StatsRunning},
+	}
+	tracker.evict()
+	a.Len(tracker.entries, maxStatsEntries)
+	a.NotContains(tracker.entries, statsKey{prefix: "expired"})
+	a.NotContains(tracker.entries, statsKey{prefix: "0"})
+	a.NotContains(tracker.entries, statsKey{prefix: "1"})
+	a.Contains(tracker.entries, statsKey{prefix: "2"})
+	a.Contains(tracker.entries, statsKey{prefix: "running"})
+}
+
+func TestServices_DiskUsage(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			a.Equal("artifacts/", *params.Prefix)
+			a.Nil(params.Delimiter)
+			if params.ContinuationToken == nil {
+				return &s3.ListObjectsV2Output{
+					Contents: []types.Object{
+						{Key: aws.String("artifacts/"), Size: aws.Int64(0)},
+						{Key: aws.String("artifacts/builds/a.zip"), Size: aws.Int64(100)},
+						{Key: aws.String("artifacts/builds/nested/b.zip"), Size: aws.Int64(200)},
+					},
+					NextContinuationToken: aws.String("1"),
+				}, nil
+			}
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String("artifacts/docs/index.html"), Size: aws.Int64(50)},
+					{Key: aws.String("artifacts/README.md"), Size: aws.Int64(7)},
+				},
+			}, nil
+		},
+	}
+	s := New(mock)
+
+	got, truncated, err := s.DiskUsage(context.Background(), "test-bucket", "artifacts")
+	a.NoError(err)
+	a.False(truncated)
+	a.Len(got, 3)
+	a.Equal("builds", *got[0].Key)
+	a.True(got[0].IsDir)
+	a.Equal(int64(300), *got[0].Size)
+	a.Equal(int64(2), *got[0].ObjectCount)
+	a.Equal("docs", *got[1].Key)
+	a.Equal(int64(50), *got[1].Size)
+	a.Equal("README.md", *got[2].Key)
+	a.False(got[2].IsDir)
+
+	// The trash kept in the bucket is neither listed nor counted.
+	mock = &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String(".trash/test-bucket/20240101T000000.000000000Z/a.zip"), Size: aws.Int64(100)},
+					{Key: aws.String("README.md"), Size: aws.Int64(7)},
+				},
+			}, nil
+		},
+	}
+	s = New(mock, WithTrash("", "", 0))
+	defer s.Close()
+	got, _, err = s.DiskUsage(context.Background(), "test-bucket", "")
+	a.NoError(err)
+	a.Len(got, 1)
+	a.Equal("README.md", *got[0].Key)
+
+	// A walk that reads too many objects stops and is reported as truncated.
+	pages := 0
+	mock = &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			pages++
+			contents := make([]types.Object, 1000)
+			for i := range contents {
+				key := fmt.Sprintf("logs/%d-%d.log", pages, i)
+				contents[i] = types.Object{Key: aws.String(key), Size: aws.Int64(1)}
+			}
+			return &s3.ListObjectsV2Output{
+				Contents:              contents,
+				NextContinuationToken: aws.String(fmt.Sprint(pages)),
+			}, nil
+		},
+	}
+	s = New(mock)
+	got, truncated, err = s.DiskUsage(context.Background(), "test-bucket", "")
+	a.NoError(err)
+	a.True(truncated)
+	a.Equal(maxDiskUsageObjects/1000, pages)
+	a.Len(got, 1)
+	a.Equal(int64(maxDiskUsageObjects), *got[0].ObjectCount)
+}
+
+func TestServices_SearchObjects(t *testing.T) {
+	t.Parallel()
+	modified := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	objects := []types.Object{
+		{Key: aws.String("data/2024/a.parquet"), Size: aws.Int64(100), LastModified: &modified},
+		{Key: aws.String("data/2024/b.csv"), Size: aws.Int64(50), LastModified: &modified},
+		{Key: aws.String("data/c.parquet"), Size: aws.Int64(5), LastModified: &modified},
+		{Key: aws.String("data/Report.PARQUET.bak"), Size: aws.Int64(500), LastModified: &modified},
+	}
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if params.ContinuationToken == nil {
+				return &s3.ListObjectsV2Output{
+					Contents: objects[:2], NextContinuationToken: aws.String("1"),
+				}, nil
+			}
+			return &s3.ListObjectsV2Output{Contents: objects[2:]}, nil
+		},
+	}
+	s := New(mock)
+
+	tests := []struct {
+		name      string
+		opts      model.SearchOptions
+		want      []string
+		truncated bool
+		wantErr   bool
+	}{
+		{
+			name: "substring is case insensitive",
+			opts: model.SearchOptions{Query: "parquet"},
+			want: []string{"data/2024/a.parquet", "data/c.parquet", "data/Report.PARQUET.bak"},
+		},
+		{
+			name: "glob matches base name in any folder",
+			opts: model.SearchOptions{Query: "*.parquet", Mode: model.SearchGlob},
+			want: []string{"data/2024/a.parquet", "data/c.parquet"},
+		},
+		{
+			name: "glob with slash matches relative key",
+			opts: model.SearchOptions{Prefix: "data/", Query: "*/*.csv", Mode: model.SearchGlob},
+			want: []string{"data/2024/b.csv"},
+		},
+		{
+			name: "regex with size range",
+			opts: model.SearchOptions{
+				Query: `\.parquet$`, Mode: model.SearchRegex,
+				MinSize: aws.Int64(10), MaxSize: aws.Int64(1000),
+			},
+			want: []string{"data/2024/a.parquet"},
+		},
+		{
+			name: "modified before excludes everything",
+			opts: model.SearchOptions{
+				Query:          "data",
+				ModifiedBefore: aws.Time(modified.Add(-time.Hour)),
+			},
+		},
+		{
+			name:      "limit truncates",
+			opts:      model.SearchOptions{Query: "data", Limit: 2},
+			want:      []string{"data/2024/a.parquet", "data/2024/b.csv"},
+			truncated: true,
+		},
+		{
+			name:    "invalid regex",
+			opts:    model.SearchOptions{Query: "(", Mode: model.SearchRegex},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+			var got []string
+			result, err := s.SearchObjects(
+				context.Background(), "test-bucket", tt.opts,
+				func(obj model.Object) error {
+					got = append(got, *obj.Key)
+					return nil
+				},
+			)
+			if tt.wantErr {
+				a.Error(err)
+				return
+			}
+			a.NoError(err)
+			a.Equal(tt.want, got)
+			a.Equal(tt.truncated, result.Truncated)
+		})
+	}
+}
+
+func TestServices_DeleteObjects(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	keys := make([]string, 2500)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("file-%04d", i)
+	}
+	var mu sync.Mutex
+	var batchSizes []int
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if *params.Prefix == "broken/" {
+				return nil, apiErr(http.StatusForbidden, "AccessDenied", "")
+			}
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String("logs/1.log")},
+					// Already part of the keys, so only deleted once.
+					{Key: aws.String("file-0000")},
+				},
+			}, nil
+		},
+		deleteObjectsFunc: func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			mu.Lock()
+			batchSizes = append(batchSizes, len(params.Delete.Objects))
+			mu.Unlock()
+			if *params.Delete.Objects[0].Key == "file-1000" {
+				return nil, apiErr(http.StatusInternalServerError, "InternalError", "")
+			}
+			out := &s3.DeleteObjectsOutput{}
+			for _, obj := range params.Delete.Objects {
+				if *obj.Key == "file-0007" {
+					out.Errors = append(out.Errors, types.Error{
+						Key: obj.Key, Message: aws.String("Access Denied"),
+					})
+				}
+			}
+			return out, nil
+		},
+	}
+	s := New(mock)
+
+	results, err := s.DeleteObjects(
+		context.Background(), "test-bucket", keys, []string{"logs/", "broken/"},
+		model.DeleteObjectsOptions{},
+	)
+	a.NoError(err)
+	a.ElementsMatch([]int{1000, 1000, 501}, batchSizes)
+	a.Len(results, 2502)
+
+	failed := make(map[string]bool)
+	for _, r := range results {
+		if !r.Deleted {
+			a.NotNil(r.Error)
+			failed[r.Key] = true
+		}
+	}
+	a.True(failed["broken/"])
+	a.True(failed["file-0007"])
+	a.True(failed["file-1000"])
+	a.True(failed["file-1999"])
+	a.False(failed["file-2000"])
+	a.False(failed["logs/1.log"])
+	a.Len(failed, 1002)
+}
+
+// waitForJob polls the job until it has finished.
+func waitForJob(t *testing.T, s *Services, id string) *model.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := s.GetJob(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.FinishedAt != nil {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func TestServices_DeleteJob(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String("logs/1.log"), Size: aws.Int64(10)},
+					{Key: aws.String("logs/2.log"), Size: aws.Int64(20)},
+				},
+			}, nil
+		},
+		deleteObjectsFunc: func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			return &s3.DeleteObjectsOutput{
+				Errors: []types.Error{{Key: aws.String("logs/2.log"), Message: aws.String("Access Denied")}},
+			}, nil
+		},
+	}
+	s := New(mock)
+	defer s.Close()
+
+	spec := model.JobSpec{
+		Type: model.JobDelete, Bucket: "test-bucket", Prefixes: []string{"logs/"},
+	}
+	_, err := s.SubmitJob(context.Background(), spec)
+	a.Error(err)
+	preview, err := s.PreviewDelete(context.Background(), "test-bucket", spec.Prefixes)
+	a.NoError(err)
+	spec.Confirm = preview.Token
+
+	job, err := s.SubmitJob(context.Background(), spec)
+	a.NoError(err)
+	a.Equal(model.JobQueued, job.Status)
+	a.Empty(job.Spec.Confirm)
+
+	job = waitForJob(t, s, job.ID)
+	// The rest is still deleted, but the job didn't finish what it was asked.
+	a.Equal(model.JobFailed, job.Status)
+	a.Equal("1 object(s) could not be deleted", aws.ToString(job.Error))
+	a.Equal(int64(2), *job.Progress.ObjectsTotal)
+	a.Equal(int64(1), job.Progress.ObjectsDone)
+	a.Equal(int64(10), job.Progress.BytesDone)
+	a.Equal(int64(1), job.Progress.ObjectsFailed)
+	a.Equal("logs/2.log", job.Failures[0].Key)
+
+	jobs, err := s.ListJobs(context.Background())
+	a.NoError(err)
+	a.Len(jobs, 1)
+
+	a.Error(s.CancelJob(context.Background(), job.ID))
+	_, err = s.GetJob(context.Background(), "missing")
+	a.Error(err)
+}
+
+func TestServices_PreviewDelete(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			var contents []types.Object
+			for i := range 30 {
+				contents = append(contents, types.Object{
+					Key:  aws.String(fmt.Sprintf("%s%02d", *params.Prefix, i)),
+					Size: aws.Int64(2),
+				})
+			}
+			return &s3.ListObjectsV2Output{Contents: contents}, nil
+		},
+	}
+	s := New(mock)
+	defer s.Close()
+
+	preview, err := s.PreviewDelete(context.Background(), "test-bucket", []string{"a/", "b/"})
+	a.NoError(err)
+	a.Equal(int64(60), preview.Objects)
+	a.Equal(int64(120), preview.Bytes)
+	a.Len(preview.Sample, deletePreviewSample)
+	a.NotEmpty(preview.ExpiresAt)
+
+	// The order of the prefixes doesn't matter, but the target does.
+	a.NoError(s.ConfirmDelete("test-bucket", []string{"b/", "a/"}, preview.Token))
+	a.Error(s.ConfirmDelete("test-bucket", []string{"a/"}, preview.Token))
+	a.Error(s.ConfirmDelete("other-bucket", []string{"a/", "b/"}, preview.Token))
+	a.Error(s.ConfirmDelete("test-bucket", []string{"a/", "b/"}, preview.Token+"x"))
+	a.Error(s.ConfirmDelete("test-bucket", []string{"a/", "b/"}, ""))
+	// Tokens from another instance aren't accepted.
+	a.Error(New(mock).ConfirmDelete("test-bucket", []string{"a/", "b/"}, preview.Token))
+
+	expired := s.deleteToken("test-bucket", []string{""}, time.Now().Add(-time.Second))
+	err = s.ConfirmDelete("test-bucket", []string{""}, expired)
+	a.ErrorContains(err, ErrConfirmationInvalid.Error())
+}
+
+func TestServices_MoveJob(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var mu sync.Mutex
+	copies := make(map[string]string)
+	var deleted []string
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			a.Equal("docs/", *params.Prefix)
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String("docs/a b.txt"), Size: aws.Int64(5)},
+					{Key: aws.String("docs/sub/c.txt"), Size: aws.Int64(7)},
+					{Key: aws.String("docs/huge.iso"), Size: aws.Int64(6 << 30)},
+				},
+			}, nil
+		},
+		copyObjectFunc: func(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+			a.Equal("archive", *params.Bucket)
+			a.Equal(types.StorageClassGlacier, params.StorageClass)
+			mu.Lock()
+			copies[*params.Key] = *params.CopySource
+			mu.Unlock()
+			return &s3.CopyObjectOutput{}, nil
+		},
+		deleteObjectsFunc: func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, obj := range params.Delete.Objects {
+				deleted = append(deleted, *obj.Key)
+			}
+			return &s3.DeleteObjectsOutput{}, nil
+		},
+	}
+	s := New(mock, WithJobWorkers(1))
+	defer s.Close()
+
+	job, err := s.SubmitJob(context.Background(), model.JobSpec{
+		Type:         model.JobMove,
+		Bucket:       "test-bucket",
+		Prefix:       "docs/",
+		DestBucket:   "archive",
+		DestPrefix:   "2024/docs/",
+		StorageClass: model.StorageGlacier,
+	})
+	a.NoError(err)
+	job = waitForJob(t, s, job.ID)
+
+	a.Equal(model.JobFailed, job.Status)
+	a.Equal("1 object(s) could not be moved", aws.ToString(job.Error))
+	a.Equal(map[string]string{
+		"2024/docs/a b.txt":   "test-bucket/docs/a%20b.txt",
+		"2024/docs/sub/c.txt": "test-bucket/docs/sub/c.txt",
+	}, copies)
+	// The object that couldn't be copied must stay in place.
+	a.ElementsMatch([]string{"docs/a b.txt", "docs/sub/c.txt"}, deleted)
+	a.Equal(int64(2), job.Progress.ObjectsDone)
+	a.Equal(int64(12), job.Progress.BytesDone)
+	a.Equal(int64(1), job.Progress.ObjectsFailed)
+	a.Equal("docs/huge.iso", job.Failures[0].Key)
+
+	job, err = s.SubmitJob(context.Background(), model.JobSpec{
+		Type:         model.JobCopy,
+		Bucket:       "test-bucket",
+		Prefix:       "docs/",
+		DestBucket:   "archive",
+		StorageClass: model.StorageGlacier,
+	})
+	a.NoError(err)
+	job = waitForJob(t, s, job.ID)
+	a.Equal(model.JobFailed, job.Status)
+	a.Equal("1 object(s) could not be copied", aws.ToString(job.Error))
+	a.Equal(int64(2), job.Progress.ObjectsDone)
+}
+
+func TestServices_CancelJob(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	started := make(chan struct{})
+	var once sync.Once
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			once.Do(func() { close(started) })
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	s := New(mock, WithJobWorkers(1))
+	defer s.Close()
+	ctx := context.Background()
+
+	expires := time.Now().Add(time.Minute)
+	running, err := s.SubmitJob(ctx, model.JobSpec{
+		Type:    model.JobDeleteBucket,
+		Bucket:  "test-bucket",
+		Confirm: s.deleteToken("test-bucket", []string{""}, expires),
+	})
+	a.NoError(err)
+	<-started
+	queued, err := s.SubmitJob(ctx, model.JobSpec{
+		Type:    model.JobDeleteBucket,
+		Bucket:  "other-bucket",
+		Confirm: s.deleteToken("other-bucket", []string{""}, expires),
+	})
+	a.NoError(err)
+
+	// The single worker is busy, so the second job is still waiting.
+	a.NoError(s.CancelJob(ctx, queued.ID))
+	job, err := s.GetJob(ctx, queued.ID)
+	a.NoError(err)
+	a.Equal(model.JobCancelled, job.Status)
+
+	a.NoError(s.CancelJob(ctx, running.ID))
+	job = waitForJob(t, s, running.ID)
+	a.Equal(model.JobCancelled, job.Status)
+
+	// Cancelled jobs no longer take up room in the queue.
+	submit := func() (*model.Job, error) {
+		return s.SubmitJob(ctx, model.JobSpec{
+			Type:    model.JobDeleteBucket,
+			Bucket:  "other-bucket",
+			Confirm: s.deleteToken("other-bucket", []string{""}, expires),
+		})
+	}
+	blocker, err := submit()
+	a.NoError(err)
+	a.Eventually(func() bool {
+		job, err := s.GetJob(ctx, blocker.ID)
+		return err == nil && job.Status == model.JobRunning
+	}, time.Second, time.Millisecond)
+	ids := make([]string, 0, jobQueueSize)
+	for range jobQueueSize {
+		job, err := submit()
+		a.NoError(err)
+		ids = append(ids, job.ID)
+	}
+	_, err = submit()
+	a.ErrorContains(err, ErrJobQueueFull.Error())
+	for _, id := range ids {
+		a.NoError(s.CancelJob(ctx, id))
+	}
+	_, err = submit()
+	a.NoError(err)
+}
+
+func TestServices_Trash(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	type stored struct {
+		size        int64
+		contentType *string
+		metadata    map[string]string
+	}
+	var mu sync.Mutex
+	store := map[string]stored{
+		"test-bucket/docs/report.pdf": {
+			size:        42,
+			contentType: aws.String("application/pdf"),
+			metadata:    map[string]string{"owner": "finance"},
+		},
+	}
+	mock := &mockS3Client{
+		headObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			obj, ok := store[*params.Bucket+"/"+*params.Key]
+			if !ok {
+				return nil, &types.NotFound{}
+			}
+			return &s3.HeadObjectOutput{
+				ContentLength: aws.Int64(obj.size),
+				ContentType:   obj.contentType,
+				Metadata:      obj.metadata,
+			}, nil
+		},
+		copyObjectFunc: func(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			a.Equal(types.MetadataDirectiveReplace, params.MetadataDirective)
+			src, err := url.PathUnescape(*params.CopySource)
+			a.NoError(err)
+			store[*params.Bucket+"/"+*params.Key] = stored{
+				size:        store[src].size,
+				contentType: params.ContentType,
+				metadata:    params.Metadata,
+			}
+			return &s3.CopyObjectOutput{}, nil
+		},
+		deleteObjectFunc: func(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(store, *params.Bucket+"/"+*params.Key)
+			return &s3.DeleteObjectOutput{}, nil
+		},
+		deleteObjectsFunc: func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, obj := range params.Delete.Objects {
+				delete(store, *params.Bucket+"/"+*obj.Key)
+			}
+			return &s3.DeleteObjectsOutput{}, nil
+		},
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			out := &s3.ListObjectsV2Output{}
+			for _, name := range slices.Sorted(maps.Keys(store)) {
+				bucket, key, _ := strings.Cut(name, "/")
+				if bucket != *params.Bucket || !strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+					continue
+				}
+				if params.Delimiter != nil {
+					rest := strings.TrimPrefix(key, aws.ToString(params.Prefix))
+					if dir, _, ok := strings.Cut(rest, "/"); ok {
+						out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{
+							Prefix: aws.String(aws.ToString(params.Prefix) + dir + "/"),
+						})
+						continue
+					}
+				}
+				out.Contents = append(out.Contents, types.Object{
+					Key: aws.String(key), Size: aws.Int64(store[name].size),
+				})
+			}
+			return out, nil
+		},
+	}
+	s := New(mock, WithTrash("", "", 0))
+	defer s.Close()
+	ctx := context.Background()
+
+	err := s.DeleteObject(ctx, "test-bucket", "docs/report.pdf", model.DeleteObjectOptions{DeletedBy: "alice"})
+	a.NoError(err)
+
+	// The trash is kept out of the bucket's listings.
+	list, _, err := s.ListObjects(ctx, "test-bucket", 100, model.ListObjectsOption{})
+	a.NoError(err)
+	a.Empty(list)
+
+	items, _, err := s.ListTrash(ctx, "test-bucket", 100, nil)
+	a.NoError(err)
+	a.Len(items, 1)
+	a.Equal("docs/report.pdf", items[0].Key)
+	a.Equal(int64(42), items[0].Size)
+	a.Equal("alice", aws.ToString(items[0].DeletedBy))
+	a.Nil(items[0].PurgeAt)
+
+	// A new object at the original path isn't replaced.
+	store["test-bucket/docs/report.pdf"] = stored{size: 1}
+	err = s.RestoreTrash(ctx, "test-bucket", items[0].ID)
+	a.ErrorContains(err, ErrRestoreExists.Error())
+	delete(store, "test-bucket/docs/report.pdf")
+
+	a.NoError(s.RestoreTrash(ctx, "test-bucket", items[0].ID))
+	restored := store["test-bucket/docs/report.pdf"]
+	a.Equal(int64(42), restored.size)
+	a.Equal("application/pdf", aws.ToString(restored.contentType))
+	a.Equal(map[string]string{"owner": "finance"}, restored.metadata)
+	items, _, err = s.ListTrash(ctx, "test-bucket", 100, nil)
+	a.NoError(err)
+	a.Empty(items)
+
+	// Permanent deletes skip the trash.
+	err = s.DeleteObject(ctx, "test-bucket", "docs/report.pdf", model.DeleteObjectOptions{Permanent: true})
+	a.NoError(err)
+	a.Empty(store)
+
+	// So do bulk deletes.
+	store["test-bucket/a.txt"] = stored{size: 1}
+	store["test-bucket/b.txt"] = stored{size: 2}
+	results, err := s.DeleteObjects(
+		ctx, "test-bucket", []string{"a.txt", "b.txt", "a.txt"}, nil,
+		model.DeleteObjectsOptions{DeletedBy: "bob"},
+	)
+	a.NoError(err)
+	a.Len(results, 2)
+	for _, r := range results {
+		a.True(r.Deleted, r.Key)
+	}
+	items, _, err = s.ListTrash(ctx, "test-bucket", 100, nil)
+	a.NoError(err)
+	a.Len(items, 2)
+	a.Equal("bob", aws.ToString(items[0].DeletedBy))
+
+	store["test-bucket/c.txt"] = stored{size: 3}
+	_, err = s.DeleteObjects(
+		ctx, "test-bucket", []string{"c.txt"}, nil,
+		model.DeleteObjectsOptions{Permanent: true},
+	)
+	a.NoError(err)
+	a.NotContains(store, "test-bucket/c.txt")
+	items, _, err = s.ListTrash(ctx, "test-bucket", 100, nil)
+	a.NoError(err)
+	a.Len(items, 2)
+
+	// Recursive deletes, of a folder, of the prefixes of a bulk delete or in
+	// a job, move everything under them.
+	store["test-bucket/logs/1.log"] = stored{size: 1}
+	store["test-bucket/logs/2.log"] = stored{size: 2}
+	err = s.DeleteObject(ctx, "test-bucket", "logs/", model.DeleteObjectOptions{Recursive: true, DeletedBy: "carol"})
+	a.NoError(err)
+	store["test-bucket/tmp/3.tmp"] = stored{size: 3}
+	results, err = s.DeleteObjects(
+		ctx, "test-bucket", nil, []string{"tmp/"}, model.DeleteObjectsOptions{},
+	)
+	a.NoError(err)
+	a.Equal([]model.DeleteResult{{Key: "tmp/3.tmp", Deleted: true}}, results)
+	store["test-bucket/old/4.bak"] = stored{size: 4}
+	preview, err := s.PreviewDelete(ctx, "test-bucket", []string{"old/"})
+	a.NoError(err)
+	job, err := s.SubmitJob(ctx, model.JobSpec{
+		Type: model.JobDelete, Bucket: "test-bucket", Prefixes: []string{"old/"},
+		Confirm: preview.Token, DeletedBy: "dave",
+	})
+	a.NoError(err)
+	job = waitForJob(t, s, job.ID)
+	a.Equal(model.JobDone, job.Status)
+	a.Equal(int64(1), job.Progress.ObjectsDone)
+	a.Equal(int64(4), job.Progress.BytesDone)
+	items, _, err = s.ListTrash(ctx, "test-bucket", 100, nil)
+	a.NoError(err)
+	var trashed []string
+	for _, item := range items {
+		trashed = append(trashed, item.Key)
+	}
+	a.ElementsMatch([]string{"a.txt", "b.txt", "logs/1.log", "logs/2.log", "tmp/3.tmp", "old/4.bak"}, trashed)
+
+	// Unless they're permanent.
+	store["test-bucket/cache/5.bin"] = stored{size: 5}
+	err = s.DeleteObject(ctx, "test-bucket", "cache/", model.DeleteObjectOptions{Recursive: true, Permanent: true})
+	a.NoError(err)
+	a.NotContains(store, "test-bucket/cache/5.bin")
+	items, _, err = s.ListTrash(ctx, "test-bucket", 100, nil)
+	a.NoError(err)
+	a.Len(items, 6)
+
+	a.Error(s.RestoreTrash(ctx, "test-bucket", "../docs/report.pdf"))
+	_, _, err = New(mock).ListTrash(ctx, "test-bucket", 100, nil)
+	a.ErrorContains(err, ErrTrashDisabled.Error())
+}
+
+func TestServices_PurgeExpiredTrash(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	old := time.Now().Add(-48 * time.Hour).UTC().Format(trashStampLayout)
+	recent := time.Now().UTC().Format(trashStampLayout)
+	var deleted []string
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			a.Equal("trash", *params.Bucket)
+			a.Equal("deleted/", *params.Prefix)
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String("deleted/photos/" + old + "/a.jpg")},
+					{Key: aws.String("deleted/photos/" + recent + "/b.jpg")},
+					{Key: aws.String("deleted/photos/stray.txt")},
+				},
+			}, nil
+		},
+		deleteObjectsFunc: func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			for _, obj := range params.Delete.Objects {
+				deleted = append(deleted, *obj.Key)
+			}
+			return &s3.DeleteObjectsOutput{}, nil
+		},
+	}
+	s := &Services{s3Client: mock, trash: &trash{
+		bucket: "trash", prefix: "deleted/", retention: 24 * time.Hour,
+	}}
+
+	a.NoError(s.purgeExpiredTrash(context.Background()))
+	a.Equal([]string{"deleted/photos/" + old + "/a.jpg"}, deleted)
+}
+
+func TestServices_SyncJob(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var mu sync.Mutex
+	var copied, deleted []string
+	var failCopies atomic.Bool
+	mock := &mockS3Client{
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if *params.Bucket == "test-bucket" {
+				a.Equal("site/", *params.Prefix)
+				return &s3.ListObjectsV2Output{Contents: []types.Object{
+					{Key: aws.String("site/index.html"), Size: aws.Int64(10), ETag: aws.String(`"a"`)},
+					{Key: aws.String("site/style.css"), Size: aws.Int64(20), ETag: aws.String(`"b"`)},
+					{Key: aws.String("site/new.js"), Size: aws.Int64(30), ETag: aws.String(`"c"`)},
+				}}, nil
+			}
+			a.Equal("backup", *params.Bucket)
+			a.Equal("www/", *params.Prefix)
+			return &s3.ListObjectsV2Output{Contents: []types.Object{
+				{Key: aws.String("www/index.html"), Size: aws.Int64(10), ETag: aws.String(`"a"`)},
+				{Key: aws.String("www/style.css"), Size: aws.Int64(20), ETag: aws.String(`"old"`)},
+				{Key: aws.String("www/gone.png"), Size: aws.Int64(40), ETag: aws.String(`"d"`)},
+			}}, nil
+		},
+		copyObjectFunc: func(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+			a.Equal("backup", *params.Bucket)
+			if failCopies.Load() && *params.Key == "www/new.js" {
+				return nil, apiErr(http.StatusForbidden, "AccessDenied", "denied")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			copied = append(copied, *params.Key)
+			return &s3.CopyObjectOutput{}, nil
+		},
+		deleteObjectsFunc: func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			a.Equal("backup", *params.Bucket)
+			mu.Lock()
+			defer mu.Unlock()
+			for _, obj := range params.Delete.Objects {
+				deleted = append(deleted, *obj.Key)
+			}
+			return &s3.DeleteObjectsOutput{}, nil
+		},
+	}
+	s := New(mock)
+	defer s.Close()
+	spec := model.JobSpec{
+		Type:       model.JobSync,
+		Bucket:     "test-bucket",
+		Prefix:     "site/",
+		DestBucket: "backup",
+		DestPrefix: "www/",
+	}
+
+	diff, err := s.SyncDiff(context.Background(), spec)
+	a.NoError(err)
+	a.Equal([]model.SyncEntry{{Key: "new.js", Size: 30}}, diff.New.Entries)
+	a.Equal([]model.SyncEntry{{Key: "style.css", Size: 20}}, diff.Changed.Entries)
+	a.Equal(int64(0), diff.Deleted.Count)
+	a.Equal(int64(1), diff.Unchanged)
+
+	a.Empty(diff.Token)
+	// Prefixes are folders, "site" doesn't match "site-old/".
+	bare := spec
+	bare.Prefix, bare.DestPrefix = "site", "www"
+	bareDiff, err := s.SyncDiff(context.Background(), bare)
+	a.NoError(err)
+	a.Equal(diff, bareDiff)
+
+	spec.Delete = true
+	diff, err = s.SyncDiff(context.Background(), spec)
+	a.NoError(err)
+	a.Equal([]model.SyncEntry{{Key: "gone.png", Size: 40}}, diff.Deleted.Entries)
+
+	// Deleting needs the token of the same sync's diff.
+	_, err = s.SubmitJob(context.Background(), spec)
+	a.ErrorContains(err, ErrConfirmationRequired.Error())
+	preview, err := s.PreviewDelete(context.Background(), "backup", []string{"www/"})
+	a.NoError(err)
+	other := spec
+	other.Confirm = preview.Token
+	_, err = s.SubmitJob(context.Background(), other)
+	a.ErrorContains(err, ErrConfirmationInvalid.Error())
+	other.Confirm, other.DestPrefix = diff.Token, "site-old/"
+	_, err = s.SubmitJob(context.Background(), other)
+	a.ErrorContains(err, ErrConfirmationInvalid.Error())
+
+	spec.Confirm = diff.Token
+	job, err := s.SubmitJob(context.Background(), spec)
+	a.NoError(err)
+	job = waitForJob(t, s, job.ID)
+	a.Equal(model.JobDone, job.Status)
+	a.Equal(int64(3), *job.Progress.ObjectsTotal)
+	a.Equal(int64(3), job.Progress.ObjectsDone)
+	a.Equal(int64(90), job.Progress.BytesDone)
+	a.ElementsMatch([]string{"www/new.js", "www/style.css"}, copied)
+	a.Equal([]string{"www/gone.png"}, deleted)
+
+	// A sync that couldn't copy everything isn't done.
+	failCopies.Store(true)
+	job, err = s.SubmitJob(context.Background(), spec)
+	a.NoError(err)
+	job = waitForJob(t, s, job.ID)
+	a.Equal(model.JobFailed, job.Status)
+	a.Equal(int64(1), job.Progress.ObjectsFailed)
+	a.Contains(*job.Error, "1 object(s) could not be synced")
+}
+
+func TestServices_FetchJob(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("release notes"))
+		case "/release.tar.gz":
+			_, _ = w.Write([]byte(strings.Repeat("x", 40)))
+		case "/huge.bin":
+			// Flushing drops the Content-Length, the limit applies while reading.
+			for range 3 {
+				_, _ = w.Write([]byte(strings.Repeat("x", 50)))
+				w.(http.Flusher).Flush()
+			}
+		case "/redirect":
+			_, port, _ := net.SplitHostPort(r.Host)
+			http.Redirect(w, r, "http://localhost:"+port+"/notes.txt", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	put := map[string]string{}
+	classes := map[string]types.StorageClass{}
+	var parts []string
+	var aborted bool
+	mock := &mockS3Client{
+		putObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			body, err := io.ReadAll(params.Body)
+			a.NoError(err)
+			mu.Lock()
+			defer mu.Unlock()
+			put[*params.Key] = *params.ContentType + ":" + string(body)
+			classes[*params.Key] = params.StorageClass
+			return &s3.PutObjectOutput{}, nil
+		},
+		createMultipartUploadFunc: func(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			classes[*params.Key] = params.StorageClass
+			return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+		},
+		uploadPartFunc: func(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+			body, err := io.ReadAll(params.Body)
+			a.NoError(err)
+			mu.Lock()
+			defer mu.Unlock()
+			parts = append(parts, fmt.Sprintf("%s#%d:%d", *params.Key, *params.PartNumber, len(body)))
+			return &s3.UploadPartOutput{ETag: aws.String("etag")}, nil
+		},
+		completeMultipartUploadFunc: func(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+			a.Len(params.MultipartUpload.Parts, 3)
+			return &s3.CompleteMultipartUploadOutput{}, nil
+		},
+		abortMultipartUploadFunc: func(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			aborted = true
+			return &s3.AbortMultipartUploadOutput{}, nil
+		},
+	}
+
+	_, err := New(mock).SubmitJob(context.Background(), model.JobSpec{
+		Type: model.JobFetch, Bucket: "releases", URL: srv.URL + "/notes.txt", Key: "notes.txt",
+	})
+	a.ErrorContains(err, ErrFetchDisabled.Error())
+
+	srvHost := strings.TrimPrefix(srv.URL, "http://")
+	_, srvPort, _ := net.SplitHostPort(srvHost)
+	s := New(mock, WithFetch([]string{srvHost, "*.example.com"}, 100))
+	defer s.Close()
+	s.fetcher.partSize = 16
+	fetch := func(path, key string) *model.Job {
+		job, err := s.SubmitJob(context.Background(), model.JobSpec{
+			Type: model.JobFetch, Bucket: "releases", URL: srv.URL + path, Key: key,
+			StorageClass: model.StorageGlacier,
+		})
+		a.NoError(err)
+		return waitForJob(t, s, job.ID)
+	}
+
+	job := fetch("/notes.txt", "v1/notes.txt")
+	a.Equal(model.JobDone, job.Status)
+	a.Equal(int64(13), job.Progress.BytesDone)
+	a.Equal(int64(13), *job.Progress.BytesTotal)
+	a.Equal("text/plain:release notes", put["v1/notes.txt"])
+
+	job = fetch("/release.tar.gz", "v1/release.tar.gz")
+	a.Equal(model.JobDone, job.Status)
+	a.Equal([]string{
+		"v1/release.tar.gz#1:16", "v1/release.tar.gz#2:16", "v1/release.tar.gz#3:8",
+	}, parts)
+	// Both ways of putting the file keep the requested class.
+	a.Equal(types.StorageClassGlacier, classes["v1/notes.txt"])
+	a.Equal(types.StorageClassGlacier, classes["v1/release.tar.gz"])
+
+	job = fetch("/huge.bin", "v1/huge.bin")
+	a.Equal(model.JobFailed, job.Status)
+	a.Contains(*job.Error, ErrFetchTooLarge.Error())
+	a.True(aborted)
+
+	job = fetch("/redirect", "v1/redirected.txt")
+	a.Equal(model.JobFailed, job.Status)
+	a.Contains(*job.Error, ErrHostNotAllowed.Error())
+
+	job = fetch("/missing", "v1/missing")
+	a.Equal(model.JobFailed, job.Status)
+
+	_, err = s.SubmitJob(context.Background(), model.JobSpec{
+		Type: model.JobFetch, Bucket: "releases", URL: "http://169.254.169.254/latest", Key: "meta",
+	})
+	a.ErrorContains(err, ErrHostNotAllowed.Error())
+	// Hosts listed without a port only allow the default ones, and those
+	// with a port only allow that port.
+	for rawURL, allowed := range map[string]bool{
+		"https://cdn.example.com/a":          true,
+		"http://cdn.example.com:80/a":        true,
+		"https://cdn.example.com:443/a":      true,
+		"http://cdn.example.com:443/a":       false,
+		"http://cdn.example.com:6379/a":      false,
+		"http://127.0.0.1/a":                 false,
+		srv.URL + "/a":                       true,
+		"http://localhost:" + srvPort + "/a": false,
+	} {
+		err := s.checkFetch(rawURL)
+		if allowed {
+			a.NoError(err, rawURL)
+		} else {
+			a.ErrorContains(err, ErrHostNotAllowed.Error(), rawURL)
+		}
+	}
+	_, err = s.SubmitJob(context.Background(), model.JobSpec{
+		Type: model.JobFetch, Bucket: "releases", URL: "file:///etc/passwd", Key: "passwd",
+	})
+	a.ErrorContains(err, ErrFetchBadScheme.Error())
+}
+
+func TestServices_EditObjectContent(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	content := map[string]string{
+		"app.json": `{"debug":false}`,
+		"logo.png": "\x89PNG\r\n\x1a\n\xff\xfe",
+		"big.txt":  strings.Repeat("a", 64),
+	}
+	etag := `"v1"`
+	var putErr error
+	var put *s3.PutObjectInput
+	mock := &mockS3Client{
+		getObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			c := content[*params.Key]
+			return &s3.GetObjectOutput{
+				Body:          io.NopCloser(strings.NewReader(c)),
+				ContentLength: aws.Int64(int64(len(c))),
+				ContentType:   aws.String("application/json"),
+				ETag:          aws.String(etag),
+			}, nil
+		},
+		headObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			if *params.Key == "gone.json" {
+				return nil, apiErr(http.StatusNotFound, "NotFound", "")
+			}
+			return &s3.HeadObjectOutput{
+				ETag:               aws.String(etag),
+				Metadata:           map[string]string{"owner": "ops"},
+				StorageClass:       types.StorageClassStandardIa,
+				CacheControl:       aws.String("no-cache"),
+				ContentDisposition: aws.String("inline"),
+				ContentEncoding:    aws.String("identity"),
+			}, nil
+		},
+		putObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			put = params
+			return &s3.PutObjectOutput{ETag: aws.String(`"v2"`)}, putErr
+		},
+	}
+	s := New(mock)
+	ctx := context.Background()
+
+	got, err := s.GetObjectContent(ctx, "test-bucket", "app.json", 32)
+	a.NoError(err)
+	a.Equal(`{"debug":false}`, got.Content)
+	a.Equal(`"v1"`, got.ETag)
+	_, err = s.GetObjectContent(ctx, "test-bucket", "logo.png", 32)
+	a.ErrorContains(err, ErrNotText.Error())
+	_, err = s.GetObjectContent(ctx, "test-bucket", "big.txt", 32)
+	a.ErrorContains(err, ErrContentTooLarge.Error())
+
+	obj, err := s.UpdateObject(ctx, "test-bucket", "app.json", "application/json", `"v1"`, strings.NewReader(`{}`))
+	a.NoError(err)
+	a.Equal(`"v2"`, *obj.ETag)
+	a.Equal(`"v1"`, *put.IfMatch)
+	// Saving only replaces the content.
+	a.Equal(map[string]string{"owner": "ops"}, put.Metadata)
+	a.Equal(types.StorageClassStandardIa, put.StorageClass)
+	a.Equal("no-cache", aws.ToString(put.CacheControl))
+	a.Equal("inline", aws.ToString(put.ContentDisposition))
+	a.Equal("identity", aws.ToString(put.ContentEncoding))
+
+	put = nil
+	_, err = s.UpdateObject(ctx, "test-bucket", "app.json", "application/json", `"v0"`, strings.NewReader(`{}`))
+	a.ErrorContains(err, ErrObjectModified.Error())
+	a.Nil(put)
+	_, err = s.UpdateObject(ctx, "test-bucket", "gone.json", "application/json", `"v1"`, strings.NewReader(`{}`))
+	a.ErrorContains(err, ErrObjectModified.Error())
+
+	// The ETag matched before the upload, but not when S3 checked it.
+	putErr = apiErr(
+		http.StatusPreconditionFailed, "PreconditionFailed",
+		"At least one of the pre-conditions you specified did not hold",
+	)
+	_, err = s.UpdateObject(ctx, "test-bucket", "app.json", "application/json", `"v1"`, strings.NewReader(`{}`))
+	a.ErrorContains(err, ErrObjectModified.Error())
+	putErr = apiErr(http.StatusConflict, "ConditionalRequestConflict", "conflict")
+	_, err = s.UpdateObject(ctx, "test-bucket", "app.json", "application/json", `"v1"`, strings.NewReader(`{}`))
+	a.ErrorContains(err, ErrConcurrentUpdate.Error())
+}
+
+func TestServices_Thumbnail(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var buf bytes.Buffer
+	a.NoError(png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 200))))
+	content := map[string][]byte{
+		"cat.png":   buf.Bytes(),
+		"notes.txt": []byte("not an image"),
+		"fake.png":  []byte("not an image either"),
+	}
+	var gets int
+	mock := &mockS3Client{
+		headObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			c, ok := content[*params.Key]
+			if !ok {
+				return nil, apiErr(http.StatusNotFound, "NotFound", "")
+			}
+			return &s3.HeadObjectOutput{
+				ContentLength: aws.Int64(int64(len(c))),
+				ETag:          aws.String(`"v1"`),
+			}, nil
+		},
+		getObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			gets++
+			return &s3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(content[*params.Key])),
+			}, nil
+		},
+	}
+	s := New(mock, WithThumbnails(t.TempDir(), 0, 0))
+	ctx := context.Background()
+
+	for range 2 {
+		thumb, err := s.Thumbnail(ctx, "test-bucket", "cat.png", 100)
+		a.NoError(err)
+		a.Equal("image/png", thumb.ContentType)
+		a.Equal(`"v1"`, thumb.ETag)
+		img, err := png.Decode(bytes.NewReader(thumb.Data))
+		a.NoError(err)
+		a.Equal(image.Rect(0, 0, 100, 50), img.Bounds())
+	}
+	a.Equal(1, gets, "the second thumbnail should come from the cache")
+
+	_, err := s.Thumbnail(ctx, "test-bucket", "notes.txt", 100)
+	a.ErrorContains(err, ErrNotImage.Error())
+	_, err = s.Thumbnail(ctx, "test-bucket", "fake.png", 100)
+	a.ErrorContains(err, ErrNotImage.Error())
+
+	small := New(mock, WithThumbnails(t.TempDir(), 0, 400*200-1))
+	_, err = small.Thumbnail(ctx, "test-bucket", "cat.png", 100)
+	a.ErrorContains(err, ErrImageTooLarge.Error())
+	small = New(mock, WithThumbnails(t.TempDir(), 16, 0))
+	_, err = small.Thumbnail(ctx, "test-bucket", "cat.png", 100)
+	a.ErrorContains(err, ErrImageTooLarge.Error())
+}
+
+func TestServices_ThumbnailLimits(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var buf bytes.Buffer
+	a.NoError(png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 200))))
+	var running, most atomic.Int32
+	mock := &mockS3Client{
+		headObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{
+				ContentLength: aws.Int64(int64(buf.Len())),
+				ContentType:   aws.String("image/png"),
+				ETag:          aws.String(`"v1"`),
+			}, nil
+		},
+		getObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+			}
+			time.Sleep(10 * time.Millisecond)
+			return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(buf.Bytes()))}, nil
+		},
+	}
+	dir := t.TempDir()
+	foreign := filepath.Join(dir, "notes.txt")
+	a.NoError(os.WriteFile(foreign, []byte("not a thumbnail"), 0o644))
+	s := New(mock, WithThumbnails(dir, 0, 0), WithThumbnailLimits(1, 1))
+	ctx := context.Background()
+
+	// Images are decoded one at a time.
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Thumbnail(ctx, "test-bucket", fmt.Sprintf("%d.png", i), 100)
+			a.NoError(err)
+		}()
+	}
+	wg.Wait()
+	a.Equal(int32(1), most.Load())
+
+	// Every thumbnail is larger than the cache, so none of them is kept,
+	// while files that aren't thumbnails are left alone.
+	entries, err := os.ReadDir(dir)
+	a.NoError(err)
+	a.Len(entries, 1)
+	a.FileExists(foreign)
+}
+
+func TestServices_ScaleDown(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	// Not an RGBA image and not at the origin, red on the left and blue on
+	// the right.
+	src := image.NewNRGBA(image.Rect(10, 10, 410, 210))
+	for y := 10; y < 210; y++ {
+		for x := 10; x < 410; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= 210 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			src.SetNRGBA(x, y, c)
+		}
+	}
+	dst := scaleDown(src, 100)
+	a.Equal(image.Rect(0, 0, 100, 50), dst.Bounds())
+	a.Equal(color.RGBA{R: 255, A: 255}, dst.At(10, 10))
+	a.Equal(color.RGBA{B: 255, A: 255}, dst.At(90, 40))
+	a.Same(src, scaleDown(src, 500))
+}
+
+func TestServices_DiffObjects(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	content := map[string]string{
+		"a/app.yaml":  "name: app\nport: 80\ndebug: false\n",
+		"b/app.yaml":  "name: app\nport: 8080\ndebug: false\n",
+		"a/logo.png":  "\x89PNG\x00\x01",
+		"b/logo.png":  "\x89PNG\x00\x02",
+		"a/large.txt": strings.Repeat("a", 64),
+	}
+	mock := &mockS3Client{
+		headObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			c, ok := content[*params.Bucket+"/"+*params.Key]
+			if !ok {
+				return nil, apiErr(http.StatusNotFound, "NotFound", "")
+			}
+			return &s3.HeadObjectOutput{
+				ContentLength: aws.Int64(int64(len(c))),
+				ETag:          aws.String(fmt.Sprintf(`"%x"`, len(c))),
+			}, nil
+		},
+		getObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			return &s3.GetObjectOutput{
+				Body: io.NopCloser(strings.NewReader(content[*params.Bucket+"/"+*params.Key])),
+			}, nil
+		},
+	}
+	s := New(mock)
+	ctx := context.Background()
+	ref := func(bucket, key string) model.ObjectRef {
+		return model.ObjectRef{Bucket: bucket, Key: key}
+	}
+
+	d, err := s.DiffObjects(ctx, ref("a", "app.yaml"), ref("b", "app.yaml"), 40)
+	a.NoError(err)
+	a.True(d.Text)
+	a.False(d.Identical)
+	a.Equal(
+		"--- a/app.yaml\n+++ b/app.yaml\n@@ -1,3 +1,3 @@\n name: app\n-port: 80\n+port: 8080\n debug: false\n",
+		d.Diff,
+	)
+
+	d, err = s.DiffObjects(ctx, ref("a", "app.yaml"), ref("a", "app.yaml"), 40)
+	a.NoError(err)
+	a.True(d.Identical)
+	a.Empty(d.Diff)
+	a.Equal(d.Left.SHA256, d.Right.SHA256)
+
+	d, err = s.DiffObjects(ctx, ref("a", "logo.png"), ref("b", "logo.png"), 40)
+	a.NoError(err)
+	a.False(d.Text)
+	a.False(d.Identical)
+	a.Empty(d.Diff)
+	a.Equal(d.Left.Size, d.Right.Size)
+	a.NotEqual(d.Left.SHA256, d.Right.SHA256)
+
+	_, err = s.DiffObjects(ctx, ref("a", "large.txt"), ref("a", "app.yaml"), 40)
+	a.ErrorContains(err, ErrDiffTooLarge.Error())
+	_, err = s.DiffObjects(ctx, ref("a", "app.yaml"), ref("b", "missing.yaml"), 40)
+	a.Error(err)
+}
+
+func TestServices_UnifiedDiff(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	lines := func(n int, changed map[int]string) string {
+		var sb strings.Builder
+		for i := 1; i <= n; i++ {
+			if c, ok := changed[i]; ok {
+				sb.WriteString(c + "\n")
+				continue
+			}
+			fmt.Fprintf(&sb, "line %d\n", i)
+		}
+		return sb.String()
+	}
+	diff := func(left, right string) string {
+		return unifiedDiff("l", "r", diffLines(splitLines(left), splitLines(right)))
+	}
+
+	// Changes far apart get their own hunks.
+	a.Equal(
+		"--- l\n+++ r\n"+
+			"@@ -1,4 +1,4 @@\n-line 1\n+first\n line 2\n line 3\n line 4\n"+
+			"@@ -17,4 +17,4 @@\n line 17\n line 18\n line 19\n-line 20\n+last\n",
+		diff(lines(20, nil), lines(20, map[int]string{1: "first", 20: "last"})),
+	)
+	a.Equal(
+		"--- l\n+++ r\n@@ -1 +1 @@\n-a\n+a\n\\ No newline at end of file\n",
+		diff("a\n", "a"),
+	)
+	a.Equal("--- l\n+++ r\n@@ -0,0 +1 @@\n+a\n", diff("", "a\n"))
+	a.Empty(diff("a\nb\n", "a\nb\n"))
+}
+
+func TestServices_Checksums(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	content := "release artifact\n"
+	sha := sha256.Sum256([]byte(content))
+	stored := map[string]*string{
+		"stored.tar.gz":    aws.String(base64.StdEncoding.EncodeToString(sha[:])),
+		"corrupt.tar.gz":   aws.String(base64.StdEncoding.EncodeToString(make([]byte, 32))),
+		"multipart.tar.gz": aws.String(base64.StdEncoding.EncodeToString(sha[:]) + "-3"),
+	}
+	var put *s3.PutObjectInput
+	var gets int
+	mock := &mockS3Client{
+		putObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			put = params
+			return &s3.PutObjectOutput{ChecksumCRC32C: aws.String("yZRlqg==")}, nil
+		},
+		headObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			a.Equal(types.ChecksumModeEnabled, params.ChecksumMode)
+			return &s3.HeadObjectOutput{
+				ContentLength:  aws.Int64(int64(len(content))),
+				ChecksumSHA256: stored[*params.Key],
+			}, nil
+		},
+		getObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			a.Equal(types.ChecksumModeEnabled, params.ChecksumMode)
+			gets++
+			return &s3.GetObjectOutput{
+				Body: io.NopCloser(strings.NewReader(content)),
+			}, nil
+		},
+	}
+	s := New(mock)
+	ctx := context.Background()
+
+	obj, err := s.PutObject(ctx, "test-bucket", "app.tar.gz", "application/gzip", strings.NewReader(content), model.PutObjectOptions{})
+	a.NoError(err)
+	a.Equal(types.ChecksumAlgorithmCrc32c, put.ChecksumAlgorithm)
+	a.Equal(map[string]string{"CRC32C": "yZRlqg=="}, obj.Checksums)
+	_, err = New(mock, WithChecksums("")).PutObject(ctx, "test-bucket", "app.tar.gz", "application/gzip", strings.NewReader(content), model.PutObjectOptions{})
+	a.NoError(err)
+	a.Empty(put.ChecksumAlgorithm)
+
+	sum, err := s.ObjectChecksum(ctx, "test-bucket", "stored.tar.gz", model.ChecksumSHA256, false)
+	a.NoError(err)
+	a.Equal(model.ChecksumStored, sum.Source)
+	a.Equal(hex.EncodeToString(sha[:]), sum.Checksum)
+	a.Zero(gets)
+
+	sum, err = s.ObjectChecksum(ctx, "test-bucket", "stored.tar.gz", model.ChecksumSHA256, true)
+	a.NoError(err)
+	a.Equal(model.ChecksumComputed, sum.Source)
+	a.Equal(hex.EncodeToString(sha[:]), sum.Checksum)
+	a.True(*sum.Matches)
+
+	sum, err = s.ObjectChecksum(ctx, "test-bucket", "corrupt.tar.gz", model.ChecksumSHA256, true)
+	a.NoError(err)
+	a.False(*sum.Matches)
+
+	// A checksum of the parts can't be compared with one of the whole.
+	sum, err = s.ObjectChecksum(ctx, "test-bucket", "multipart.tar.gz", model.ChecksumSHA256, false)
+	a.NoError(err)
+	a.Equal(model.ChecksumComputed, sum.Source)
+	a.Nil(sum.Matches)
+
+	sum, err = s.ObjectChecksum(ctx, "test-bucket", "stored.tar.gz", model.ChecksumMD5, false)
+	a.NoError(err)
+	md5Sum := md5.Sum([]byte(content))
+	a.Equal(hex.EncodeToString(md5Sum[:]), sum.Checksum)
+	a.Nil(sum.Matches)
+
+	_, err = s.ObjectChecksum(ctx, "test-bucket", "stored.tar.gz", "crc32", false)
+	a.ErrorContains(err, ErrChecksumAlgorithm.Error())
+}
+
+func TestServices_ParseChecksumAlgorithm(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	for name, want := range map[string]types.ChecksumAlgorithm{
+		"":       types.ChecksumAlgorithmCrc32c,
+		"sha256": types.ChecksumAlgorithmSha256,
+		"CRC32C": types.ChecksumAlgorithmCrc32c,
+		"none":   "",
+	} {
+		got, err := ParseChecksumAlgorithm(name)
+		a.NoError(err, name)
+		a.Equal(want, got, name)
+	}
+	_, err := ParseChecksumAlgorithm("md5")
+	a.Error(err)
+}
+
+func TestServices_MapS3ErrToAppErr(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantMsg    string
+	}{
+		{
+			name:       "by code",
+			err:        apiErr(http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist"),
+			wantStatus: http.StatusNotFound,
+			wantMsg:    ErrMissingBucket.Error(),
+		},
+		{
+			name:       "archived object",
+			err:        apiErr(http.StatusForbidden, "InvalidObjectState", "The operation is not valid"),
+			wantStatus: http.StatusConflict,
+			wantMsg:    ErrNotRestored.Error(),
+		},
+		{
+			name:       "object lock",
+			err:        apiErr(http.StatusForbidden, "AccessDenied", "Access Denied because object protected by object lock"),
+			wantStatus: http.StatusConflict,
+			wantMsg:    ErrObjectLocked.Error(),
+		},
+		{
+			name:       "unknown client error",
+			err:        apiErr(http.StatusForbidden, "AccessDenied", "Access Denied"),
+			wantStatus: http.StatusForbidden,
+			wantMsg:    "Access Denied",
+		},
+		{
+			name:       "unavailable",
+			err:        apiErr(http.StatusServiceUnavailable, "ServiceUnavailable", "Reduce your request rate"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantMsg:    "Reduce your request rate",
+		},
+		{
+			name:       "server error",
+			err:        apiErr(http.StatusInternalServerError, "InternalError", ""),
+			wantStatus: http.StatusBadGateway,
+			wantMsg:    http.StatusText(http.StatusBadGateway),
+		},
+		{
+			name:       "no response",
+			err:        fmt.Errorf("dial tcp: %w", context.DeadlineExceeded),
+			wantStatus: http.StatusRequestTimeout,
+			wantMsg:    http.StatusText(http.StatusRequestTimeout),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var s3Err *model.S3Error
+			a.ErrorAs(mapS3ErrToAppErr(tt.err), &s3Err)
+			a.Equal(tt.wantStatus, s3Err.Status)
+			a.Equal(tt.wantMsg, s3Err.Message)
+			a.ErrorIs(s3Err, tt.err)
+		})
+	}
+
+	err := mapS3ErrToAppErr(hostIDErr{
+		error:  apiErr(http.StatusNotFound, "NoSuchKey", "The specified key does not exist."),
+		hostID: "ef8yU9AS1ed4OpIszj7UDNEHGran",
+	})
+	var s3Err *model.S3Error
+	a.ErrorAs(err, &s3Err)
+	a.Equal(model.S3Error{
+		Status:    http.StatusNotFound,
+		Message:   ErrMissingObject.Error(),
+		Code:      "NoSuchKey",
+		S3Message: "The specified key does not exist.",
+		S3Status:  http.StatusNotFound,
+		RequestID: "4442587FB7D0A2F9",
+		HostID:    "ef8yU9AS1ed4OpIszj7UDNEHGran",
+		Err:       s3Err.Err,
+	}, *s3Err)
+	// Mapping twice keeps what was mapped first.
+	wrapped := fmt.Errorf("deleting objects: %w", err)
+	a.Equal(wrapped, mapS3ErrToAppErr(wrapped))
+}
+
+func TestServices_RetryAndRateLimit(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var mu sync.Mutex
+	var attempts []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts = append(attempts, time.Now())
+		n := len(attempts)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/xml")
+		if n <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, "<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>")
+			return
+		}
+		_, _ = io.WriteString(w, "<ListBucketResult></ListBucketResult>")
+	}))
+	defer srv.Close()
+
+	_, err := Retry("sometimes", 0, 0)
+	a.Error(err)
+	retry, err := Retry("standard", 3, time.Millisecond)
+	a.NoError(err)
+	client := s3.New(s3.Options{
+		BaseEndpoint: aws.String(srv.URL),
+		Region:       "us-east-1",
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+	}, retry, RateLimit(20, 1))
+
+	// SlowDown is retried, each attempt waiting for its turn.
+	start := time.Now()
+	_, err = client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+		Bucket: aws.String("test-bucket"),
+	})
+	a.NoError(err)
+	a.Len(attempts, 3)
+	a.GreaterOrEqual(time.Since(start), 90*time.Millisecond)
+
+	// A request given up on while waiting hands its turn back.
+	bucket := newTokenBucket(1, 1)
+	a.NoError(bucket.wait(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	a.ErrorIs(bucket.wait(ctx), context.DeadlineExceeded)
+	a.InDelta(0, bucket.tokens, 0.1)
+}
+
+// streamReader is a seekable body that can't tell its size, like a stream
+// that can only be rewound.
+type streamReader struct {
+	*bytes.Reader
+}
+
+func (r streamReader) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekEnd {
+		return 0, errors.New("size unknown")
+	}
+	return r.Reader.Seek(offset, whence)
+}
+
+func TestServices_Metrics(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	seekable := 0
+	mock := &mockS3Client{
+		putObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			if seeker, ok := params.Body.(io.Seeker); ok {
+				seekable++
+				// Read part of the body, then rewind it as a retry would.
+				_, err := io.CopyN(io.Discard, params.Body, 2)
+				a.NoError(err)
+				_, err = seeker.Seek(0, io.SeekStart)
+				a.NoError(err)
+			}
+			_, err := io.Copy(io.Discard, params.Body)
+			return &s3.PutObjectOutput{}, err
+		},
+		getObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("hello world"))}, nil
+		},
+		listObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			return nil, apiErr(http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		},
+	}
+	reg := metrics.NewRegistry()
+	s := New(mock, WithMetrics(reg))
+	ctx := context.Background()
+
+	// A seekable body is measured up front, any other one as it's read.
+	body := bytes.NewReader([]byte("0123456789"))
+	_, _ = body.Seek(4, io.SeekStart)
+	_, err := s.s3Client.PutObject(ctx, &s3.PutObjectInput{Body: body})
+	a.NoError(err)
+	_, err = s.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Body: io.NopCloser(strings.NewReader("abcd")),
+	})
+	a.NoError(err)
+	// One that can't be measured is still seekable, and bytes read again
+	// after rewinding are counted once.
+	_, err = s.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Body: streamReader{bytes.NewReader([]byte("xyz12"))},
+	})
+	a.NoError(err)
+	a.Equal(2, seekable)
+
+	out, err := s.s3Client.GetObject(ctx, &s3.GetObjectInput{})
+	a.NoError(err)
+	_, err = io.Copy(io.Discard, out.Body)
+	a.NoError(err)
+	a.NoError(out.Body.Close())
+
+	_, err = s.s3Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{})
+	a.Error(err)
+
+	w := httptest.NewRecorder()
+	reg.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	got := w.Body.String()
+	for _, line := range []string{
+		`s3manager_s3_requests_total{operation="PutObject"} 3`,
+		`s3manager_s3_requests_total{operation="GetObject"} 1`,
+		`s3manager_s3_requests_total{operation="ListObjectsV2"} 1`,
+		`s3manager_s3_errors_total{operation="ListObjectsV2",code="NoSuchBucket"} 1`,
+		`s3manager_s3_request_duration_seconds_count{operation="PutObject"} 3`,
+		`s3manager_s3_uploaded_bytes_total 15`,
+		`s3manager_s3_downloaded_bytes_total 11`,
+		`s3manager_jobs_queued 0`,
+	} {
+		a.Contains(got, line+"\n")
+	}
+	a.NotContains(got, `s3manager_s3_errors_total{operation="PutObject"`)
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	// sortedListTTL is how long the listing read for the first page of a
+	// sorted listing serves its later pages.
+	sortedListTTL = 30 * time.Second
+	// maxSortedLists caps the listings kept, evicting the oldest first.
+	maxSortedLists = 32
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid continuation token")
+	ErrInvalidCount = errors.New("count must be positive")
+)
+
+// listEntry is a directory listing entry along with its sort keys.
+type listEntry struct {
+	obj      model.Object
+	name     string
+	size     int64
+	modified int64
+}
+
+// listCursor points at the last entry of a page. Resuming after it, instead
+// of after an offset, keeps pages consistent when objects are added or
+// removed between requests.
+type listCursor struct {
+	Options uint64 `json:"o"`
+	IsDir   bool   `json:"d,omitempty"`
+	Value   int64  `json:"v,omitempty"`
+	Name    string `json:"n"`
+}
+
+// needsFullListing reports whether the options require reading the whole
+// directory, since S3 only lists keys in lexical order.
+func needsFullListing(opt model.ListObjectsOption) bool {
+	return opt.SortBy != "" || opt.Descending || hasObjectFilters(opt)
+}
+
+func hasObjectFilters(opt model.ListObjectsOption) bool {
+	return opt.MinSize != nil || opt.MaxSize != nil ||
+		opt.ModifiedAfter != nil || opt.ModifiedBefore != nil ||
+		len(opt.Extensions) > 0 || len(opt.StorageClasses) > 0
+}
+
+// listObjectsSorted reads the whole directory under prefix, then filters,
+// sorts and pages through it. Directories come first; they are left out once
+// any object filter is set, as they have no size, date or storage class.
+// Only the first page reads the directory, later ones reuse its listing for
+// sortedListTTL.
+func (s *Services) listObjectsSorted(
+	ctx context.Context,
+	bucketName string,
+	maxKeys int32,
+	opt model.ListObjectsOption,
+	prefix, pathPrefix string,
+) ([]model.Object, *string, error) {
+	if maxKeys < 1 {
+		return nil, nil, errs.BadRequest(errs.WithMsg(ErrInvalidCount.Error()))
+	}
+	fingerprint := optionsFingerprint(opt)
+	var cursor *listCursor
+	if opt.ContinuationToken != nil {
+		c, err := decodeListCursor(*opt.ContinuationToken)
+		if err != nil || c.Options != fingerprint {
+			return nil, nil, errs.BadRequest(errs.WithMsg(ErrInvalidToken.Error()))
+		}
+		cursor = c
+	}
+
+	key := sortedListKey{bucket: bucketName, options: fingerprint}
+	compare := entryComparator(opt)
+	var entries []listEntry
+	var ok bool
+	if cursor != nil {
+		entries, ok = s.sortedLists.get(key)
+	}
+	if !ok {
+		var err error
+		entries, err = s.readListing(ctx, bucketName, opt, prefix, pathPrefix)
+		if err != nil {
+			return nil, nil, err
+		}
+		slices.SortFunc(entries, compare)
+		s.sortedLists.put(key, entries)
+	}
+
+	start := 0
+	if cursor != nil {
+		last := listEntry{
+			obj:      model.Object{IsDir: cursor.IsDir},
+			name:     cursor.Name,
+			size:     cursor.Value,
+			modified: cursor.Value,
+		}
+		start = sort.Search(len(entries), func(i int) bool {
+			return compare(entries[i], last) > 0
+		})
+	}
+	end := min(start+int(maxKeys), len(entries))
+
+	page := make([]model.Object, 0, end-start)
+	for _, e := range entries[start:end] {
+		page = append(page, e.obj)
+	}
+	var next *string
+	if end > start && end < len(entries) {
+		last := entries[end-1]
+		c := listCursor{Options: fingerprint, IsDir: last.obj.IsDir, Name: last.name}
+		switch opt.SortBy {
+		case model.SortBySize:
+			c.Value = last.size
+		case model.SortByModified:
+			c.Value = last.modified
+		}
+		token, err := encodeListCursor(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		next = &token
+	}
+	return page, next, nil
+}
+
+// readListing reads the entries of the directory under prefix that match
+// the filters of opt, in no particular order.
+func (s *Services) readListing(
+	ctx context.Context,
+	bucketName string,
+	opt model.ListObjectsOption,
+	prefix, pathPrefix string,
+) ([]listEntry, error) {
+	params := &s3.ListObjectsV2Input{
+		Bucket:                   aws.String(bucketName),
+		Prefix:                   &prefix,
+		Delimiter:                listDelimiter(opt),
+		OptionalObjectAttributes: restoreStatusAttrs,
+	}
+	filtered := hasObjectFilters(opt)
+	var entries []listEntry
+	for {
+		list, err := s.s3Client.ListObjectsV2(ctx, params)
+		if err != nil {
+			return nil, mapS3ErrToAppErr(err)
+		}
+		if !filtered {
+			for _, p := range list.CommonPrefixes {
+				if s.trash.hides(aws.ToString(p.Prefix)) {
+					continue
+				}
+				name := strings.TrimSuffix(
+					strings.TrimPrefix(aws.ToString(p.Prefix), pathPrefix), "/",
+				)
+				entries = append(entries, listEntry{
+					obj:  model.Object{Key: aws.String(name), IsDir: true},
+					name: name,
+				})
+			}
+		}
+		for _, obj := range list.Contents {
+			if aws.ToString(obj.Key) == pathPrefix || s.trash.hides(aws.ToString(obj.Key)) {
+				continue
+			}
+			if filtered && isFolderMarker(obj) || !matchesFilters(obj, opt) {
+				continue
+			}
+			entry := listEntry{
+				obj:  toModelObject(obj, pathPrefix),
+				size: aws.ToInt64(obj.Size),
+			}
+			entry.name = aws.ToString(entry.obj.Key)
+			if obj.LastModified != nil {
+				entry.modified = obj.LastModified.UnixNano()
+			}
+			entries = append(entries, entry)
+		}
+		if list.NextContinuationToken == nil {
+			break
+		}
+		params.ContinuationToken = list.NextContinuationToken
+	}
+	return entries, nil
+}
+
+type sortedListKey struct {
+	bucket  string
+	options uint64
+}
+
+type sortedList struct {
+	entries []listEntry
+	listed  time.Time
+}
+
+// sortedListCache keeps the sorted directory listings, so paging through one
+// doesn't read the whole directory again for every page. Listings aren't
+// modified once stored.
+type sortedListCache struct {
+	mu    sync.Mutex
+	lists map[sortedListKey]*sortedList
+}
+
+func newSortedListCache() *sortedListCache {
+	return &sortedListCache{lists: make(map[sortedListKey]*sortedList)}
+}
+
+// get returns the listing stored under key, unless it has expired.
+func (c *sortedListCache) get(key sortedListKey) ([]listEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list, ok := c.lists[key]
+	if !ok || time.Since(list.listed) >= sortedListTTL {
+		return nil, false
+	}
+	return list.entries, true
+}
+
+// put stores a listing under key, after dropping the expired ones and then
+// the oldest while there are maxSortedLists.
+func (c *sortedListCache) put(key sortedListKey, entries []listEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.lists, key)
+	var kept []sortedListKey
+	for k, list := range c.lists {
+		if time.Since(list.listed) >= sortedListTTL {
+			delete(c.lists, k)
+			continue
+		}
+		kept = append(kept, k)
+	}
+	slices.SortFunc(kept, func(a, b sortedListKey) int {
+		return c.lists[a].listed.Compare(c.lists[b].listed)
+	})
+	for _, k := range kept {
+		if len(c.lists) < maxSortedLists {
+			break
+		}
+		delete(c.lists, k)
+	}
+	c.lists[key] = &sortedList{entries: entries, listed: time.Now()}
+}
+
+// entryComparator orders directories before objects, then by the sort key,
+// falling back to the name so the order is total and cursors are exact.
+func entryComparator(opt model.ListObjectsOption) func(a, b listEntry) int {
+	return func(a, b listEntry) int {
+		if a.obj.IsDir != b.obj.IsDir {
+			if a.obj.IsDir {
+				return -1
+			}
+			return 1
+		}
+		var c int
+		if !a.obj.IsDir {
+			switch opt.SortBy {
+			case model.SortBySize:
+				c = cmp.Compare(a.size, b.size)
+			case model.SortByModified:
+				c = cmp.Compare(a.modified, b.modified)
+			}
+		}
+		if c == 0 {
+			c = strings.Compare(a.name, b.name)
+		}
+		if opt.Descending {
+			return -c
+		}
+		return c
+	}
+}
+
+func matchesFilters(obj types.Object, opt model.ListObjectsOption) bool {
+	size := aws.ToInt64(obj.Size)
+	if opt.MinSize != nil && size < *opt.MinSize {
+		return false
+	}
+	if opt.MaxSize != nil && size > *opt.MaxSize {
+		return false
+	}
+	modified := aws.ToTime(obj.LastModified)
+	if opt.ModifiedAfter != nil && modified.Before(*opt.ModifiedAfter) {
+		return false
+	}
+	if opt.ModifiedBefore != nil && modified.After(*opt.ModifiedBefore) {
+		return false
+	}
+	if len(opt.Extensions) > 0 {
+		ext := strings.ToLower(strings.TrimPrefix(path.Ext(aws.ToString(obj.Key)), "."))
+		if !slices.Contains(opt.Extensions, ext) {
+			return false
+		}
+	}
+	if len(opt.StorageClasses) > 0 {
+		if !slices.Contains(opt.StorageClasses, storageClass(obj.StorageClass)) {
+			return false
+		}
+	}
+	return true
+}
+
+// optionsFingerprint identifies the listing a cursor belongs to, so a token
+// can't be replayed against a different sort order or filter set.
+func optionsFingerprint(opt model.ListObjectsOption) uint64 {
+	h := fnv.New64a()
+	fmt.Fprint(h,
+		opt.Path, "\x00", opt.Filter, "\x00", opt.Recursive, opt.SortBy, opt.Descending,
+		aws.ToInt64(opt.MinSize), opt.MinSize != nil,
+		aws.ToInt64(opt.MaxSize), opt.MaxSize != nil,
+		timeKey(opt.ModifiedAfter), timeKey(opt.ModifiedBefore),
+		strings.Join(opt.Extensions, ","), strings.Join(opt.StorageClasses, ","),
+	)
+	return h.Sum64()
+}
+
+func timeKey(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func encodeListCursor(c listCursor) (string, error) {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("encoding cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeListCursor(token string) (*listCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var c listCursor
+	if err = json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	// statsTTL is how long a finished walk is served before a new one starts,
+	// and kept before it's evicted.
+	statsTTL = 10 * time.Minute
+	// maxStatsEntries caps the walks kept, evicting the finished ones used
+	// least recently first.
+	maxStatsEntries = 100
+	// maxStatsWalks caps the walks running at once.
+	maxStatsWalks = 4
+	largestCount  = 10
+)
+
+var (
+	ErrNoStatsRunning   = errors.New("no statistics are being computed")
+	ErrTooManyStatsRuns = errors.New("too many statistics are being computed, try again later")
+)
+
+var sizeRanges = []struct {
+	label string
+	upper int64
+}{
+	{"< 1 KB", 1 << 10},
+	{"< 1 MB", 1 << 20},
+	{"< 10 MB", 10 << 20},
+	{"< 100 MB", 100 << 20},
+	{"< 1 GB", 1 << 30},
+	{"< 10 GB", 10 << 30},
+	{">= 10 GB", 0},
+}
+
+type statsKey struct {
+	bucket string
+	prefix string
+}
+
+type statsEntry struct {
+	stats   model.BucketStats
+	largest largestHeap
+	cancel  context.CancelFunc
+	done    time.Time
+	used    time.Time
+}
+
+// statsTracker holds the bucket walks, whether running or finished, and
+// serves as the results cache. Walks outlive the requests that started them,
+// until base is cancelled.
+type statsTracker struct {
+	mu      sync.Mutex
+	entries map[statsKey]*statsEntry
+	running int
+
+	base context.Context
+	stop context.CancelFunc
+}
+
+func newStatsTracker() *statsTracker {
+	base, stop := context.WithCancel(context.Background())
+	return &statsTracker{
+		entries: make(map[statsKey]*statsEntry),
+		base:    base,
+		stop:    stop,
+	}
+}
+
+// evict drops the finished walks older than statsTTL, then the ones used
+// least recently while there are more than maxStatsEntries. Running walks
+// are kept. Callers must hold the lock.
+func (t *statsTracker) evict() {
+	var finished []statsKey
+	for key, entry := range t.entries {
+		switch {
+		case entry.stats.Status == model.StatsRunning:
+		case time.Since(entry.done) >= statsTTL:
+			delete(t.entries, key)
+		default:
+			finished = append(finished, key)
+		}
+	}
+	slices.SortFunc(finished, func(a, b statsKey) int {
+		return t.entries[a].used.Compare(t.entries[b].used)
+	})
+	for _, key := range finished {
+		if len(t.entries) <= maxStatsEntries {
+			break
+		}
+		delete(t.entries, key)
+	}
+}
+
+// BucketStats returns the statistics of all objects under prefix in the
+// bucket. The walk runs in the background; until it's done, the returned
+// stats reflect the progress so far. A new walk is started if there are no
+// fresh results or refresh is set, unless maxStatsWalks are running already.
+func (s *Services) BucketStats(
+	_ context.Context, bucketName, prefix string, refresh bool,
+) (*model.BucketStats, error) {
+	key := statsKey{bucket: bucketName, prefix: prefix}
+	t := s.stats
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.entries[key]
+	switch {
+	case !ok:
+	case entry.stats.Status == model.StatsRunning:
+		entry.used = time.Now()
+		return entry.snapshot(), nil
+	case !refresh && time.Since(entry.done) < statsTTL:
+		entry.used = time.Now()
+		return entry.snapshot(), nil
+	}
+	if t.running >= maxStatsWalks {
+		return nil, errs.New(
+			http.StatusTooManyRequests, errs.WithMsg(ErrTooManyStatsRuns.Error()),
+		)
+	}
+
+	walkCtx, cancel := context.WithCancel(t.base)
+	entry = &statsEntry{
+		stats: model.BucketStats{
+			Bucket:         bucketName,
+			Prefix:         prefix,
+			Status:         model.StatsRunning,
+			StorageClasses: make(map[string]model.StorageUsage),
+			Histogram:      make([]model.SizeRange, len(sizeRanges)),
+			StartedAt:      aws.ToString(formatTime(aws.Time(time.Now()))),
+		},
+		cancel: cancel,
+		used:   time.Now(),
+	}
+	for i, r := range sizeRanges {
+		entry.stats.Histogram[i].Label = r.label
+		if r.upper > 0 {
+			entry.stats.Histogram[i].UpperBound = aws.Int64(r.upper)
+		}
+	}
+	t.entries[key] = entry
+	t.running++
+	t.evict()
+	go s.walkStats(walkCtx, entry)
+
+	return entry.snapshot(), nil
+}
+
+// CancelBucketStats stops a running walk. The partial results stay available
+// until the next walk is started.
+func (s *Services) CancelBucketStats(
+	_ context.Context, bucketName, prefix string,
+) error {
+	t := s.stats
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.entries[statsKey{bucket: bucketName, prefix: prefix}]
+	if !ok || entry.stats.Status != model.StatsRunning {
+		return errs.NotFound(errs.WithMsg(ErrNoStatsRunning.Error()))
+	}
+	entry.cancel()
+	return nil
+}
+
+func (s *Services) walkStats(ctx context.Context, entry *statsEntry) {
+	defer entry.cancel()
+	params := &s3.ListObjectsV2Input{
+		Bucket: aws.String(entry.stats.Bucket),
+	}
+	if entry.stats.Prefix != "" {
+		params.Prefix = aws.String(entry.stats.Prefix)
+	}
+
+	var err error
+	for {
+		var list *s3.ListObjectsV2Output
+		list, err = s.s3Client.ListObjectsV2(ctx, params)
+		if err != nil {
+			break
+		}
+		s.stats.mu.Lock()
+		for _, obj := range list.Contents {
+			entry.add(obj)
+		}
+		s.stats.mu.Unlock()
+		if list.NextContinuationToken == nil {
+			break
+		}
+		params.ContinuationToken = list.NextContinuationToken
+	}
+
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	s.stats.running--
+	entry.done = time.Now()
+	entry.stats.FinishedAt = formatTime(&entry.done)
+	switch {
+	case err == nil:
+		entry.stats.Status = model.StatsDone
+	case errors.Is(err, context.Canceled):
+		entry.stats.Status = model.StatsCancelled
+	default:
+		entry.stats.Status = model.StatsFailed
+		entry.stats.Error = aws.String(mapS3ErrToAppErr(err).Error())
+	}
+}
+
+// add accounts a single object. Callers must hold the tracker's lock.
+func (e *statsEntry) add(obj types.Object) {
+	size := aws.ToInt64(obj.Size)
+	e.stats.ObjectCount++
+	e.stats.TotalBytes += size
+
+	for i, r := range sizeRanges {
+		if r.upper == 0 || size < r.upper {
+			e.stats.Histogram[i].Count++
+			e.stats.Histogram[i].Bytes += size
+			break
+		}
+	}
+
+	class := storageClass(obj.StorageClass)
+	usage := e.stats.StorageClasses[class]
+	usage.Count++
+	usage.Bytes += size
+	e.stats.StorageClasses[class] = usage
+
+	if e.largest.Len() < largestCount {
+		heap.Push(&e.largest, obj)
+	} else if size > aws.ToInt64(e.largest[0].Size) {
+		e.largest[0] = obj
+		heap.Fix(&e.largest, 0)
+	}
+}
+
+// snapshot returns a copy of the current stats, safe to use after the lock
+// has been released. Callers must hold the tracker's lock.
+func (e *statsEntry) snapshot() *model.BucketStats {
+	stats := e.stats
+	stats.Histogram = slices.Clone(e.stats.Histogram)
+	stats.StorageClasses = maps.Clone(e.stats.StorageClasses)
+
+	largest := slices.Clone(e.largest)
+	slices.SortFunc(largest, func(a, b types.Object) int {
+		return cmp.Compare(aws.ToInt64(b.Size), aws.ToInt64(a.Size))
+	})
+	stats.Largest = make([]model.Object, 0, len(largest))
+	for _, obj := range largest {
+		stats.Largest = append(stats.Largest, toModelObject(obj, ""))
+	}
+	return &stats
+}
+
+// largestHeap is a min-heap on object size, keeping the largest objects seen
+// so far with the smallest of them at the root.
+type largestHeap []types.Object
+
+func (h largestHeap) Len() int { return len(h) }
+func (h largestHeap) Less(i, j int) bool {
+	return aws.ToInt64(h[i].Size) < aws.ToInt64(h[j].Size)
+}
+func (h largestHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *largestHeap) Push(x any)   { *h = append(*h, x.(types.Object)) }
+func (h *largestHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+// maxSyncDiffEntries caps the objects listed for each kind of change in a
+// diff, the counts are exact.
+const maxSyncDiffEntries = 1000
+
+// syncPlan is what a sync has to do: the source objects to copy and the
+// destination objects to delete.
+type syncPlan struct {
+	copies  []types.Object
+	deletes []deleteTarget
+	diff    model.SyncDiff
+}
+
+// SyncDiff compares the source and destination of a sync job by key, size
+// and ETag, and reports what running it would change. When the sync deletes,
+// the diff carries the token that confirms it. Both are read with the
+// one S3 client, objects are copied server-side, so syncing to another
+// endpoint or account isn't supported.
+func (s *Services) SyncDiff(
+	ctx context.Context, spec model.JobSpec,
+) (*model.SyncDiff, error) {
+	spec = syncSpec(spec)
+	plan, err := s.planSync(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Delete {
+		expires := time.Now().Add(deleteTokenTTL).Truncate(time.Second)
+		plan.diff.Token = s.syncToken(spec, expires)
+		plan.diff.ExpiresAt = aws.ToString(formatTime(&expires))
+	}
+	return &plan.diff, nil
+}
+
+// confirmSync checks that token came from the diff of a sync with the same
+// source and destination, and that it hasn't expired yet.
+func (s *Services) confirmSync(spec model.JobSpec, token string) error {
+	return checkToken(token, func(expires time.Time) string {
+		return s.syncToken(spec, expires)
+	})
+}
+
+// syncToken signs the source and destination of a sync that deletes.
+func (s *Services) syncToken(spec model.JobSpec, expires time.Time) string {
+	return s.signToken(
+		"sync", expires, spec.Bucket, spec.Prefix, destBucket(spec), spec.DestPrefix,
+	)
+}
+
+// syncSpec makes the prefixes of a sync end in "/", so "photos" doesn't also
+// match "photos-old/". The empty prefix stays the whole bucket.
+func syncSpec(spec model.JobSpec) model.JobSpec {
+	spec.Prefix = dirPrefix(spec.Prefix)
+	spec.DestPrefix = dirPrefix(spec.DestPrefix)
+	return spec
+}
+
+func dirPrefix(prefix string) string {
+	if prefix == "" || strings.HasSuffix(prefix, "/") {
+		return prefix
+	}
+	return prefix + "/"
+}
+
+// planSync lists both sides of a sync. Objects are the same when their size
+// and ETag match. ETags of multipart uploads depend on the part size, so an
+// object uploaded in a different way is copied again.
+func (s *Services) planSync(
+	ctx context.Context, spec model.JobSpec,
+) (*syncPlan, error) {
+	source := make(map[string]types.Object)
+	err := s.walkPrefix(ctx, spec.Bucket, spec.Prefix, func(obj types.Object) {
+		source[strings.TrimPrefix(aws.ToString(obj.Key), spec.Prefix)] = obj
+	})
+	if err != nil {
+		return nil, mapS3ErrToAppErr(err)
+	}
+	dest := make(map[string]types.Object)
+	err = s.walkPrefix(ctx, destBucket(spec), spec.DestPrefix, func(obj types.Object) {
+		dest[strings.TrimPrefix(aws.ToString(obj.Key), spec.DestPrefix)] = obj
+	})
+	if err != nil {
+		return nil, mapS3ErrToAppErr(err)
+	}
+
+	plan := &syncPlan{diff: model.SyncDiff{
+		New:     model.SyncChanges{Entries: []model.SyncEntry{}},
+		Changed: model.SyncChanges{Entries: []model.SyncEntry{}},
+		Deleted: model.SyncChanges{Entries: []model.SyncEntry{}},
+	}}
+	for _, key := range slices.Sorted(maps.Keys(source)) {
+		obj := source[key]
+		existing, ok := dest[key]
+		switch {
+		case !ok:
+			addSyncChange(&plan.diff.New, key, obj)
+		case aws.ToInt64(obj.Size) != aws.ToInt64(existing.Size) ||
+			aws.ToString(obj.ETag) != aws.ToString(existing.ETag):
+			addSyncChange(&plan.diff.Changed, key, obj)
+		default:
+			plan.diff.Unchanged++
+			continue
+		}
+		plan.copies = append(plan.copies, obj)
+	}
+	if spec.Delete {
+		for _, key := range slices.Sorted(maps.Keys(dest)) {
+			if _, ok := source[key]; ok {
+				continue
+			}
+			obj := dest[key]
+			addSyncChange(&plan.diff.Deleted, key, obj)
+			plan.deletes = append(plan.deletes, deleteTarget{
+				key: aws.ToString(obj.Key), size: aws.ToInt64(obj.Size),
+			})
+		}
+	}
+	return plan, nil
+}
+
+func addSyncChange(c *model.SyncChanges, key string, obj types.Object) {
+	size := aws.ToInt64(obj.Size)
+	c.Count++
+	c.Bytes += size
+	if len(c.Entries) == maxSyncDiffEntries {
+		c.Truncated = true
+		return
+	}
+	c.Entries = append(c.Entries, model.SyncEntry{Key: key, Size: size})
+}
+
+// runSync copies the new and changed objects to the destination, then
+// deletes the ones missing from the source if the job asks for it. The job
+// fails if any object couldn't be copied or deleted.
+func (s *Services) runSync(ctx context.Context, r *jobRun) error {
+	spec := r.spec()
+	plan, err := s.planSync(ctx, spec)
+	if err != nil {
+		return err
+	}
+	r.setTotal(len(plan.copies) + len(plan.deletes))
+
+	s.copyObjects(ctx, r, plan.copies, func(t deleteTarget) {
+		r.done(1, t.size)
+	})
+	if len(plan.deletes) > 0 && ctx.Err() == nil {
+		s.deleteTargets(ctx, destBucket(spec), plan.deletes, func(_ int, results []model.DeleteResult, bytes int64) {
+			r.report(results, bytes)
+		})
+	}
+	if n := r.failed(); n > 0 {
+		return fmt.Errorf("%d object(s) could not be synced", n)
+	}
+	return nil
+}
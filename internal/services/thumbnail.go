@@ -0,0 +1,412 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/slogger"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	defaultThumbMaxBytes      = 20 * 1024 * 1024
+	defaultThumbMaxPixels     = 40_000_000
+	defaultThumbConcurrency   = 2
+	defaultThumbMaxCacheBytes = 256 * 1024 * 1024
+	jpegThumbQuality          = 80
+)
+
+var (
+	ErrNotImage      = errors.New("object is not a PNG, JPEG or GIF image")
+	ErrImageTooLarge = errors.New("image is too large to make a thumbnail of")
+)
+
+// thumbnailer makes thumbnails of image objects and keeps them in dir, so
+// each version of an image is only decoded once per size. The thumbnails used
+// least recently are evicted once they take more than maxCacheBytes.
+type thumbnailer struct {
+	dir           string
+	maxBytes      int64
+	maxPixels     int
+	maxCacheBytes int64
+	// sem caps the images being read and decoded at once, each of which can
+	// take maxBytes and its decoded pixels in memory.
+	sem chan struct{}
+
+	mu sync.Mutex
+	// cacheBytes is the size of dir, or -1 until it has been measured.
+	cacheBytes int64
+}
+
+func newThumbnailer() *thumbnailer {
+	return &thumbnailer{
+		dir:           filepath.Join(os.TempDir(), "s3manager", "thumbnails"),
+		maxBytes:      defaultThumbMaxBytes,
+		maxPixels:     defaultThumbMaxPixels,
+		maxCacheBytes: defaultThumbMaxCacheBytes,
+		sem:           make(chan struct{}, defaultThumbConcurrency),
+		cacheBytes:    -1,
+	}
+}
+
+// WithThumbnails keeps thumbnails in dir, and refuses to make them of images
+// larger than maxBytes or with more than maxPixels pixels. Zero values keep
+// the defaults.
+func WithThumbnails(dir string, maxBytes int64, maxPixels int) Option {
+	return func(s *Services) {
+		if dir != "" {
+			s.thumbs.dir = dir
+		}
+		if maxBytes > 0 {
+			s.thumbs.maxBytes = maxBytes
+		}
+		if maxPixels > 0 {
+			s.thumbs.maxPixels = maxPixels
+		}
+	}
+}
+
+// WithThumbnailLimits decodes at most concurrency images at once, and keeps
+// the cached thumbnails under maxCacheBytes. Zero values keep the defaults.
+func WithThumbnailLimits(concurrency int, maxCacheBytes int64) Option {
+	return func(s *Services) {
+		if concurrency > 0 {
+			s.thumbs.sem = make(chan struct{}, concurrency)
+		}
+		if maxCacheBytes > 0 {
+			s.thumbs.maxCacheBytes = maxCacheBytes
+		}
+	}
+}
+
+// Thumbnail returns an image object scaled down to fit in a size by size
+// square. Thumbnails are cached by the object's ETag, so a changed image
+// gets a new one.
+func (s *Services) Thumbnail(
+	ctx context.Context, bucketName, objectKey string, size int,
+) (*model.Thumbnail, error) {
+	t := s.thumbs
+	obj, err := s.StatObject(ctx, bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	if !isImage(objectKey, aws.ToString(obj.ContentType)) {
+		return nil, errs.New(
+			http.StatusUnsupportedMediaType, errs.WithMsg(ErrNotImage.Error()),
+		)
+	}
+	if aws.ToInt64(obj.Size) > t.maxBytes {
+		return nil, errs.New(
+			http.StatusRequestEntityTooLarge, errs.WithMsg(ErrImageTooLarge.Error()),
+		)
+	}
+
+	etag := aws.ToString(obj.ETag)
+	name := t.cacheName(bucketName, objectKey, etag, size)
+	if thumb := t.cached(name, etag); thumb != nil {
+		return thumb, nil
+	}
+
+	select {
+	case t.sem <- struct{}{}:
+		defer func() { <-t.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	// It may have been made while this request waited for its turn.
+	if thumb := t.cached(name, etag); thumb != nil {
+		return thumb, nil
+	}
+
+	body, _, err := s.GetObject(ctx, bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			slogger.Error(ctx, "closing image body", slogger.Err("error", err))
+		}
+	}()
+	data, err := io.ReadAll(io.LimitReader(body, t.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading image: %w", err)
+	}
+	if int64(len(data)) > t.maxBytes {
+		return nil, errs.New(
+			http.StatusRequestEntityTooLarge, errs.WithMsg(ErrImageTooLarge.Error()),
+		)
+	}
+
+	thumb, ext, err := t.make(data, size)
+	if err != nil {
+		return nil, err
+	}
+	if err = t.store(name+ext, thumb); err != nil {
+		// The thumbnail is still served, it's only made again next time.
+		slogger.Error(ctx, "caching thumbnail", slogger.Err("error", err))
+	}
+	return &model.Thumbnail{
+		Data: thumb, ContentType: thumbContentType(ext), ETag: etag,
+	}, nil
+}
+
+// make decodes an image and scales it down. The header is read first, so
+// images with too many pixels are refused before they're decoded. JPEGs stay
+// JPEGs, everything else becomes a PNG to keep its transparency.
+func (t *thumbnailer) make(data []byte, size int) ([]byte, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errs.New(
+			http.StatusUnsupportedMediaType,
+			errs.WithErr(err), errs.WithMsg(ErrNotImage.Error()),
+		)
+	}
+	if cfg.Width*cfg.Height > t.maxPixels {
+		return nil, "", errs.New(
+			http.StatusRequestEntityTooLarge, errs.WithMsg(ErrImageTooLarge.Error()),
+		)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errs.New(
+			http.StatusUnsupportedMediaType,
+			errs.WithErr(err), errs.WithMsg(ErrNotImage.Error()),
+		)
+	}
+
+	var buf bytes.Buffer
+	scaled := scaleDown(img, size)
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: jpegThumbQuality})
+		return buf.Bytes(), ".jpg", err
+	}
+	err = png.Encode(&buf, scaled)
+	return buf.Bytes(), ".png", err
+}
+
+// cached reads a thumbnail from the cache, marking it as used, or returns nil
+// when it isn't there.
+func (t *thumbnailer) cached(name, etag string) *model.Thumbnail {
+	for _, ext := range []string{".jpg", ".png"} {
+		file := filepath.Join(t.dir, name+ext)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		now := time.Now()
+		_ = os.Chtimes(file, now, now)
+		return &model.Thumbnail{
+			Data: data, ContentType: thumbContentType(ext), ETag: etag,
+		}
+	}
+	return nil
+}
+
+// store writes a thumbnail to a temporary file first, so a thumbnail that's
+// being written is never read half-done.
+func (t *thumbnailer) store(name string, data []byte) error {
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(t.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), filepath.Join(t.dir, name)); err != nil {
+		return err
+	}
+	return t.grow(int64(len(data)))
+}
+
+// grow adds n bytes to the size of the cache, and evicts thumbnails once it's
+// larger than maxCacheBytes.
+func (t *thumbnailer) grow(n int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cacheBytes >= 0 {
+		t.cacheBytes += n
+		if t.cacheBytes <= t.maxCacheBytes {
+			return nil
+		}
+	}
+	var err error
+	t.cacheBytes, err = t.evict()
+	return err
+}
+
+// evict removes the thumbnails used least recently until the cache is down
+// to three quarters of maxCacheBytes, so it isn't pruned again on the next
+// store, and returns its size. Only files named like thumbnails are counted
+// and removed, in case dir is shared.
+func (t *thumbnailer) evict() (int64, error) {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return -1, err
+	}
+	type thumbFile struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var files []thumbFile
+	var total int64
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !isThumbName(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			// Removed in the meantime.
+			continue
+		}
+		files = append(files, thumbFile{
+			path: filepath.Join(t.dir, e.Name()),
+			size: info.Size(),
+			used: info.ModTime(),
+		})
+		total += info.Size()
+	}
+	if total <= t.maxCacheBytes {
+		return total, nil
+	}
+
+	slices.SortFunc(files, func(a, b thumbFile) int {
+		return a.used.Compare(b.used)
+	})
+	target := t.maxCacheBytes / 4 * 3
+	for _, f := range files {
+		if total <= target {
+			break
+		}
+		err = os.Remove(f.path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return total, err
+		}
+		total -= f.size
+	}
+	return total, nil
+}
+
+// isThumbName reports whether name is a thumbnail made by cacheName.
+func isThumbName(name string) bool {
+	ext := filepath.Ext(name)
+	if ext != ".jpg" && ext != ".png" {
+		return false
+	}
+	_, err := hex.DecodeString(strings.TrimSuffix(name, ext))
+	return err == nil && len(name) == sha256.Size*2+len(ext)
+}
+
+// cacheName hashes everything that identifies a thumbnail, so any key makes
+// a valid file name.
+func (t *thumbnailer) cacheName(bucketName, objectKey, etag string, size int) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%d", bucketName, objectKey, etag, size)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// scaleDown shrinks img to fit in a size by size square, averaging the
+// pixels that fall into each of the new ones. The source is converted one
+// row at a time rather than copied whole, so only the decoded image itself
+// is kept in memory. Smaller images are kept as they are.
+func scaleDown(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	row := image.NewRGBA(image.Rect(0, 0, w, 1))
+	sums := make([]int, dw*4)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		y0, y1 := scaleSpan(y, h, dh)
+		clear(sums)
+		for sy := y0; sy < y1; sy++ {
+			draw.Draw(row, row.Bounds(), img, image.Pt(b.Min.X, b.Min.Y+sy), draw.Src)
+			for x := range dw {
+				x0, x1 := scaleSpan(x, w, dw)
+				sum := sums[x*4 : x*4+4]
+				for i := x0 * 4; i < x1*4; i += 4 {
+					sum[0] += int(row.Pix[i])
+					sum[1] += int(row.Pix[i+1])
+					sum[2] += int(row.Pix[i+2])
+					sum[3] += int(row.Pix[i+3])
+				}
+			}
+		}
+		for x := range dw {
+			x0, x1 := scaleSpan(x, w, dw)
+			n := (x1 - x0) * (y1 - y0)
+			p := dst.Pix[y*dst.Stride+x*4:]
+			for i, sum := range sums[x*4 : x*4+4] {
+				p[i] = uint8(sum / n)
+			}
+		}
+	}
+	return dst
+}
+
+// scaleSpan returns the source pixels, out of n, that make up pixel i of
+// the dn scaled down ones.
+func scaleSpan(i, n, dn int) (int, int) {
+	start := i * n / dn
+	return start, max((i+1)*n/dn, start+1)
+}
+
+// isImage tells images apart by their content type, or by their extension
+// when it's missing or generic.
+func isImage(key, contentType string) bool {
+	switch strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0])) {
+	case "image/png", "image/jpeg", "image/gif":
+		return true
+	case "", "application/octet-stream", "binary/octet-stream":
+		switch strings.ToLower(path.Ext(key)) {
+		case ".png", ".jpg", ".jpeg", ".gif":
+			return true
+		}
+	}
+	return false
+}
+
+func thumbContentType(ext string) string {
+	if ext == ".jpg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
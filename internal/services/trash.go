@@ -0,0 +1,465 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hossein1376/grape/errs"
+	"github.com/hossein1376/grape/slogger"
+
+	"github.com/hossein1376/s3manager/internal/model"
+)
+
+const (
+	defaultTrashPrefix = ".trash/"
+	// trashStampLayout names the folder every deletion goes to. It sorts by
+	// time and tells when the object was deleted without another request.
+	trashStampLayout   = "20060102T150405.000000000Z"
+	trashPurgeInterval = time.Hour
+	// trashHeadConcurrency caps the requests reading who deleted the items
+	// of a trash listing.
+	trashHeadConcurrency = 8
+	// trashMoveConcurrency caps the objects of a bulk delete moved to the
+	// trash at once.
+	trashMoveConcurrency = 8
+
+	metaOriginalBucket = "trash-original-bucket"
+	metaOriginalKey    = "trash-original-key"
+	metaDeletedBy      = "trash-deleted-by"
+	metaDeletedAt      = "trash-deleted-at"
+)
+
+var (
+	ErrTrashDisabled  = errors.New("trash is not enabled")
+	ErrTrashItem      = errors.New("invalid trash item")
+	ErrTrashTooLarge  = errors.New("objects larger than 5 GiB can't be moved to the trash, delete them permanently instead")
+	ErrRestoreExists  = errors.New("an object already exists at the original path")
+	ErrTrashNotPurged = errors.New("some trash items could not be purged")
+
+	errTrashMissing = errors.New("object to move to the trash doesn't exist")
+)
+
+// trash moves deleted objects under prefix, in bucket or in the bucket they
+// were deleted from when it's empty. Items older than retention are purged,
+// unless it's zero.
+type trash struct {
+	bucket    string
+	prefix    string
+	retention time.Duration
+	stop      context.CancelFunc
+}
+
+// WithTrash makes object deletes, single, bulk or recursive, move objects to
+// the trash rather than removing them, unless they're asked to be permanent.
+func WithTrash(bucket, prefix string, retention time.Duration) Option {
+	return func(s *Services) {
+		if prefix == "" {
+			prefix = defaultTrashPrefix
+		}
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		s.trash = &trash{bucket: bucket, prefix: prefix, retention: retention}
+	}
+}
+
+// location returns where objects deleted from bucketName are kept.
+func (t *trash) location(bucketName string) (string, string) {
+	if t.bucket == "" {
+		return bucketName, t.prefix + bucketName + "/"
+	}
+	return t.bucket, t.prefix + bucketName + "/"
+}
+
+// keeps reports whether deleting key moves it to the trash. Objects that are
+// already part of the trash are deleted for good.
+func (t *trash) keeps(bucketName, key string) bool {
+	switch {
+	case t == nil, t.hides(key):
+		return false
+	case t.bucket != "":
+		return bucketName != t.bucket
+	}
+	return true
+}
+
+// hides reports whether key is part of the trash, which is left out of the
+// listings of the bucket it's kept in.
+func (t *trash) hides(key string) bool {
+	return t != nil && t.bucket == "" && strings.HasPrefix(key, t.prefix)
+}
+
+// moveToTrash copies the object to the trash, along with where it came from
+// and who deleted it, then removes the original.
+func (s *Services) moveToTrash(
+	ctx context.Context, bucketName, key, deletedBy string,
+) error {
+	head, err := s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	switch {
+	case errors.As(err, &notFound):
+		return errTrashMissing
+	case err != nil:
+		return mapS3ErrToAppErr(err)
+	}
+	if aws.ToInt64(head.ContentLength) > maxCopySize {
+		return errs.BadRequest(errs.WithMsg(ErrTrashTooLarge.Error()))
+	}
+
+	now := time.Now().UTC()
+	trashBucket, prefix := s.trash.location(bucketName)
+	metadata := maps.Clone(head.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadata[metaOriginalBucket] = bucketName
+	metadata[metaOriginalKey] = key
+	metadata[metaDeletedAt] = now.Format(time.RFC3339)
+	if deletedBy != "" {
+		metadata[metaDeletedBy] = deletedBy
+	}
+	err = s.copyWithMetadata(
+		ctx, bucketName, key, trashBucket,
+		prefix+now.Format(trashStampLayout)+"/"+key, head, metadata,
+	)
+	if err != nil {
+		return fmt.Errorf("moving to trash: %w", err)
+	}
+
+	_, err = s.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	return mapS3ErrToAppErr(err)
+}
+
+// trashTargets moves the targets the trash keeps to it, up to
+// trashMoveConcurrency at once, and returns the ones left to delete. onMoved
+// is called, possibly concurrently, with the result of each move and the
+// bytes it freed.
+func (s *Services) trashTargets(
+	ctx context.Context,
+	bucketName string,
+	targets []deleteTarget,
+	deletedBy string,
+	onMoved func(result model.DeleteResult, bytes int64),
+) []deleteTarget {
+	var kept, rest []deleteTarget
+	for _, t := range targets {
+		if s.trash.keeps(bucketName, t.key) {
+			kept = append(kept, t)
+		} else {
+			rest = append(rest, t)
+		}
+	}
+
+	sem := make(chan struct{}, trashMoveConcurrency)
+	var wg sync.WaitGroup
+	for _, t := range kept {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := s.moveToTrash(ctx, bucketName, t.key, deletedBy)
+			if err != nil && !errors.Is(err, errTrashMissing) {
+				onMoved(failedDelete(t.key, err), 0)
+				return
+			}
+			// Deleting a missing key still succeeds.
+			onMoved(model.DeleteResult{Key: t.key, Deleted: true}, t.size)
+		}()
+	}
+	wg.Wait()
+	return rest
+}
+
+// trashPrefix moves every object under prefix to the trash, and deletes the
+// ones it doesn't keep. It fails if any of them is left behind.
+func (s *Services) trashPrefix(
+	ctx context.Context, bucketName, prefix, deletedBy string,
+) error {
+	results, err := s.DeleteObjects(
+		ctx, bucketName, nil, []string{prefix},
+		model.DeleteObjectsOptions{DeletedBy: deletedBy},
+	)
+	if err != nil {
+		return err
+	}
+	var failures []types.Error
+	for _, r := range results {
+		if !r.Deleted {
+			failures = append(failures, types.Error{Key: aws.String(r.Key), Message: r.Error})
+		}
+	}
+	return deleteErrsToAppErr(failures)
+}
+
+// copyWithMetadata copies an object, replacing its user metadata while
+// keeping the headers that describe its content.
+func (s *Services) copyWithMetadata(
+	ctx context.Context,
+	bucketName, key, destBucket, destKey string,
+	head *s3.HeadObjectOutput,
+	metadata map[string]string,
+) error {
+	_, err := s.s3Client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:             aws.String(destBucket),
+		Key:                aws.String(destKey),
+		CopySource:         aws.String(copySource(bucketName, key)),
+		MetadataDirective:  types.MetadataDirectiveReplace,
+		Metadata:           metadata,
+		ContentType:        head.ContentType,
+		ContentEncoding:    head.ContentEncoding,
+		ContentDisposition: head.ContentDisposition,
+		ContentLanguage:    head.ContentLanguage,
+		CacheControl:       head.CacheControl,
+	})
+	return mapS3ErrToAppErr(err)
+}
+
+// ListTrash lists the items deleted from a bucket, oldest first.
+func (s *Services) ListTrash(
+	ctx context.Context, bucketName string, count int32, token *string,
+) ([]model.TrashItem, *string, error) {
+	if s.trash == nil {
+		return nil, nil, errs.NotFound(errs.WithMsg(ErrTrashDisabled.Error()))
+	}
+	trashBucket, prefix := s.trash.location(bucketName)
+	list, err := s.s3Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:            aws.String(trashBucket),
+		Prefix:            aws.String(prefix),
+		MaxKeys:           aws.Int32(count),
+		ContinuationToken: token,
+	})
+	if err != nil {
+		return nil, nil, mapS3ErrToAppErr(err)
+	}
+
+	items := make([]model.TrashItem, 0, len(list.Contents))
+	for _, obj := range list.Contents {
+		id := strings.TrimPrefix(aws.ToString(obj.Key), prefix)
+		deletedAt, key, err := parseTrashID(id)
+		if err != nil {
+			// Not something the trash put there.
+			continue
+		}
+		item := model.TrashItem{
+			ID:        id,
+			Bucket:    bucketName,
+			Key:       key,
+			Size:      aws.ToInt64(obj.Size),
+			DeletedAt: aws.ToString(formatTime(&deletedAt)),
+		}
+		if s.trash.retention > 0 {
+			item.PurgeAt = formatTime(aws.Time(deletedAt.Add(s.trash.retention)))
+		}
+		items = append(items, item)
+	}
+
+	// Who deleted an item is only kept in its metadata. Items that can't be
+	// read are still listed, without it.
+	sem := make(chan struct{}, trashHeadConcurrency)
+	var wg sync.WaitGroup
+	for i := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			head, err := s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: aws.String(trashBucket),
+				Key:    aws.String(prefix + items[i].ID),
+			})
+			if err == nil && head.Metadata[metaDeletedBy] != "" {
+				items[i].DeletedBy = aws.String(head.Metadata[metaDeletedBy])
+			}
+		}()
+	}
+	wg.Wait()
+	return items, list.NextContinuationToken, nil
+}
+
+// RestoreTrash moves an item back to where it was deleted from. It doesn't
+// replace an object that has been put there since.
+func (s *Services) RestoreTrash(ctx context.Context, bucketName, id string) error {
+	if s.trash == nil {
+		return errs.NotFound(errs.WithMsg(ErrTrashDisabled.Error()))
+	}
+	_, key, err := parseTrashID(id)
+	if err != nil {
+		return err
+	}
+	trashBucket, prefix := s.trash.location(bucketName)
+	trashKey := prefix + id
+	head, err := s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(trashBucket),
+		Key:    aws.String(trashKey),
+	})
+	if err != nil {
+		return mapS3ErrToAppErr(err)
+	}
+
+	_, err = s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	switch {
+	case err == nil:
+		return errs.Conflict(errs.WithMsg(ErrRestoreExists.Error()))
+	case !errors.As(err, &notFound):
+		return mapS3ErrToAppErr(err)
+	}
+
+	metadata := maps.Clone(head.Metadata)
+	for _, k := range []string{
+		metaOriginalBucket, metaOriginalKey, metaDeletedBy, metaDeletedAt,
+	} {
+		delete(metadata, k)
+	}
+	err = s.copyWithMetadata(
+		ctx, trashBucket, trashKey, bucketName, key, head, metadata,
+	)
+	if err != nil {
+		return fmt.Errorf("restoring from trash: %w", err)
+	}
+	_, err = s.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(trashBucket),
+		Key:    aws.String(trashKey),
+	})
+	return mapS3ErrToAppErr(err)
+}
+
+// PurgeTrash permanently deletes an item from the trash.
+func (s *Services) PurgeTrash(ctx context.Context, bucketName, id string) error {
+	if s.trash == nil {
+		return errs.NotFound(errs.WithMsg(ErrTrashDisabled.Error()))
+	}
+	if _, _, err := parseTrashID(id); err != nil {
+		return err
+	}
+	trashBucket, prefix := s.trash.location(bucketName)
+	_, err := s.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(trashBucket),
+		Key:    aws.String(prefix + id),
+	})
+	return mapS3ErrToAppErr(err)
+}
+
+// EmptyTrash permanently deletes every item deleted from a bucket.
+func (s *Services) EmptyTrash(ctx context.Context, bucketName string) error {
+	if s.trash == nil {
+		return errs.NotFound(errs.WithMsg(ErrTrashDisabled.Error()))
+	}
+	trashBucket, prefix := s.trash.location(bucketName)
+	return s.purgeTrash(ctx, trashBucket, prefix, time.Time{})
+}
+
+// purgeTrash deletes the items under prefix that were deleted before the
+// cutoff, or all of them when it's zero.
+func (s *Services) purgeTrash(
+	ctx context.Context, trashBucket, prefix string, cutoff time.Time,
+) error {
+	var targets []deleteTarget
+	err := s.walkPrefix(ctx, trashBucket, prefix, func(obj types.Object) {
+		key := aws.ToString(obj.Key)
+		if !cutoff.IsZero() {
+			// Expired items are looked up from the root of the trash, where
+			// they sit under <bucket>/<stamp>/.
+			_, id, _ := strings.Cut(strings.TrimPrefix(key, prefix), "/")
+			deletedAt, _, err := parseTrashID(id)
+			if err != nil || !deletedAt.Before(cutoff) {
+				return
+			}
+		}
+		targets = append(targets, deleteTarget{key: key})
+	})
+	if err != nil {
+		return mapS3ErrToAppErr(err)
+	}
+
+	var failed atomic.Bool
+	s.deleteTargets(ctx, trashBucket, targets, func(_ int, results []model.DeleteResult, _ int64) {
+		for _, r := range results {
+			if !r.Deleted {
+				failed.Store(true)
+			}
+		}
+	})
+	if failed.Load() {
+		return errs.BadGateway(errs.WithMsg(ErrTrashNotPurged.Error()))
+	}
+	return nil
+}
+
+// purgeExpiredTrash deletes the items that have been in the trash longer
+// than the retention. Without a trash bucket, every bucket has its own.
+func (s *Services) purgeExpiredTrash(ctx context.Context) error {
+	cutoff := time.Now().Add(-s.trash.retention)
+	if s.trash.bucket != "" {
+		return s.purgeTrash(ctx, s.trash.bucket, s.trash.prefix, cutoff)
+	}
+
+	params := &s3.ListBucketsInput{}
+	var errList []error
+	for {
+		list, err := s.s3Client.ListBuckets(ctx, params)
+		if err != nil {
+			return mapS3ErrToAppErr(err)
+		}
+		for _, b := range list.Buckets {
+			err = s.purgeTrash(ctx, aws.ToString(b.Name), s.trash.prefix, cutoff)
+			if err != nil {
+				errList = append(errList, fmt.Errorf("%s: %w", aws.ToString(b.Name), err))
+			}
+		}
+		if list.ContinuationToken == nil {
+			return errors.Join(errList...)
+		}
+		params.ContinuationToken = list.ContinuationToken
+	}
+}
+
+// purgeTrashLoop purges expired items right away, then periodically, until
+// the service is closed.
+func (s *Services) purgeTrashLoop(ctx context.Context) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.purgeExpiredTrash(ctx); err != nil && ctx.Err() == nil {
+			slogger.Error(ctx, "purging expired trash", slogger.Err("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseTrashID splits an item's ID into the time it was deleted and its
+// original key.
+func parseTrashID(id string) (time.Time, string, error) {
+	stamp, key, ok := strings.Cut(id, "/")
+	if !ok || key == "" {
+		return time.Time{}, "", errs.BadRequest(errs.WithMsg(ErrTrashItem.Error()))
+	}
+	deletedAt, err := time.Parse(trashStampLayout, stamp)
+	if err != nil {
+		return time.Time{}, "", errs.BadRequest(errs.WithMsg(ErrTrashItem.Error()))
+	}
+	return deletedAt, key, nil
+}
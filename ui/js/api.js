@@ -26,6 +26,45 @@ async function apiGet(endpoint, params = {}) {
     return response.json();
 }
 
+/**
+ * Makes a GET request to a streaming endpoint, calling onEvent for every
+ * line of the newline delimited JSON response
+ * @param {string} endpoint - API endpoint
+ * @param {Object} params - Query parameters
+ * @param {Function} onEvent - Called with each parsed line
+ * @param {AbortSignal} signal - Aborts the request
+ * @returns {Promise<void>}
+ */
+async function apiStream(endpoint, params, onEvent, signal) {
+    const url = new URL(`${API_BASE}${endpoint}`);
+    Object.entries(params).forEach(([key, value]) => {
+        if (value !== null && value !== undefined && value !== '') {
+            url.searchParams.set(key, value);
+        }
+    });
+
+    const response = await fetch(url, { signal });
+    if (!response.ok) {
+        const errorText = await response.text();
+        throw new Error(errorText || `HTTP ${response.status}`);
+    }
+
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = '';
+    for (;;) {
+        const { done, value } = await reader.read();
+        if (done) break;
+        buffer += decoder.decode(value, { stream: true });
+        const lines = buffer.split('\n');
+        buffer = lines.pop();
+        lines.filter(line => line.trim()).forEach(line => onEvent(JSON.parse(line)));
+    }
+    if (buffer.trim()) {
+        onEvent(JSON.parse(buffer));
+    }
+}
+
 /**
  * Makes a POST request to the API
  * @param {string} endpoint - API endpoint
@@ -70,11 +109,59 @@ async function apiPutFormData(endpoint, formData) {
     return text ? JSON.parse(text) : {};
 }
 
+/**
+ * Makes a POST request with FormData to the API
+ * @param {string} endpoint - API endpoint
+ * @param {FormData} formData - Form data to upload
+ * @returns {Promise<Object>} Response data
+ */
+async function apiPostFormData(endpoint, formData) {
+    const response = await fetch(`${API_BASE}${endpoint}`, {
+        method: 'POST',
+        body: formData
+    });
+
+    if (!response.ok) {
+        const errorText = await response.text();
+        throw new Error(errorText || `HTTP ${response.status}`);
+    }
+
+    const text = await response.text();
+    return text ? JSON.parse(text) : {};
+}
+
+/**
+ * Makes a PUT request with a text body to the API
+ * @param {string} endpoint - API endpoint
+ * @param {string} body - Text to send
+ * @param {string} contentType - Type of the body
+ * @param {Object} headers - Extra request headers, such as If-Match
+ * @returns {Promise<Object>} Response data; a failed request throws an error
+ * with its status code
+ */
+async function apiPutText(endpoint, body, contentType = 'text/plain; charset=utf-8', headers = {}) {
+    const response = await fetch(`${API_BASE}${endpoint}`, {
+        method: 'PUT',
+        headers: { ...headers, 'Content-Type': contentType },
+        body
+    });
+
+    if (!response.ok) {
+        const errorText = await response.text();
+        const error = new Error(errorText || `HTTP ${response.status}`);
+        error.status = response.status;
+        throw error;
+    }
+
+    const text = await response.text();
+    return text ? JSON.parse(text) : {};
+}
+
 /**
  * Makes a DELETE request to the API
  * @param {string} endpoint - API endpoint
  * @param {Object} params - Query parameters
- * @returns {Promise<void>}
+ * @returns {Promise<Object>} Response data, empty when there is none
  */
 async function apiDelete(endpoint, params = {}) {
     let url = `${API_BASE}${endpoint}`;
@@ -89,6 +176,9 @@ async function apiDelete(endpoint, params = {}) {
         const errorText = await response.text();
         throw new Error(errorText || `HTTP ${response.status}`);
     }
+
+    const text = await response.text();
+    return text ? JSON.parse(text) : {};
 }
 
 /**
@@ -101,11 +191,26 @@ function getObjectDownloadUrl(bucket, key) {
     return `${API_BASE}/buckets/${bucket}/objects/${encodeURIComponent(key)}`;
 }
 
+/**
+ * Gets the thumbnail URL for an image object
+ * @param {string} bucket - Bucket name
+ * @param {string} key - Object key
+ * @param {number} size - Largest side of the thumbnail in pixels
+ * @returns {string} Thumbnail URL
+ */
+function getThumbnailUrl(bucket, key, size = 256) {
+    return `${getObjectDownloadUrl(bucket, key)}/thumbnail?size=${size}`;
+}
+
 // Export for use in other modules
 window.S3API = {
     get: apiGet,
+    stream: apiStream,
     post: apiPost,
     putFormData: apiPutFormData,
+    postFormData: apiPostFormData,
+    putText: apiPutText,
     delete: apiDelete,
-    getObjectDownloadUrl
+    getObjectDownloadUrl,
+    getThumbnailUrl
 };
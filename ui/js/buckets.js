@@ -48,6 +48,21 @@ const BucketsModule = (function () {
       createForm.addEventListener("submit", handleCreateBucket);
     }
 
+    // Create bucket options modal
+    const optionsBtn = document.getElementById("bucket-options-button");
+    if (optionsBtn) {
+      optionsBtn.addEventListener("click", () =>
+        document.getElementById("bucket-options-modal")?.showModal(),
+      );
+    }
+
+    const closeOptionsBtn = document.getElementById("close-bucket-options");
+    if (closeOptionsBtn) {
+      closeOptionsBtn.addEventListener("click", () =>
+        document.getElementById("bucket-options-modal")?.close(),
+      );
+    }
+
     // Filter form
     const filterForm = document.getElementById("bucket-filter-form");
     if (filterForm) {
@@ -159,7 +174,7 @@ const BucketsModule = (function () {
                         </a>
                     </div>
                 </td>
-                <td class="cell-date">${S3Utils.formatDate(bucket.created_at)}</td>
+                <td class="cell-date" title="${S3Utils.escapeHtml([bucket.created_at, bucket.region].filter(Boolean).join(" · "))}">${S3Utils.formatDate(bucket.created_at)}</td>
                 <td class="cell-actions">
                     <div class="action-buttons">
                         <button class="btn btn-primary btn-sm" onclick="BucketsModule.openBucket('${S3Utils.escapeHtml(bucket.name)}')">
@@ -195,9 +210,25 @@ const BucketsModule = (function () {
     submitBtn.disabled = true;
     submitBtn.setAttribute("aria-busy", "true");
 
+    let body;
+    try {
+      body = { name, ...readBucketOptions() };
+    } catch (error) {
+      S3Utils.showToast(error.message, "warning");
+      submitBtn.disabled = false;
+      submitBtn.setAttribute("aria-busy", "false");
+      return;
+    }
+
     try {
-      await S3API.post("/buckets", { name });
+      await S3API.post("/buckets", body);
       nameInput.value = "";
+      document
+        .querySelectorAll("#bucket-options-modal input, #bucket-options-modal select, #bucket-options-modal textarea")
+        .forEach((el) => {
+          if (el.type === "checkbox") el.checked = false;
+          else el.value = "";
+        });
       loadBuckets(true);
       S3Utils.showToast(`Bucket "${name}" was created`, "success");
     } catch (error) {
@@ -208,6 +239,45 @@ const BucketsModule = (function () {
     }
   }
 
+  /**
+   * Reads the optional bucket creation settings from the options modal
+   * @returns {Object} Create bucket request fields
+   */
+  function readBucketOptions() {
+    const value = (id) => document.getElementById(id)?.value.trim() || "";
+    const checked = (id) => document.getElementById(id)?.checked || false;
+    const options = {};
+
+    if (value("bucket-region")) options.region = value("bucket-region");
+    if (value("bucket-ownership")) {
+      options.object_ownership = value("bucket-ownership");
+    }
+    if (checked("bucket-versioning")) options.versioning = true;
+    if (checked("bucket-object-lock")) options.object_lock = { enabled: true };
+    if (value("bucket-encryption")) {
+      options.encryption = { algorithm: value("bucket-encryption") };
+      if (value("bucket-kms-key")) {
+        options.encryption.kms_key_id = value("bucket-kms-key");
+      }
+    }
+
+    const tags = {};
+    value("bucket-tags")
+      .split("\n")
+      .map((line) => line.trim())
+      .filter(Boolean)
+      .forEach((line) => {
+        const idx = line.indexOf("=");
+        if (idx <= 0) {
+          throw new Error(`Invalid tag "${line}", expected key=value`);
+        }
+        tags[line.slice(0, idx).trim()] = line.slice(idx + 1).trim();
+      });
+    if (Object.keys(tags).length > 0) options.tags = tags;
+
+    return options;
+  }
+
   /**
    * Handles filter form submission
    * @param {Event} e - Submit event
@@ -272,8 +342,24 @@ const BucketsModule = (function () {
     closeDeleteModal();
 
     try {
-      const params = recursive ? { recursive: "true" } : {};
-      await S3API.delete(`/buckets/${name}`, params);
+      // Emptying a bucket can take longer than a request may last, so it
+      // runs as a background job.
+      if (recursive) {
+        const { data: preview } = await S3API.delete(`/buckets/${name}`, {
+          recursive: "true",
+          dry_run: "true",
+        });
+        if (!(await S3Utils.confirmDeletePreview(preview, name))) return;
+        const spec = { type: "delete_bucket", bucket: name, confirm: preview.token };
+        await JobsModule.submit(spec, (job) => {
+          if (job.status === "done") {
+            loadBuckets(true);
+            S3Utils.showToast(`Bucket "${name}" was deleted`, "success");
+          }
+        });
+        return;
+      }
+      await S3API.delete(`/buckets/${name}`);
       loadBuckets(true);
       S3Utils.showToast(`Bucket "${name}" was deleted`, "success");
     } catch (error) {
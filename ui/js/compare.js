@@ -0,0 +1,135 @@
+/**
+ * Compare Module - Compares two objects, in the same or different buckets
+ */
+
+const CompareModule = (function () {
+  /**
+   * Initializes the compare dialog
+   */
+  function init() {
+    document.getElementById("compare-toggle")?.addEventListener("click", open);
+    document.getElementById("compare-form")?.addEventListener("submit", (e) => {
+      e.preventDefault();
+      compare();
+    });
+    document.getElementById("compare-swap")?.addEventListener("click", () => {
+      const left = document.getElementById("compare-left");
+      const right = document.getElementById("compare-right");
+      [left.value, right.value] = [right.value, left.value];
+    });
+    document
+      .getElementById("cancel-compare")
+      ?.addEventListener("click", () =>
+        document.getElementById("compare-modal")?.close(),
+      );
+  }
+
+  /**
+   * Opens the dialog with the first two selected objects as its sides
+   */
+  function open() {
+    const bucket = S3Utils.getQueryParam("bucket");
+    const path = S3Utils.getQueryParam("path") || "";
+    const selected = [...document.querySelectorAll(".select-object:checked")]
+      .slice(0, 2)
+      .map((cb) => `${bucket}/${path === "" ? cb.value : `${path}/${cb.value}`}`);
+
+    document.getElementById("compare-left").value = selected[0] || `${bucket}/`;
+    document.getElementById("compare-right").value = selected[1] || `${bucket}/`;
+    document.getElementById("compare-result").innerHTML = "";
+    document.getElementById("compare-modal")?.showModal();
+  }
+
+  /**
+   * Compares the two sides and shows the result
+   */
+  async function compare() {
+    const result = document.getElementById("compare-result");
+    result.innerHTML = `<p class="text-muted">Comparing...</p>`;
+    try {
+      const { data } = await S3API.get("/diff", {
+        left: document.getElementById("compare-left").value.trim(),
+        right: document.getElementById("compare-right").value.trim(),
+      });
+      result.innerHTML = renderSummary(data) + renderDiff(data);
+    } catch (error) {
+      result.innerHTML = "";
+      S3Utils.showToast(`Error comparing objects: ${error.message}`);
+    }
+  }
+
+  /**
+   * Renders the properties of both sides, marking those that differ
+   * @param {Object} diff - Comparison result
+   * @returns {string} HTML
+   */
+  function renderSummary(diff) {
+    const rows = [
+      ["Size", (s) => S3Utils.formatFileSize(s.size)],
+      ["ETag", (s) => s.etag || ""],
+      ["SHA-256", (s) => s.sha256],
+      ["Type", (s) => s.content_type || ""],
+      ["Modified", (s) => S3Utils.formatDate(s.last_modified)],
+    ]
+      .map(([name, value]) => {
+        const left = value(diff.left);
+        const right = value(diff.right);
+        return `<tr class="${left === right ? "" : "compare-differs"}">
+                    <th>${name}</th>
+                    <td>${S3Utils.escapeHtml(left)}</td>
+                    <td>${S3Utils.escapeHtml(right)}</td>
+                </tr>`;
+      })
+      .join("");
+    const verdict = diff.identical
+      ? "The objects are identical."
+      : diff.text
+        ? "The objects differ."
+        : "The objects differ, and at least one of them isn't text.";
+    return `<p>${verdict}</p>
+            <div class="table-container overflow-auto">
+                <table class="compare-summary">
+                    <thead>
+                        <tr>
+                            <th></th>
+                            <th>${S3Utils.escapeHtml(`${diff.left.bucket}/${diff.left.key}`)}</th>
+                            <th>${S3Utils.escapeHtml(`${diff.right.bucket}/${diff.right.key}`)}</th>
+                        </tr>
+                    </thead>
+                    <tbody>${rows}</tbody>
+                </table>
+            </div>`;
+  }
+
+  /**
+   * Renders the unified diff of two text objects, if there is one
+   * @param {Object} diff - Comparison result
+   * @returns {string} HTML
+   */
+  function renderDiff(diff) {
+    if (!diff.diff) return "";
+    const lines = diff.diff
+      .replace(/\n$/, "")
+      .split("\n")
+      .map((line) => {
+        const kind = line.startsWith("@@")
+          ? "diff-hunk"
+          : line.startsWith("+") && !line.startsWith("+++")
+            ? "diff-add"
+            : line.startsWith("-") && !line.startsWith("---")
+              ? "diff-del"
+              : "diff-same";
+        return `<div class="${kind}">${S3Utils.escapeHtml(line)}</div>`;
+      })
+      .join("");
+    return `<pre class="edit-diff">${lines}</pre>`;
+  }
+
+  // Public API
+  return {
+    init,
+  };
+})();
+
+// Make available globally
+window.CompareModule = CompareModule;
@@ -0,0 +1,248 @@
+/**
+ * Disk Usage Module - Per-folder sizes as a sortable table and a treemap
+ */
+
+const DiskUsageModule = (function () {
+  // Private state
+  let entries = [];
+  let sortKey = "size";
+  let sortAsc = false;
+
+  /**
+   * Initializes the disk usage panel
+   */
+  function init() {
+    const toggleBtn = document.getElementById("du-toggle");
+    if (toggleBtn) {
+      toggleBtn.addEventListener("click", toggle);
+    }
+
+    document.querySelectorAll("#du-table th[data-sort]").forEach((th) => {
+      th.addEventListener("click", () => sortBy(th.dataset.sort));
+    });
+
+    window.addEventListener(
+      "resize",
+      S3Utils.debounce(() => {
+        if (!document.getElementById("du-panel")?.classList.contains("hidden")) {
+          renderTreemap();
+        }
+      }, 200),
+    );
+  }
+
+  /**
+   * Shows or hides the disk usage panel
+   */
+  function toggle() {
+    const panel = document.getElementById("du-panel");
+    if (!panel) return;
+
+    panel.classList.toggle("hidden");
+    if (!panel.classList.contains("hidden")) {
+      load();
+    }
+  }
+
+  /**
+   * Loads the disk usage of the current path
+   */
+  async function load() {
+    const bucket = S3Utils.getQueryParam("bucket");
+    const path = S3Utils.getQueryParam("path") || "";
+    const panel = document.getElementById("du-panel");
+    S3Utils.showLoading(panel);
+
+    try {
+      const data = await S3API.get(`/buckets/${bucket}`, { path, du: "true" });
+      entries = (data.list || []).map((obj) => ({
+        name: obj.key,
+        isDir: obj.is_dir,
+        size: obj.size || 0,
+        count: obj.is_dir ? obj.object_count || 0 : 1,
+      }));
+      const note = document.getElementById("du-note");
+      if (note) {
+        note.textContent = data.truncated
+          ? "Too many objects to read them all, sizes only cover the first part"
+          : "Folder sizes include everything nested below them";
+      }
+      render();
+    } catch (error) {
+      S3Utils.showToast(`Error loading disk usage: ${error.message}`);
+    } finally {
+      S3Utils.hideLoading(panel);
+    }
+  }
+
+  /**
+   * Changes the table sort order
+   * @param {string} key - Column to sort by
+   */
+  function sortBy(key) {
+    if (sortKey === key) {
+      sortAsc = !sortAsc;
+    } else {
+      sortKey = key;
+      sortAsc = key === "name";
+    }
+    renderTable();
+  }
+
+  /**
+   * Renders both the table and the treemap
+   */
+  function render() {
+    renderTable();
+    renderTreemap();
+  }
+
+  /**
+   * Renders the sortable table
+   */
+  function renderTable() {
+    const tbody = document.querySelector("#du-table tbody");
+    if (!tbody) return;
+
+    const total = entries.reduce((sum, e) => sum + e.size, 0) || 1;
+    const sorted = [...entries].sort((a, b) => {
+      const cmp =
+        sortKey === "name"
+          ? a.name.localeCompare(b.name)
+          : a[sortKey] - b[sortKey];
+      return sortAsc ? cmp : -cmp;
+    });
+
+    document.querySelectorAll("#du-table th[data-sort]").forEach((th) => {
+      th.dataset.active = th.dataset.sort === sortKey ? (sortAsc ? "asc" : "desc") : "";
+    });
+
+    if (sorted.length === 0) {
+      tbody.innerHTML = `<tr><td colspan="4" class="text-muted">Nothing here</td></tr>`;
+      return;
+    }
+
+    const bucket = S3Utils.getQueryParam("bucket");
+    const path = S3Utils.getQueryParam("path") || "";
+    tbody.innerHTML = sorted
+      .map((e) => {
+        const fullKey = path === "" ? e.name : `${path}/${e.name}`;
+        const name = e.isDir
+          ? `📁 <a href="objects.html?bucket=${encodeURIComponent(bucket)}&path=${encodeURIComponent(fullKey)}" class="item-link">${S3Utils.escapeHtml(e.name)}/</a>`
+          : `📄 ${S3Utils.escapeHtml(e.name)}`;
+        return `
+                <tr>
+                    <td>${name}</td>
+                    <td class="cell-size">${S3Utils.formatFileSize(e.size)}</td>
+                    <td>${e.count.toLocaleString()}</td>
+                    <td>${((e.size / total) * 100).toFixed(1)}%</td>
+                </tr>`;
+      })
+      .join("");
+  }
+
+  /**
+   * Renders the treemap using the squarified layout
+   */
+  function renderTreemap() {
+    const container = document.getElementById("du-treemap");
+    if (!container) return;
+    container.innerHTML = "";
+
+    const items = entries
+      .filter((e) => e.size > 0)
+      .sort((a, b) => b.size - a.size);
+    const width = container.clientWidth;
+    const height = container.clientHeight;
+    if (items.length === 0 || width === 0 || height === 0) return;
+
+    const total = items.reduce((sum, e) => sum + e.size, 0);
+    const scale = (width * height) / total;
+    const rects = squarify(
+      items.map((e) => ({ item: e, area: e.size * scale })),
+      { x: 0, y: 0, w: width, h: height },
+    );
+
+    rects.forEach(({ item, x, y, w, h }, i) => {
+      const cell = document.createElement("div");
+      cell.className = `du-cell${item.isDir ? " du-dir" : ""}`;
+      cell.style.cssText = `left:${x}px;top:${y}px;width:${w}px;height:${h}px;--du-shade:${Math.max(0.35, 1 - i * 0.05)}`;
+      cell.title = `${item.name}${item.isDir ? "/" : ""} · ${S3Utils.formatFileSize(item.size)}`;
+      if (w > 60 && h > 24) {
+        cell.textContent = item.name;
+      }
+      container.appendChild(cell);
+    });
+  }
+
+  /**
+   * Lays out weighted items in a rectangle, keeping cells close to square
+   * @param {Array} items - Items with an area, sorted in descending order
+   * @param {Object} rect - Rectangle to fill
+   * @returns {Array} Items with their computed position and size
+   */
+  function squarify(items, rect) {
+    const result = [];
+    let remaining = [...items];
+    let { x, y, w, h } = rect;
+
+    while (remaining.length > 0) {
+      const side = Math.min(w, h);
+      let row = [remaining[0]];
+      let i = 1;
+      while (
+        i < remaining.length &&
+        worst([...row, remaining[i]], side) <= worst(row, side)
+      ) {
+        row.push(remaining[i]);
+        i++;
+      }
+      remaining = remaining.slice(i);
+
+      const rowArea = row.reduce((sum, r) => sum + r.area, 0);
+      const thickness = rowArea / side;
+      let offset = 0;
+      row.forEach((r) => {
+        const length = r.area / thickness;
+        if (w >= h) {
+          result.push({ item: r.item, x, y: y + offset, w: thickness, h: length });
+        } else {
+          result.push({ item: r.item, x: x + offset, y, w: length, h: thickness });
+        }
+        offset += length;
+      });
+
+      if (w >= h) {
+        x += thickness;
+        w -= thickness;
+      } else {
+        y += thickness;
+        h -= thickness;
+      }
+    }
+    return result;
+  }
+
+  /**
+   * Gets the worst aspect ratio of a row laid along a side
+   * @param {Array} row - Row items
+   * @param {number} side - Length of the side
+   * @returns {number} Worst aspect ratio
+   */
+  function worst(row, side) {
+    const areas = row.map((r) => r.area);
+    const sum = areas.reduce((a, b) => a + b, 0);
+    const max = Math.max(...areas);
+    const min = Math.min(...areas);
+    return Math.max((side * side * max) / (sum * sum), (sum * sum) / (side * side * min));
+  }
+
+  // Public API
+  return {
+    init,
+    load,
+  };
+})();
+
+// Make available globally
+window.DiskUsageModule = DiskUsageModule;
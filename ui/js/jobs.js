@@ -0,0 +1,214 @@
+/**
+ * Jobs Module - Background jobs drawer with progress and cancellation
+ */
+
+const JobsModule = (function () {
+  // Private state
+  let jobs = [];
+  let pollTimer = null;
+  const watchers = new Map();
+
+  const ACTIVE = ["queued", "running"];
+  const POLL_INTERVAL = 1000;
+
+  /**
+   * Initializes the jobs drawer
+   */
+  function init() {
+    const toggleBtn = document.getElementById("jobs-toggle");
+    if (toggleBtn) {
+      toggleBtn.addEventListener("click", toggle);
+    }
+
+    const closeBtn = document.getElementById("jobs-close");
+    if (closeBtn) {
+      closeBtn.addEventListener("click", () => setOpen(false));
+    }
+
+    refresh();
+  }
+
+  /**
+   * Shows or hides the drawer
+   */
+  function toggle() {
+    const drawer = document.getElementById("jobs-drawer");
+    if (!drawer) return;
+    setOpen(drawer.classList.contains("hidden"));
+  }
+
+  function setOpen(open) {
+    const drawer = document.getElementById("jobs-drawer");
+    if (!drawer) return;
+    drawer.classList.toggle("hidden", !open);
+    if (open) refresh();
+  }
+
+  /**
+   * Submits a new job and opens the drawer
+   * @param {Object} spec - Job type, bucket and its parameters
+   * @param {Function} onFinish - Called with the job once it has finished
+   * @returns {Promise<Object>} The queued job
+   */
+  async function submit(spec, onFinish) {
+    const { data: job } = await S3API.post("/jobs", spec);
+    if (onFinish) watchers.set(job.id, onFinish);
+    setOpen(true);
+    return job;
+  }
+
+  /**
+   * Cancels a queued or running job
+   * @param {string} id - Job ID
+   */
+  async function cancel(id) {
+    try {
+      await S3API.delete(`/jobs/${id}`);
+      refresh();
+    } catch (error) {
+      S3Utils.showToast(`Error cancelling job: ${error.message}`);
+    }
+  }
+
+  /**
+   * Reloads the jobs, polling while any of them is still active
+   */
+  async function refresh() {
+    clearTimeout(pollTimer);
+    try {
+      const { data } = await S3API.get("/jobs");
+      jobs = data || [];
+    } catch (error) {
+      console.error("Failed to load jobs:", error);
+      return;
+    }
+
+    jobs
+      .filter((job) => !ACTIVE.includes(job.status) && watchers.has(job.id))
+      .forEach((job) => {
+        const onFinish = watchers.get(job.id);
+        watchers.delete(job.id);
+        onFinish(job);
+      });
+
+    render();
+    if (jobs.some((job) => ACTIVE.includes(job.status))) {
+      pollTimer = setTimeout(refresh, POLL_INTERVAL);
+    }
+  }
+
+  /**
+   * Renders the badge and the drawer contents
+   */
+  function render() {
+    const active = jobs.filter((job) => ACTIVE.includes(job.status)).length;
+    const badge = document.getElementById("jobs-count");
+    if (badge) {
+      badge.textContent = active;
+      badge.classList.toggle("hidden", active === 0);
+    }
+
+    const list = document.getElementById("jobs-list");
+    if (!list) return;
+    if (jobs.length === 0) {
+      list.innerHTML = `<li class="text-muted">No jobs yet</li>`;
+      return;
+    }
+
+    list.innerHTML = jobs.map(renderJob).join("");
+    list.querySelectorAll("[data-cancel]").forEach((btn) => {
+      btn.addEventListener("click", () => cancel(btn.dataset.cancel));
+    });
+  }
+
+  /**
+   * Renders a single job
+   * @param {Object} job - Job to render
+   * @returns {string} HTML of the job
+   */
+  function renderJob(job) {
+    const p = job.progress;
+    const handled = p.objects_done + p.objects_failed;
+    const total = p.objects_total;
+    // Jobs that know their size in bytes, like fetches, progress by bytes.
+    let bar = `<progress></progress>`;
+    if (p.bytes_total !== undefined) {
+      bar = `<progress value="${p.bytes_done}" max="${p.bytes_total || 1}"></progress>`;
+    } else if (total !== undefined) {
+      bar = `<progress value="${handled}" max="${total || 1}"></progress>`;
+    }
+    const counts = [
+      `${p.objects_done.toLocaleString()}${total !== undefined ? ` / ${total.toLocaleString()}` : ""} objects`,
+      S3Utils.formatFileSize(p.bytes_done) +
+        (p.bytes_total !== undefined
+          ? ` / ${S3Utils.formatFileSize(p.bytes_total)}`
+          : ""),
+      p.objects_failed ? `${p.objects_failed.toLocaleString()} failed` : "",
+    ]
+      .filter(Boolean)
+      .join(" · ");
+
+    const failures = (job.failures || [])
+      .map(
+        (f) =>
+          `<li><code>${S3Utils.escapeHtml(f.key)}</code> ${S3Utils.escapeHtml(f.error)}</li>`,
+      )
+      .join("");
+
+    return `
+            <li class="job job-${job.status}">
+                <div class="job-header">
+                    <strong>${S3Utils.escapeHtml(describe(job.spec))}</strong>
+                    <span class="badge">${job.status}</span>
+                    ${
+                      ACTIVE.includes(job.status)
+                        ? `<button class="btn btn-danger btn-sm ml-auto" data-cancel="${job.id}">Cancel</button>`
+                        : ""
+                    }
+                </div>
+                ${ACTIVE.includes(job.status) ? bar : ""}
+                <div class="text-muted">${counts}</div>
+                ${job.error ? `<div class="job-error">${S3Utils.escapeHtml(job.error)}</div>` : ""}
+                ${failures ? `<details><summary>Failures</summary><ul>${failures}</ul></details>` : ""}
+            </li>`;
+  }
+
+  /**
+   * Describes what a job does
+   * @param {Object} spec - Job spec
+   * @returns {string} Short description
+   */
+  function describe(spec) {
+    const dest = `${spec.dest_bucket || spec.bucket}/${spec.dest_prefix || ""}`;
+    switch (spec.type) {
+      case "delete_bucket":
+        return `Delete bucket ${spec.bucket}`;
+      case "delete": {
+        const count = (spec.keys || []).length + (spec.prefixes || []).length;
+        return count === 1
+          ? `Delete ${spec.bucket}/${(spec.prefixes || spec.keys)[0]}`
+          : `Delete ${count} items in ${spec.bucket}`;
+      }
+      case "copy":
+        return `Copy ${spec.bucket}/${spec.prefix || ""} to ${dest}`;
+      case "move":
+        return `Move ${spec.bucket}/${spec.prefix || ""} to ${dest}`;
+      case "sync":
+        return `Sync ${spec.bucket}/${spec.prefix || ""} to ${dest}`;
+      case "fetch":
+        return `Fetch ${spec.url} to ${spec.bucket}/${spec.key}`;
+      default:
+        return spec.type;
+    }
+  }
+
+  // Public API
+  return {
+    init,
+    submit,
+    refresh,
+  };
+})();
+
+// Make available globally
+window.JobsModule = JobsModule;
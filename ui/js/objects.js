@@ -6,8 +6,32 @@ const ObjectsModule = (function () {
   // Private state
   let nextToken = null;
   let filter = "";
+  let recursive = false;
+  let sortBy = "";
+  let sortOrder = "asc";
+  let columnFilters = {};
   let pageSize = 20;
   let selectedKeysToDelete = [];
+  let selectedFoldersToDelete = [];
+
+  const UPLOAD_BATCH_SIZE = 100;
+  // Larger objects are refused by the server's editor limit anyway.
+  const EDITOR_MAX_SIZE = 1024 * 1024;
+  // Past this many compared line pairs, the diff only shows the changed block.
+  const DIFF_MAX_CELLS = 4_000_000;
+  const TEXT_EXTENSIONS = new Set([
+    "txt", "md", "json", "yaml", "yml", "toml", "ini", "env", "conf", "cfg",
+    "properties", "xml", "csv", "tsv", "log", "html", "css", "js", "ts", "go",
+    "py", "rs", "sh", "sql",
+  ]);
+  // The object open in the editor, and the ETag it had when it was loaded.
+  let editing = null;
+  // Larger images are refused by the server's thumbnail limit anyway.
+  const THUMBNAIL_MAX_SIZE = 20 * 1024 * 1024;
+  const IMAGE_EXTENSIONS = new Set(["png", "jpg", "jpeg", "gif"]);
+  let gridView = localStorage.getItem("s3manager_view") === "grid";
+  // Objects in these classes have to be restored before they can be read.
+  const ARCHIVE_CLASSES = new Set(["GLACIER", "DEEP_ARCHIVE"]);
 
   /**
    * Gets the current bucket name from URL
@@ -37,6 +61,9 @@ const ObjectsModule = (function () {
 
     const urlParams = new URLSearchParams(window.location.search);
     filter = urlParams.get("filter") || "";
+    recursive = urlParams.get("recursive") === "true";
+    sortBy = urlParams.get("sort") || "";
+    sortOrder = urlParams.get("order") === "desc" ? "desc" : "asc";
 
     // Load page size from URL, then localStorage, then default
     const urlPageSize = parseInt(urlParams.get("count"));
@@ -65,6 +92,7 @@ const ObjectsModule = (function () {
 
     setupNavigation();
     setupEventListeners();
+    applyView();
     loadObjects(true);
   }
 
@@ -133,6 +161,38 @@ const ObjectsModule = (function () {
       filterForm.addEventListener("submit", handleFilter);
     }
 
+    // Sortable column headers
+    document.querySelectorAll("#objects-table th[data-sort]").forEach((th) => {
+      th.addEventListener("click", () => handleSort(th.dataset.sort));
+    });
+    updateSortIndicators();
+
+    // Flat listing of every nested object
+    const flatToggle = document.getElementById("flat-toggle");
+    if (flatToggle) {
+      flatToggle.checked = recursive;
+      flatToggle.addEventListener("change", (e) => {
+        recursive = e.target.checked;
+        loadObjects(true);
+      });
+    }
+
+    // Column filters
+    const filtersToggle = document.getElementById("filters-toggle");
+    if (filtersToggle) {
+      filtersToggle.addEventListener("click", () => {
+        document.getElementById("column-filters")?.classList.toggle("hidden");
+      });
+    }
+    const filtersForm = document.getElementById("column-filters");
+    if (filtersForm) {
+      filtersForm.addEventListener("submit", handleColumnFilters);
+      filtersForm.addEventListener("reset", () => {
+        columnFilters = {};
+        loadObjects(true);
+      });
+    }
+
     // Page size selector
     const pageSizeSelect = document.getElementById("object-page-size");
     if (pageSizeSelect) {
@@ -184,6 +244,96 @@ const ObjectsModule = (function () {
       );
     }
 
+    const transferForm = document.getElementById("transfer-form");
+    if (transferForm) {
+      transferForm.addEventListener("submit", confirmTransfer);
+    }
+    const cancelTransferBtn = document.getElementById("cancel-transfer");
+    if (cancelTransferBtn) {
+      cancelTransferBtn.addEventListener("click", () =>
+        document.getElementById("transfer-modal")?.close(),
+      );
+    }
+    document
+      .getElementById("transfer-type")
+      ?.addEventListener("change", updateTransferForm);
+    document
+      .getElementById("transfer-delete")
+      ?.addEventListener("change", updateTransferForm);
+    document
+      .getElementById("transfer-preview")
+      ?.addEventListener("click", previewSync);
+    document
+      .getElementById("new-folder-toggle")
+      ?.addEventListener("click", () => showCreateModal("new-folder"));
+    document
+      .getElementById("new-file-toggle")
+      ?.addEventListener("click", () => showCreateModal("new-file"));
+    document
+      .getElementById("new-folder-form")
+      ?.addEventListener("submit", createFolder);
+    document
+      .getElementById("new-file-form")
+      ?.addEventListener("submit", createFile);
+    ["new-folder", "new-file"].forEach((name) =>
+      document
+        .getElementById(`cancel-${name}`)
+        ?.addEventListener("click", () =>
+          document.getElementById(`${name}-modal`)?.close(),
+        ),
+    );
+    document
+      .getElementById("edit-form")
+      ?.addEventListener("submit", reviewEdit);
+    document
+      .getElementById("cancel-edit")
+      ?.addEventListener("click", () =>
+        document.getElementById("edit-modal")?.close(),
+      );
+    document
+      .getElementById("edit-back")
+      ?.addEventListener("click", () => showEditStep("edit"));
+    document
+      .getElementById("edit-save")
+      ?.addEventListener("click", () => saveEdit(false));
+    document
+      .getElementById("edit-overwrite")
+      ?.addEventListener("click", () => saveEdit(true));
+    document
+      .getElementById("edit-reload")
+      ?.addEventListener("click", () => editObject(editing.key));
+    document
+      .getElementById("fetch-toggle")
+      ?.addEventListener("click", showFetchModal);
+    document
+      .getElementById("fetch-url")
+      ?.addEventListener("input", suggestFetchKey);
+    document
+      .getElementById("fetch-form")
+      ?.addEventListener("submit", confirmFetch);
+    document
+      .getElementById("cancel-fetch")
+      ?.addEventListener("click", () =>
+        document.getElementById("fetch-modal")?.close(),
+      );
+    document
+      .getElementById("restore-form")
+      ?.addEventListener("submit", confirmRestore);
+    document
+      .getElementById("cancel-restore")
+      ?.addEventListener("click", () =>
+        document.getElementById("restore-modal")?.close(),
+      );
+    document.getElementById("view-toggle")?.addEventListener("click", () => {
+      gridView = !gridView;
+      localStorage.setItem("s3manager_view", gridView ? "grid" : "list");
+      applyView();
+      loadObjects(true);
+    });
+    document.getElementById("sync-toggle")?.addEventListener("click", () => {
+      showTransferModal(getCurrentPath().replace(/\/+$/, ""), "sync");
+    });
+
     const confirmDeleteSelectedBtn = document.getElementById(
       "confirm-delete-selected",
     );
@@ -198,18 +348,20 @@ const ObjectsModule = (function () {
    */
   async function loadObjects(reset = true) {
     const tbody = document.querySelector("#objects-table tbody");
+    const grid = document.getElementById("objects-grid");
     if (!tbody) return;
 
     if (reset) {
       nextToken = null;
       tbody.innerHTML = "";
+      if (grid) grid.innerHTML = "";
     }
 
     const bucket = getBucketName();
     const path = getCurrentPath();
 
     // Show loading state
-    const table = document.getElementById("objects-table");
+    const table = gridView && grid ? grid : document.getElementById("objects-table");
     S3Utils.showLoading(table);
 
     // Update URL with filter and count
@@ -220,6 +372,18 @@ const ObjectsModule = (function () {
       url.searchParams.delete("filter");
     }
     url.searchParams.set("count", pageSize);
+    if (recursive) {
+      url.searchParams.set("recursive", "true");
+    } else {
+      url.searchParams.delete("recursive");
+    }
+    if (sortBy) {
+      url.searchParams.set("sort", sortBy);
+      url.searchParams.set("order", sortOrder);
+    } else {
+      url.searchParams.delete("sort");
+      url.searchParams.delete("order");
+    }
     window.history.replaceState({}, "", url);
 
     try {
@@ -228,10 +392,18 @@ const ObjectsModule = (function () {
         count: pageSize,
         filter: filter,
         token: nextToken,
+        recursive: recursive ? "true" : "",
+        sort: sortBy,
+        order: sortBy ? sortOrder : "",
+        ...columnFilters,
       });
 
       const objects = data.list || [];
-      renderObjects(objects, tbody, bucket, path);
+      if (gridView && grid) {
+        renderGrid(objects, grid, bucket, path);
+      } else {
+        renderObjects(objects, tbody, bucket, path);
+      }
       nextToken = data.next_token || null;
 
       // Toggle load more button
@@ -279,7 +451,7 @@ const ObjectsModule = (function () {
       const tr = document.createElement("tr");
 
       const checkboxCell = obj.is_dir
-        ? `<td><input type="checkbox" disabled title="Cannot select folders"></td>`
+        ? `<td><input type="checkbox" class="select-folder" value="${S3Utils.escapeHtml(obj.key)}" title="Select folder to delete"></td>`
         : `<td><input type="checkbox" class="select-object" value="${S3Utils.escapeHtml(obj.key)}"></td>`;
 
       const nameCell = obj.is_dir
@@ -292,9 +464,11 @@ const ObjectsModule = (function () {
                     </div>
                 </td>`
         : `<td>
-                    <div class="item-name">
+                    <div class="item-name" title="${obj.etag ? `ETag ${S3Utils.escapeHtml(obj.etag)}` : ""}">
                         <span class="item-icon">${getFileIcon(obj.key)}</span>
                         <span>${S3Utils.escapeHtml(obj.key)}</span>
+                        ${obj.storage_class && obj.storage_class !== "STANDARD" ? `<span class="badge">${S3Utils.escapeHtml(obj.storage_class)}</span>` : ""}
+                        ${restoreBadge(obj)}
                     </div>
                 </td>`;
 
@@ -302,17 +476,28 @@ const ObjectsModule = (function () {
         ? `<button class="btn btn-primary btn-sm" onclick="ObjectsModule.openFolder('${S3Utils.escapeHtml(bucket)}', '${S3Utils.escapeHtml(fullKey)}')">
                         <span class="btn-icon">📂</span>
                         <span class="btn-text">Open</span>
+                    </button>
+                    <button class="btn btn-secondary btn-sm" onclick="ObjectsModule.showTransferModal('${S3Utils.escapeHtml(fullKey)}')">
+                        <span class="btn-icon">⇄</span>
+                        <span class="btn-text">Copy / Move</span>
                     </button>`
-        : `<button class="btn btn-primary btn-sm" onclick="ObjectsModule.downloadObject('${S3Utils.escapeHtml(bucket)}', '${S3Utils.escapeHtml(fullKey)}')">
+        : `${needsRestore(obj) ? `<button class="btn btn-secondary btn-sm" onclick="ObjectsModule.showRestoreModal('${S3Utils.escapeHtml(fullKey)}')">
+                        <span class="btn-icon">❄</span>
+                        <span class="btn-text">Restore</span>
+                    </button>` : `<button class="btn btn-primary btn-sm" onclick="ObjectsModule.downloadObject('${S3Utils.escapeHtml(bucket)}', '${S3Utils.escapeHtml(fullKey)}')">
                         <span class="btn-icon">⬇</span>
                         <span class="btn-text">Download</span>
-                    </button>`;
+                    </button>`}
+                    ${isEditable(obj) ? `<button class="btn btn-secondary btn-sm" onclick="ObjectsModule.editObject('${S3Utils.escapeHtml(fullKey)}')">
+                        <span class="btn-icon">✎</span>
+                        <span class="btn-text">Edit</span>
+                    </button>` : ""}`;
 
       tr.innerHTML = `
                 ${checkboxCell}
                 ${nameCell}
                 <td class="cell-size">${S3Utils.formatFileSize(obj.size)}</td>
-                <td class="cell-date">${S3Utils.formatDate(obj.last_modified)}</td>
+                <td class="cell-date" title="${S3Utils.escapeHtml(obj.last_modified || "")}">${S3Utils.formatDate(obj.last_modified)}</td>
                 <td class="cell-actions">
                     <div class="action-buttons">
                         ${actionButton}
@@ -326,6 +511,89 @@ const ObjectsModule = (function () {
     });
   }
 
+  /**
+   * Shows either the table or the grid of thumbnails
+   */
+  function applyView() {
+    document
+      .querySelector("#objects-table")
+      ?.closest(".overflow-auto")
+      ?.classList.toggle("hidden", gridView);
+    document
+      .getElementById("objects-grid")
+      ?.classList.toggle("hidden", !gridView);
+    const label = document.querySelector("#view-toggle .btn-text");
+    if (label) label.textContent = gridView ? "List" : "Grid";
+  }
+
+  /**
+   * Reports whether the server can make a thumbnail of an object
+   * @param {Object} obj - Listed object
+   * @returns {boolean} Whether it looks like a small enough image
+   */
+  function hasThumbnail(obj) {
+    const ext = obj.key.split(".").pop().toLowerCase();
+    return obj.size <= THUMBNAIL_MAX_SIZE && IMAGE_EXTENSIONS.has(ext);
+  }
+
+  /**
+   * Renders objects as a grid of thumbnails. Images are only loaded once
+   * they're scrolled into view.
+   * @param {Array} objects - Array of object data
+   * @param {HTMLElement} grid - Grid element
+   * @param {string} bucket - Bucket name
+   * @param {string} path - Current path
+   */
+  function renderGrid(objects, grid, bucket, path) {
+    if (!objects || objects.length === 0) {
+      if (grid.children.length === 0) {
+        grid.innerHTML = `
+                <div class="empty-state">
+                    <div class="empty-state-content">
+                        <span class="empty-state-icon">📄</span>
+                        <p>No objects found</p>
+                        <p class="text-muted">Upload files to get started</p>
+                    </div>
+                </div>`;
+      }
+      return;
+    }
+
+    objects.forEach((obj) => {
+      const fullKey = path === "" ? obj.key : `${path}/${obj.key}`;
+      const card = document.createElement("div");
+      card.className = "grid-item";
+      card.title = obj.key;
+
+      const preview = obj.is_dir
+        ? `<span class="grid-icon">📁</span>`
+        : hasThumbnail(obj)
+          ? `<img src="${S3Utils.escapeHtml(S3API.getThumbnailUrl(bucket, fullKey))}" alt="${S3Utils.escapeHtml(obj.key)}" loading="lazy">`
+          : `<span class="grid-icon">${getFileIcon(obj.key)}</span>`;
+
+      card.innerHTML = `
+                <div class="grid-preview">${preview}</div>
+                <div class="grid-caption">
+                    <input type="checkbox" class="${obj.is_dir ? "select-folder" : "select-object"}" value="${S3Utils.escapeHtml(obj.key)}">
+                    <span class="grid-name">${S3Utils.escapeHtml(obj.key)}${obj.is_dir ? "/" : ""}</span>
+                </div>
+                <div class="grid-meta text-muted">${obj.is_dir ? "Folder" : S3Utils.formatFileSize(obj.size)}</div>`;
+
+      // Images the server can't make a thumbnail of fall back to their icon.
+      card.querySelector("img")?.addEventListener("error", (e) => {
+        e.target.outerHTML = `<span class="grid-icon">${getFileIcon(obj.key)}</span>`;
+      });
+      card.querySelector(".grid-preview").addEventListener("click", () => {
+        if (obj.is_dir) {
+          openFolder(bucket, fullKey);
+        } else {
+          downloadObject(bucket, fullKey);
+        }
+      });
+      grid.appendChild(card);
+    });
+  }
+
   /**
    * Gets an appropriate icon for a file type
    * @param {string} filename - File name
@@ -405,24 +673,36 @@ const ObjectsModule = (function () {
 
     const bucket = getBucketName();
     const path = getCurrentPath();
+    const storageClass = document.getElementById("upload-storage-class")?.value;
     let successCount = 0;
     let errorCount = 0;
 
-    for (const file of files) {
+    // Files go in batches, each one a single request the server uploads
+    // concurrently, with their paths relative to the current folder.
+    for (let i = 0; i < files.length; i += UPLOAD_BATCH_SIZE) {
       const formData = new FormData();
-      let key = file.webkitRelativePath || file.name;
-      if (path) {
-        key = `${path}/${key}`;
+      formData.append("prefix", path);
+      if (storageClass) formData.append("storage_class", storageClass);
+      for (const file of files.slice(i, i + UPLOAD_BATCH_SIZE)) {
+        formData.append("path", file.webkitRelativePath || file.name);
+        formData.append("file", file);
       }
-      formData.append("key", key);
-      formData.append("file", file);
 
       try {
-        await S3API.putFormData(`/buckets/${bucket}/objects`, formData);
-        successCount++;
+        const data = await S3API.postFormData(
+          `/buckets/${bucket}/upload`,
+          formData,
+        );
+        successCount += data.data.uploaded;
+        errorCount += data.data.failed;
+        data.data.results
+          .filter((result) => !result.uploaded)
+          .forEach((result) =>
+            console.error(`Failed to upload ${result.key}:`, result.error),
+          );
       } catch (error) {
-        errorCount++;
-        console.error(`Failed to upload ${file.name}:`, error);
+        errorCount += Math.min(UPLOAD_BATCH_SIZE, files.length - i);
+        console.error("Failed to upload files:", error);
       }
     }
 
@@ -458,6 +738,50 @@ const ObjectsModule = (function () {
     loadObjects(true);
   }
 
+  /**
+   * Sorts by the given column, flipping the order when it's already active
+   * @param {string} key - Column to sort by
+   */
+  function handleSort(key) {
+    if (sortBy === key) {
+      sortOrder = sortOrder === "asc" ? "desc" : "asc";
+    } else {
+      sortBy = key;
+      sortOrder = key === "name" ? "asc" : "desc";
+    }
+    updateSortIndicators();
+    loadObjects(true);
+  }
+
+  /**
+   * Marks the active sort column
+   */
+  function updateSortIndicators() {
+    document.querySelectorAll("#objects-table th[data-sort]").forEach((th) => {
+      th.dataset.active = th.dataset.sort === sortBy ? sortOrder : "";
+    });
+  }
+
+  /**
+   * Handles column filters form submission
+   * @param {Event} e - Submit event
+   */
+  function handleColumnFilters(e) {
+    e.preventDefault();
+    const value = (id) => document.getElementById(id).value.trim();
+    const dayStart = value("filter-after");
+    const dayEnd = value("filter-before");
+    columnFilters = {
+      min_size: value("filter-min-size"),
+      max_size: value("filter-max-size"),
+      modified_after: dayStart ? new Date(`${dayStart}T00:00:00`).toISOString() : "",
+      modified_before: dayEnd ? new Date(`${dayEnd}T23:59:59`).toISOString() : "",
+      ext: value("filter-ext"),
+      storage_class: value("filter-class"),
+    };
+    loadObjects(true);
+  }
+
   /**
    * Handles page size change
    * @param {Event} e - Change event
@@ -473,7 +797,7 @@ const ObjectsModule = (function () {
    * @param {Event} e - Change event
    */
   function handleSelectAll(e) {
-    document.querySelectorAll(".select-object").forEach((cb) => {
+    document.querySelectorAll(".select-object, .select-folder").forEach((cb) => {
       cb.checked = e.target.checked;
     });
   }
@@ -520,6 +844,10 @@ const ObjectsModule = (function () {
     if (recursiveCheckbox) {
       recursiveCheckbox.checked = false;
     }
+    const permanentCheckbox = document.getElementById("delete-object-permanent");
+    if (permanentCheckbox) {
+      permanentCheckbox.checked = false;
+    }
 
     if (modal) modal.showModal();
   }
@@ -540,37 +868,526 @@ const ObjectsModule = (function () {
     const key = document.getElementById("delete-object-name").textContent;
     const recursive =
       document.getElementById("delete-object-recursive")?.checked || false;
+    const permanent =
+      document.getElementById("delete-object-permanent")?.checked || false;
 
     closeDeleteModal();
 
     try {
-      const params = recursive ? { recursive: "true" } : {};
+      if (recursive) {
+        const prefix = `${key}/`;
+        const { data: preview } = await S3API.delete(
+          `/buckets/${bucket}/objects/${encodeURIComponent(prefix)}`,
+          { recursive: "true", dry_run: "true" },
+        );
+        const trashed = !permanent && window.TrashModule?.isEnabled();
+        if (!(await S3Utils.confirmDeletePreview(preview, key, trashed))) return;
+        await JobsModule.submit(
+          { type: "delete", bucket, prefixes: [prefix], permanent, confirm: preview.token },
+          () => loadObjects(true),
+        );
+        return;
+      }
       await S3API.delete(
         `/buckets/${bucket}/objects/${encodeURIComponent(key)}`,
-        params,
+        permanent ? { permanent: "true" } : {},
       );
       loadObjects(true);
-      S3Utils.showToast(`Object "${key}" was deleted`, "success");
+      const trashed = !permanent && window.TrashModule?.isEnabled();
+      S3Utils.showToast(
+        trashed ? `Object "${key}" was moved to the trash` : `Object "${key}" was deleted`,
+        "success",
+      );
     } catch (error) {
       S3Utils.showToast(`Error deleting object: ${error.message}`);
     }
   }
 
+  /**
+   * Shows the copy / move / sync modal for a folder
+   * @param {string} folder - Folder path within the bucket, empty for all of it
+   * @param {string} type - Preselected operation
+   */
+  function showTransferModal(folder, type = "copy") {
+    const source = document.getElementById("transfer-source");
+    source.textContent = `${getBucketName()}/${folder ? `${folder}/` : ""}`;
+    source.dataset.folder = folder;
+    document.getElementById("transfer-type").value = type;
+    document.getElementById("transfer-dest-bucket").value = getBucketName();
+    document.getElementById("transfer-dest-prefix").value = "";
+    document.getElementById("transfer-delete").checked = false;
+    document.getElementById("transfer-storage-class").value = "";
+    updateTransferForm();
+    document.getElementById("transfer-modal")?.showModal();
+  }
+
+  /**
+   * Shows the sync options only when syncing, and drops a stale preview
+   */
+  function updateTransferForm() {
+    const sync = document.getElementById("transfer-type").value === "sync";
+    document.getElementById("transfer-sync-options").classList.toggle("hidden", !sync);
+    document.getElementById("transfer-preview").classList.toggle("hidden", !sync);
+    document.getElementById("transfer-diff").classList.add("hidden");
+  }
+
+  /**
+   * Reads the job spec from the transfer form
+   * @returns {Object} Job spec
+   */
+  function transferSpec() {
+    const folder = document.getElementById("transfer-source").dataset.folder;
+    const destPrefix = document
+      .getElementById("transfer-dest-prefix")
+      .value.trim()
+      .replace(/^\/+|\/+$/g, "");
+    const spec = {
+      type: document.getElementById("transfer-type").value,
+      bucket: getBucketName(),
+      prefix: folder ? `${folder}/` : "",
+      dest_bucket: document.getElementById("transfer-dest-bucket").value.trim(),
+      dest_prefix: destPrefix ? `${destPrefix}/` : "",
+      storage_class: document.getElementById("transfer-storage-class").value,
+    };
+    if (spec.type === "sync") {
+      spec.delete = document.getElementById("transfer-delete").checked;
+    }
+    return spec;
+  }
+
+  /**
+   * Compares the source and destination of a sync
+   * @param {Object} spec - Sync job spec
+   * @returns {Promise<Object>} The diff
+   */
+  async function fetchSyncDiff(spec) {
+    const { data: diff } = await S3API.get(`/buckets/${spec.bucket}/sync`, {
+      prefix: spec.prefix,
+      dest_bucket: spec.dest_bucket,
+      dest_prefix: spec.dest_prefix,
+      delete: spec.delete ? "true" : "",
+    });
+    return diff;
+  }
+
+  /**
+   * Shows what a sync would copy and delete
+   */
+  async function previewSync() {
+    const spec = transferSpec();
+    const diffEl = document.getElementById("transfer-diff");
+    try {
+      const diff = await fetchSyncDiff(spec);
+      const section = (label, changes) => {
+        const items = changes.entries
+          .map((e) => `<li>${S3Utils.escapeHtml(e.key)} (${S3Utils.formatFileSize(e.size)})</li>`)
+          .join("");
+        const more = changes.truncated
+          ? `<li>… and ${changes.count - changes.entries.length} more</li>`
+          : "";
+        return `
+          <details>
+            <summary>${label}: ${changes.count} (${S3Utils.formatFileSize(changes.bytes)})</summary>
+            <ul>${items}${more}</ul>
+          </details>`;
+      };
+      diffEl.innerHTML = `
+        ${section("New", diff.new)}
+        ${section("Changed", diff.changed)}
+        ${spec.delete ? section("Deleted", diff.deleted) : ""}
+        <p class="text-muted">${diff.unchanged} unchanged</p>`;
+      diffEl.classList.remove("hidden");
+    } catch (error) {
+      S3Utils.showToast(`Error comparing folders: ${error.message}`);
+    }
+  }
+
+  /**
+   * Starts the copy, move or sync job. A sync that deletes is confirmed with
+   * the token of its diff first.
+   * @param {Event} e - Submit event
+   */
+  async function confirmTransfer(e) {
+    e.preventDefault();
+    const spec = transferSpec();
+
+    document.getElementById("transfer-modal")?.close();
+    try {
+      if (spec.type === "sync" && spec.delete) {
+        const diff = await fetchSyncDiff(spec);
+        const preview = {
+          objects: diff.deleted.count,
+          bytes: diff.deleted.bytes,
+          sample: diff.deleted.entries.map((e) => e.key),
+        };
+        const dest = `${spec.dest_bucket || spec.bucket}/${spec.dest_prefix}`;
+        if (preview.objects > 0 && !(await S3Utils.confirmDeletePreview(preview, dest))) {
+          return;
+        }
+        spec.confirm = diff.token;
+      }
+      await JobsModule.submit(spec, () => loadObjects(true));
+    } catch (error) {
+      S3Utils.showToast(`Error starting job: ${error.message}`);
+    }
+  }
+
+  /**
+   * Shows an empty new folder or new file modal
+   * @param {string} name - "new-folder" or "new-file"
+   */
+  function showCreateModal(name) {
+    document.getElementById(`${name}-form`)?.reset();
+    document.getElementById(`${name}-modal`)?.showModal();
+  }
+
+  /**
+   * Joins a name to the current folder
+   * @param {string} name - Name relative to the current folder
+   * @returns {string} Key from the bucket's root
+   */
+  function keyInCurrentPath(name) {
+    const path = getCurrentPath().replace(/\/+$/, "");
+    const rel = name.trim().replace(/^\/+/, "");
+    return path ? `${path}/${rel}` : rel;
+  }
+
+  /**
+   * Creates an empty folder in the current one
+   * @param {Event} e - Submit event
+   */
+  async function createFolder(e) {
+    e.preventDefault();
+    const path = keyInCurrentPath(
+      document.getElementById("new-folder-name").value,
+    );
+    try {
+      await S3API.post(`/buckets/${getBucketName()}/folders`, { path });
+      document.getElementById("new-folder-modal")?.close();
+      S3Utils.showToast(`Folder "${path}" was created`, "success");
+      loadObjects(true);
+    } catch (error) {
+      S3Utils.showToast(`Error creating folder: ${error.message}`);
+    }
+  }
+
+  /**
+   * Saves a new text file in the current folder
+   * @param {Event} e - Submit event
+   */
+  async function createFile(e) {
+    e.preventDefault();
+    const key = keyInCurrentPath(
+      document.getElementById("new-file-name").value,
+    );
+    const content = document.getElementById("new-file-content").value;
+    try {
+      await S3API.putText(
+        `/buckets/${getBucketName()}/objects/${encodeURIComponent(key)}`,
+        content,
+      );
+      document.getElementById("new-file-modal")?.close();
+      S3Utils.showToast(`"${key}" was saved`, "success");
+      loadObjects(true);
+    } catch (error) {
+      S3Utils.showToast(`Error saving file: ${error.message}`);
+    }
+  }
+
+  /**
+   * Reports whether an object can be opened in the editor
+   * @param {Object} obj - Listed object
+   * @returns {boolean} Whether it looks like a small text file
+   */
+  function isEditable(obj) {
+    const ext = obj.key.includes(".")
+      ? obj.key.split(".").pop().toLowerCase()
+      : "txt";
+    return obj.size <= EDITOR_MAX_SIZE && TEXT_EXTENSIONS.has(ext);
+  }
+
+  /**
+   * Opens a text object in the editor, remembering its ETag for the save
+   * @param {string} key - Object key
+   */
+  async function editObject(key) {
+    try {
+      const response = await S3API.get(
+        `/buckets/${getBucketName()}/objects/${encodeURIComponent(key)}/content`,
+      );
+      const content = response.data;
+      editing = {
+        key,
+        etag: content.etag,
+        contentType: content.content_type,
+        original: content.content,
+      };
+      document.getElementById("edit-title").textContent = key;
+      document.getElementById("edit-content").value = content.content;
+      showEditStep("edit");
+      const modal = document.getElementById("edit-modal");
+      if (!modal.open) modal.showModal();
+    } catch (error) {
+      S3Utils.showToast(`Error opening file: ${error.message}`);
+    }
+  }
+
+  /**
+   * Switches the editor between editing, the diff and a conflicting save
+   * @param {string} step - "edit", "diff" or "conflict"
+   */
+  function showEditStep(step) {
+    document.getElementById("edit-content").hidden = step !== "edit";
+    document.getElementById("edit-diff").hidden = step === "edit";
+    document
+      .getElementById("edit-conflict")
+      .classList.toggle("hidden", step !== "conflict");
+    document.getElementById("edit-review").hidden = step !== "edit";
+    document.getElementById("edit-back").hidden = step === "edit";
+    document.getElementById("edit-save").hidden = step !== "diff";
+  }
+
+  /**
+   * Shows the changes about to be saved
+   * @param {Event} e - Submit event
+   */
+  function reviewEdit(e) {
+    e.preventDefault();
+    const updated = document.getElementById("edit-content").value;
+    if (updated === editing.original) {
+      S3Utils.showToast("There are no changes to save");
+      return;
+    }
+    const diff = document.getElementById("edit-diff");
+    diff.innerHTML = diffLines(editing.original, updated)
+      .map(
+        ([op, line]) =>
+          `<div class="diff-${op === "+" ? "add" : op === "-" ? "del" : "same"}">${op} ${S3Utils.escapeHtml(line)}</div>`,
+      )
+      .join("");
+    showEditStep("diff");
+  }
+
+  /**
+   * Saves the edited content. Unless overwriting, it only succeeds if the
+   * object wasn't changed since it was opened.
+   * @param {boolean} overwrite - Whether to replace any newer version
+   */
+  async function saveEdit(overwrite) {
+    const headers = overwrite ? {} : { "If-Match": editing.etag };
+    try {
+      await S3API.putText(
+        `/buckets/${getBucketName()}/objects/${encodeURIComponent(editing.key)}`,
+        document.getElementById("edit-content").value,
+        editing.contentType || undefined,
+        headers,
+      );
+      document.getElementById("edit-modal")?.close();
+      S3Utils.showToast(`"${editing.key}" was saved`, "success");
+      loadObjects(true);
+    } catch (error) {
+      if (error.status === 409 || error.status === 412) {
+        showEditStep("conflict");
+        return;
+      }
+      S3Utils.showToast(`Error saving file: ${error.message}`);
+    }
+  }
+
+  /**
+   * Compares two texts line by line
+   * @param {string} before - Original text
+   * @param {string} after - Edited text
+   * @returns {Array<Array<string>>} Pairs of "+", "-" or " " and the line
+   */
+  function diffLines(before, after) {
+    const a = before.split("\n");
+    const b = after.split("\n");
+    let start = 0;
+    while (start < a.length && start < b.length && a[start] === b[start]) {
+      start++;
+    }
+    let endA = a.length;
+    let endB = b.length;
+    while (endA > start && endB > start && a[endA - 1] === b[endB - 1]) {
+      endA--;
+      endB--;
+    }
+
+    const same = (lines) => lines.map((line) => [" ", line]);
+    const midA = a.slice(start, endA);
+    const midB = b.slice(start, endB);
+    let middle;
+    if (midA.length * midB.length > DIFF_MAX_CELLS) {
+      middle = [
+        ...midA.map((line) => ["-", line]),
+        ...midB.map((line) => ["+", line]),
+      ];
+    } else {
+      // lcs[i][j] is the longest common subsequence of midA[i:] and midB[j:].
+      const lcs = Array.from({ length: midA.length + 1 }, () =>
+        new Uint32Array(midB.length + 1),
+      );
+      for (let i = midA.length - 1; i >= 0; i--) {
+        for (let j = midB.length - 1; j >= 0; j--) {
+          lcs[i][j] =
+            midA[i] === midB[j]
+              ? lcs[i + 1][j + 1] + 1
+              : Math.max(lcs[i + 1][j], lcs[i][j + 1]);
+        }
+      }
+      middle = [];
+      let i = 0;
+      let j = 0;
+      while (i < midA.length || j < midB.length) {
+        if (i < midA.length && j < midB.length && midA[i] === midB[j]) {
+          middle.push([" ", midA[i++]]);
+          j++;
+        } else if (
+          j < midB.length &&
+          (i === midA.length || lcs[i][j + 1] >= lcs[i + 1][j])
+        ) {
+          middle.push(["+", midB[j++]]);
+        } else {
+          middle.push(["-", midA[i++]]);
+        }
+      }
+    }
+    return [
+      ...same(a.slice(0, start)),
+      ...middle,
+      ...same(a.slice(endA)),
+    ];
+  }
+
+  /**
+   * Reports whether an object is archived and has no readable copy
+   * @param {Object} obj - Listed object
+   * @returns {boolean} Whether it has to be restored to be downloaded
+   */
+  function needsRestore(obj) {
+    if (!ARCHIVE_CLASSES.has(obj.storage_class)) return false;
+    return !obj.restore || obj.restore.in_progress;
+  }
+
+  /**
+   * Renders the restore status of an archived object
+   * @param {Object} obj - Listed object
+   * @returns {string} HTML
+   */
+  function restoreBadge(obj) {
+    if (!obj.restore) return "";
+    if (obj.restore.in_progress) {
+      return `<span class="badge badge-restoring">Restoring…</span>`;
+    }
+    return `<span class="badge badge-restored" title="Readable until ${S3Utils.escapeHtml(obj.restore.expires_at || "")}">Restored until ${S3Utils.formatDate(obj.restore.expires_at)}</span>`;
+  }
+
+  /**
+   * Shows the restore modal for an archived object
+   * @param {string} key - Object key
+   */
+  function showRestoreModal(key) {
+    const keyEl = document.getElementById("restore-key");
+    keyEl.textContent = key;
+    keyEl.dataset.key = key;
+    document.getElementById("restore-modal")?.showModal();
+  }
+
+  /**
+   * Asks for the archived object to be restored
+   * @param {Event} e - Submit event
+   */
+  async function confirmRestore(e) {
+    e.preventDefault();
+    const key = document.getElementById("restore-key").dataset.key;
+    document.getElementById("restore-modal")?.close();
+    try {
+      await S3API.post(
+        `/buckets/${getBucketName()}/objects/${encodeURIComponent(key)}/restore`,
+        {
+          days: parseInt(document.getElementById("restore-days").value, 10),
+          tier: document.getElementById("restore-tier").value,
+        },
+      );
+      S3Utils.showToast(`Restore of ${key} requested`, "success");
+      loadObjects(true);
+    } catch (error) {
+      S3Utils.showToast(`Error restoring object: ${error.message}`);
+    }
+  }
+
+  /**
+   * Shows the upload from URL modal
+   */
+  function showFetchModal() {
+    document.getElementById("fetch-url").value = "";
+    document.getElementById("fetch-key").value = "";
+    document.getElementById("fetch-key").dataset.edited = "";
+    document.getElementById("fetch-storage-class").value = "";
+    document.getElementById("fetch-modal")?.showModal();
+  }
+
+  /**
+   * Fills the key in with the file name of the URL, unless it was edited
+   */
+  function suggestFetchKey() {
+    const keyInput = document.getElementById("fetch-key");
+    if (keyInput.dataset.edited) return;
+    let name = "";
+    try {
+      const url = new URL(document.getElementById("fetch-url").value);
+      name = decodeURIComponent(url.pathname.split("/").pop() || "");
+    } catch {
+      // Not a URL yet.
+    }
+    const path = getCurrentPath().replace(/\/+$/, "");
+    keyInput.value = name && path ? `${path}/${name}` : name;
+    keyInput.oninput = () => (keyInput.dataset.edited = "true");
+  }
+
+  /**
+   * Starts the fetch job
+   * @param {Event} e - Submit event
+   */
+  async function confirmFetch(e) {
+    e.preventDefault();
+    const spec = {
+      type: "fetch",
+      bucket: getBucketName(),
+      url: document.getElementById("fetch-url").value.trim(),
+      key: document.getElementById("fetch-key").value.trim(),
+      storage_class: document.getElementById("fetch-storage-class").value,
+    };
+
+    document.getElementById("fetch-modal")?.close();
+    try {
+      await JobsModule.submit(spec, () => loadObjects(true));
+    } catch (error) {
+      S3Utils.showToast(`Error starting fetch: ${error.message}`);
+    }
+  }
+
   /**
    * Shows the delete selected modal
    */
   function showDeleteSelectedModal() {
-    const checkboxes = document.querySelectorAll(".select-object:checked");
-    const keys = [...checkboxes].map((cb) => cb.value);
+    const checked = (selector) =>
+      [...document.querySelectorAll(`${selector}:checked`)].map((cb) => cb.value);
+    const keys = checked(".select-object");
+    const folders = checked(".select-folder");
 
-    if (keys.length === 0) {
+    if (keys.length + folders.length === 0) {
       S3Utils.showToast("No objects selected", "warning");
       return;
     }
 
     selectedKeysToDelete = keys;
+    selectedFoldersToDelete = folders;
     const countSpan = document.getElementById("delete-selected-count");
-    if (countSpan) countSpan.textContent = keys.length;
+    if (countSpan) countSpan.textContent = keys.length + folders.length;
+    const permanentCheckbox = document.getElementById("delete-selected-permanent");
+    if (permanentCheckbox) permanentCheckbox.checked = false;
 
     const modal = document.getElementById("delete-selected-modal");
     if (modal) modal.showModal();
@@ -593,20 +1410,35 @@ const ObjectsModule = (function () {
 
     closeDeleteSelectedModal();
 
+    const fullKey = (key) => (path === "" ? key : `${path}/${key}`);
+    const keys = selectedKeysToDelete.map(fullKey);
+    const prefixes = selectedFoldersToDelete.map((folder) => `${fullKey(folder)}/`);
+    const permanent =
+      document.getElementById("delete-selected-permanent")?.checked || false;
     let successCount = 0;
     let errorCount = 0;
-
-    for (const key of selectedKeysToDelete) {
-      const fullKey = path === "" ? key : `${path}/${key}`;
-      try {
-        await S3API.delete(
-          `/buckets/${bucket}/objects/${encodeURIComponent(fullKey)}`,
+    try {
+      const body = { keys, prefixes, permanent };
+      // Folders are deleted with everything in them, which has to be
+      // previewed and confirmed first.
+      if (prefixes.length > 0) {
+        const { data: preview } = await S3API.post(
+          `/buckets/${bucket}/delete?dry_run=true`,
+          body,
         );
-        successCount++;
-      } catch (error) {
-        errorCount++;
-        console.error(`Failed to delete ${key}:`, error);
+        const trashed = !permanent && window.TrashModule?.isEnabled();
+        if (!(await S3Utils.confirmDeletePreview(preview, bucket, trashed))) return;
+        body.confirm = preview.token;
       }
+      const { data } = await S3API.post(`/buckets/${bucket}/delete`, body);
+      successCount = data.deleted;
+      errorCount = data.failed;
+      data.results
+        .filter((r) => !r.deleted)
+        .forEach((r) => console.error(`Failed to delete ${r.key}:`, r.error));
+    } catch (error) {
+      S3Utils.showToast(`Error deleting objects: ${error.message}`);
+      return;
     }
 
     // Reset select all
@@ -656,7 +1488,10 @@ const ObjectsModule = (function () {
     init,
     loadObjects,
     openFolder,
+    showTransferModal,
+    showRestoreModal,
     downloadObject,
+    editObject,
     deleteObject,
     closeDeleteModal,
     confirmDelete,
@@ -0,0 +1,161 @@
+/**
+ * Search Module - Recursive search below the current path, streamed from the server
+ */
+
+const SearchModule = (function () {
+  // Private state
+  let controller = null;
+  let matched = 0;
+
+  /**
+   * Initializes the search panel
+   */
+  function init() {
+    const toggleBtn = document.getElementById("search-toggle");
+    if (toggleBtn) {
+      toggleBtn.addEventListener("click", toggle);
+    }
+
+    const form = document.getElementById("search-form");
+    if (form) {
+      form.addEventListener("submit", (e) => {
+        e.preventDefault();
+        start();
+      });
+    }
+
+    const stopBtn = document.getElementById("search-stop");
+    if (stopBtn) {
+      stopBtn.addEventListener("click", stop);
+    }
+  }
+
+  /**
+   * Shows or hides the search panel, stopping any running search when hidden
+   */
+  function toggle() {
+    const panel = document.getElementById("search-panel");
+    if (!panel) return;
+
+    panel.classList.toggle("hidden");
+    if (panel.classList.contains("hidden")) {
+      stop();
+    } else {
+      document.getElementById("search-query")?.focus();
+    }
+  }
+
+  /**
+   * Starts a new search, replacing the previous results
+   */
+  async function start() {
+    stop();
+
+    const bucket = S3Utils.getQueryParam("bucket");
+    const path = S3Utils.getQueryParam("path") || "";
+    const params = {
+      q: document.getElementById("search-query").value.trim(),
+      mode: document.getElementById("search-mode").value,
+      prefix: path === "" ? "" : `${path}/`,
+      min_size: document.getElementById("search-min-size").value,
+      max_size: document.getElementById("search-max-size").value,
+      modified_after: toTimestamp(document.getElementById("search-after").value, "00:00:00"),
+      modified_before: toTimestamp(document.getElementById("search-before").value, "23:59:59"),
+    };
+    if (!params.q) return;
+
+    const tbody = document.querySelector("#search-table tbody");
+    tbody.innerHTML = "";
+    matched = 0;
+    controller = new AbortController();
+    setRunning(true);
+    setStatus("Searching...");
+
+    try {
+      await S3API.stream(`/buckets/${bucket}/search`, params, handleEvent, controller.signal);
+    } catch (error) {
+      if (error.name === "AbortError") {
+        setStatus(`Stopped after ${matched.toLocaleString()} matches`);
+      } else {
+        setStatus("");
+        S3Utils.showToast(`Error searching objects: ${error.message}`);
+      }
+    } finally {
+      controller = null;
+      setRunning(false);
+    }
+  }
+
+  /**
+   * Stops the running search, if any
+   */
+  function stop() {
+    if (controller) {
+      controller.abort();
+    }
+  }
+
+  /**
+   * Handles a single event of the result stream
+   * @param {Object} event - Object match, final result or error
+   */
+  function handleEvent(event) {
+    if (event.object) {
+      matched++;
+      appendRow(event.object);
+      setStatus(`Searching... ${matched.toLocaleString()} matches`);
+    } else if (event.result) {
+      const { matched: count, scanned, truncated } = event.result;
+      setStatus(
+        `${count.toLocaleString()} matches in ${scanned.toLocaleString()} objects` +
+          (truncated ? " (limit reached)" : ""),
+      );
+    } else if (event.error) {
+      setStatus(`Stopped after ${matched.toLocaleString()} matches`);
+      S3Utils.showToast(`Error searching objects: ${event.error}`);
+    }
+  }
+
+  /**
+   * Appends a matched object to the results table
+   * @param {Object} obj - Matched object
+   */
+  function appendRow(obj) {
+    const bucket = S3Utils.getQueryParam("bucket");
+    const tbody = document.querySelector("#search-table tbody");
+    const row = document.createElement("tr");
+    row.innerHTML = `
+            <td>📄 <a href="${S3API.getObjectDownloadUrl(bucket, obj.key)}" class="item-link">${S3Utils.escapeHtml(obj.key)}</a></td>
+            <td class="cell-size">${S3Utils.formatFileSize(obj.size || 0)}</td>
+            <td>${S3Utils.formatDate(obj.last_modified)}</td>`;
+    tbody.appendChild(row);
+  }
+
+  /**
+   * Converts a date input value to an RFC 3339 timestamp in the local zone
+   * @param {string} date - Value of a date input
+   * @param {string} time - Time of day
+   * @returns {string} Timestamp, or an empty string if no date was picked
+   */
+  function toTimestamp(date, time) {
+    return date ? new Date(`${date}T${time}`).toISOString() : "";
+  }
+
+  function setRunning(running) {
+    document.getElementById("search-start").style.display = running ? "none" : "";
+    document.getElementById("search-stop").style.display = running ? "" : "none";
+  }
+
+  function setStatus(text) {
+    document.getElementById("search-status").textContent = text;
+  }
+
+  // Public API
+  return {
+    init,
+    stop,
+  };
+})();
+
+// Make available globally
+window.SearchModule = SearchModule;
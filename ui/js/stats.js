@@ -0,0 +1,182 @@
+/**
+ * Stats Module - Bucket usage panel backed by the stats endpoint
+ */
+
+const StatsModule = (function () {
+  // Private state
+  let pollTimer = null;
+  const pollInterval = 1000;
+
+  /**
+   * Gets the stats prefix for the current path
+   * @returns {string} Prefix, ending with a slash unless at the root
+   */
+  function getPrefix() {
+    const path = S3Utils.getQueryParam("path") || "";
+    return path === "" || path.endsWith("/") ? path : `${path}/`;
+  }
+
+  /**
+   * Initializes the usage panel
+   */
+  function init() {
+    const toggleBtn = document.getElementById("usage-toggle");
+    if (toggleBtn) {
+      toggleBtn.addEventListener("click", toggle);
+    }
+
+    const refreshBtn = document.getElementById("usage-refresh");
+    if (refreshBtn) {
+      refreshBtn.addEventListener("click", () => load(true));
+    }
+
+    const cancelBtn = document.getElementById("usage-cancel");
+    if (cancelBtn) {
+      cancelBtn.addEventListener("click", cancel);
+    }
+  }
+
+  /**
+   * Shows or hides the usage panel
+   */
+  function toggle() {
+    const panel = document.getElementById("usage-panel");
+    if (!panel) return;
+
+    panel.classList.toggle("hidden");
+    if (panel.classList.contains("hidden")) {
+      stopPolling();
+    } else {
+      load(false);
+    }
+  }
+
+  /**
+   * Loads the stats, polling while the walk is still running
+   * @param {boolean} refresh - Whether to discard cached results
+   */
+  async function load(refresh = false) {
+    stopPolling();
+    const bucket = S3Utils.getQueryParam("bucket");
+
+    try {
+      const data = await S3API.get(`/buckets/${bucket}/stats`, {
+        prefix: getPrefix(),
+        refresh: refresh ? "true" : null,
+      });
+      const stats = data.data || {};
+      render(stats);
+      if (stats.status === "running") {
+        pollTimer = setTimeout(() => load(false), pollInterval);
+      }
+    } catch (error) {
+      S3Utils.showToast(`Error loading usage: ${error.message}`);
+    }
+  }
+
+  /**
+   * Cancels the running walk
+   */
+  async function cancel() {
+    const bucket = S3Utils.getQueryParam("bucket");
+    try {
+      await S3API.delete(`/buckets/${bucket}/stats`, { prefix: getPrefix() });
+      load(false);
+    } catch (error) {
+      S3Utils.showToast(`Error cancelling usage: ${error.message}`);
+    }
+  }
+
+  /**
+   * Stops polling for progress
+   */
+  function stopPolling() {
+    if (pollTimer) {
+      clearTimeout(pollTimer);
+      pollTimer = null;
+    }
+  }
+
+  /**
+   * Renders the stats into the usage panel
+   * @param {Object} stats - Bucket stats
+   */
+  function render(stats) {
+    const running = stats.status === "running";
+    const cancelBtn = document.getElementById("usage-cancel");
+    if (cancelBtn) cancelBtn.style.display = running ? "inline-flex" : "none";
+
+    const status = document.getElementById("usage-status");
+    if (status) {
+      const label = {
+        running: "Scanning…",
+        done: `Computed ${S3Utils.formatDate(stats.finished_at)}`,
+        cancelled: "Cancelled, partial results",
+        failed: `Failed: ${stats.error || "unknown error"}`,
+      };
+      status.textContent = label[stats.status] || stats.status;
+    }
+
+    const summary = document.getElementById("usage-summary");
+    if (summary) {
+      summary.innerHTML = `
+                <div><strong>${(stats.object_count || 0).toLocaleString()}</strong> objects</div>
+                <div><strong>${S3Utils.formatFileSize(stats.total_bytes || 0)}</strong> total</div>`;
+    }
+
+    const histogram = document.getElementById("usage-histogram");
+    if (histogram) {
+      const ranges = stats.histogram || [];
+      const max = Math.max(1, ...ranges.map((r) => r.count));
+      histogram.innerHTML = ranges
+        .map(
+          (r) => `
+                <div class="usage-row">
+                    <span class="usage-label">${S3Utils.escapeHtml(r.label)}</span>
+                    <span class="usage-bar"><span style="width: ${(r.count / max) * 100}%"></span></span>
+                    <span class="usage-value">${r.count.toLocaleString()} · ${S3Utils.formatFileSize(r.bytes)}</span>
+                </div>`,
+        )
+        .join("");
+    }
+
+    const classes = document.getElementById("usage-classes");
+    if (classes) {
+      const entries = Object.entries(stats.storage_classes || {});
+      classes.innerHTML = entries.length
+        ? entries
+            .map(
+              ([name, usage]) => `
+                <div class="usage-row">
+                    <span class="usage-label">${S3Utils.escapeHtml(name)}</span>
+                    <span class="usage-value">${usage.count.toLocaleString()} · ${S3Utils.formatFileSize(usage.bytes)}</span>
+                </div>`,
+            )
+            .join("")
+        : `<p class="text-muted">No objects</p>`;
+    }
+
+    const largest = document.getElementById("usage-largest");
+    if (largest) {
+      largest.innerHTML = (stats.largest || [])
+        .map(
+          (obj) => `
+                <li>
+                    <span>${S3Utils.escapeHtml(obj.key)}</span>
+                    <span class="usage-value">${S3Utils.formatFileSize(obj.size)}</span>
+                </li>`,
+        )
+        .join("");
+    }
+  }
+
+  // Public API
+  return {
+    init,
+    load,
+    cancel,
+  };
+})();
+
+// Make available globally
+window.StatsModule = StatsModule;
@@ -0,0 +1,190 @@
+/**
+ * Trash Module - Lists deleted objects to restore or purge them
+ */
+
+const TrashModule = (function () {
+  // Private state
+  let enabled = false;
+  let nextToken = null;
+
+  const PAGE_SIZE = 100;
+
+  /**
+   * Checks whether the trash is enabled and wires up its dialog
+   */
+  async function init() {
+    document
+      .getElementById("trash-toggle")
+      ?.addEventListener("click", open);
+    document
+      .getElementById("trash-close")
+      ?.addEventListener("click", () =>
+        document.getElementById("trash-modal")?.close(),
+      );
+    document.getElementById("trash-more")?.addEventListener("click", () => {
+      load(false);
+    });
+    document.getElementById("trash-empty")?.addEventListener("click", empty);
+
+    try {
+      await S3API.get(`/buckets/${getBucketName()}/trash`, { count: 1 });
+      enabled = true;
+    } catch {
+      // The trash is disabled, deletes are permanent.
+      enabled = false;
+    }
+    document.getElementById("trash-toggle")?.classList.toggle("hidden", !enabled);
+    for (const id of ["delete-object-permanent-container", "delete-selected-permanent-container"]) {
+      document.getElementById(id)?.classList.toggle("hidden", !enabled);
+    }
+    const notice = document.getElementById("delete-object-notice");
+    if (notice && enabled) {
+      notice.textContent = "It will be moved to the trash, where it can be restored.";
+    }
+    const selectedNotice = document.getElementById("delete-selected-notice");
+    if (selectedNotice && enabled) {
+      selectedNotice.textContent =
+        "Objects and folders will be moved to the trash, where they can be restored.";
+    }
+  }
+
+  function getBucketName() {
+    return S3Utils.getQueryParam("bucket") || "";
+  }
+
+  /**
+   * Opens the trash dialog
+   */
+  function open() {
+    document.getElementById("trash-modal")?.showModal();
+    load(true);
+  }
+
+  /**
+   * Loads a page of trash items
+   * @param {boolean} reset - Start over from the first page
+   */
+  async function load(reset) {
+    const tbody = document.getElementById("trash-list");
+    if (!tbody) return;
+    if (reset) {
+      nextToken = null;
+      tbody.innerHTML = "";
+    }
+
+    try {
+      const params = { count: PAGE_SIZE };
+      if (nextToken) params.token = nextToken;
+      const data = await S3API.get(`/buckets/${getBucketName()}/trash`, params);
+      nextToken = data.next_token || null;
+      (data.list || []).forEach((item) => tbody.appendChild(renderItem(item)));
+    } catch (error) {
+      S3Utils.showToast(`Error loading trash: ${error.message}`);
+    }
+
+    document.getElementById("trash-more")?.classList.toggle("hidden", !nextToken);
+    document
+      .getElementById("trash-empty-notice")
+      ?.classList.toggle("hidden", tbody.children.length > 0);
+  }
+
+  /**
+   * Renders a trash item row
+   * @param {Object} item - Trash item
+   * @returns {HTMLElement} Table row
+   */
+  function renderItem(item) {
+    const row = document.createElement("tr");
+    row.innerHTML = `
+      <td title="${S3Utils.escapeHtml(item.key)}">${S3Utils.escapeHtml(item.key)}</td>
+      <td>${S3Utils.formatFileSize(item.size)}</td>
+      <td>${S3Utils.formatDate(item.deleted_at)}</td>
+      <td>${S3Utils.escapeHtml(item.deleted_by || "-")}</td>
+      <td>${S3Utils.formatDate(item.purge_at)}</td>
+      <td class="actions"></td>
+    `;
+    const actions = row.querySelector(".actions");
+    actions.appendChild(
+      S3Utils.createElement(
+        "button",
+        { className: "btn btn-secondary btn-sm", onclick: () => restore(item, row) },
+        "Restore",
+      ),
+    );
+    actions.appendChild(
+      S3Utils.createElement(
+        "button",
+        { className: "btn btn-danger btn-sm", onclick: () => purge(item, row) },
+        "Purge",
+      ),
+    );
+    return row;
+  }
+
+  /**
+   * Moves an item back to its original path
+   * @param {Object} item - Trash item
+   * @param {HTMLElement} row - Its table row
+   */
+  async function restore(item, row) {
+    try {
+      await S3API.post(
+        `/buckets/${getBucketName()}/trash/${encodeURIComponent(item.id)}/restore`,
+      );
+      row.remove();
+      S3Utils.showToast(`"${item.key}" was restored`, "success");
+      if (window.ObjectsModule) ObjectsModule.loadObjects(true);
+    } catch (error) {
+      S3Utils.showToast(`Error restoring object: ${error.message}`);
+    }
+  }
+
+  /**
+   * Permanently deletes an item
+   * @param {Object} item - Trash item
+   * @param {HTMLElement} row - Its table row
+   */
+  async function purge(item, row) {
+    if (!confirm(`Permanently delete "${item.key}"?`)) return;
+    try {
+      await S3API.delete(
+        `/buckets/${getBucketName()}/trash/${encodeURIComponent(item.id)}`,
+      );
+      row.remove();
+    } catch (error) {
+      S3Utils.showToast(`Error purging object: ${error.message}`);
+    }
+  }
+
+  /**
+   * Permanently deletes everything in the bucket's trash
+   */
+  async function empty() {
+    if (!confirm("Permanently delete every object in the trash?")) return;
+    try {
+      await S3API.delete(`/buckets/${getBucketName()}/trash`);
+      load(true);
+      S3Utils.showToast("Trash was emptied", "success");
+    } catch (error) {
+      S3Utils.showToast(`Error emptying trash: ${error.message}`);
+    }
+  }
+
+  /**
+   * Reports whether deleted objects go to the trash
+   * @returns {boolean}
+   */
+  function isEnabled() {
+    return enabled;
+  }
+
+  // Public API
+  return {
+    init,
+    open,
+    isEnabled,
+  };
+})();
+
+// Make available globally
+window.TrashModule = TrashModule;
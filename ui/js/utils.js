@@ -43,8 +43,8 @@ function formatFileSize(bytes) {
 }
 
 /**
- * Formats a date string to a locale-friendly format
- * @param {string} dateString - ISO date string
+ * Formats a date string in the viewer's locale and time zone
+ * @param {string} dateString - RFC 3339 timestamp
  * @returns {string} Formatted date string
  */
 function formatDate(dateString) {
@@ -58,6 +58,7 @@ function formatDate(dateString) {
       day: "numeric",
       hour: "2-digit",
       minute: "2-digit",
+      timeZoneName: "short",
     });
   } catch {
     return dateString;
@@ -162,6 +163,56 @@ function createElement(tag, attrs = {}, children = []) {
   return el;
 }
 
+/**
+ * Shows what a recursive delete would remove and waits for the user to
+ * confirm it by typing the target's name
+ * @param {Object} preview - Dry run result from the API
+ * @param {string} name - Name the user has to type to confirm
+ * @param {boolean} [trashed] - Whether the objects go to the trash
+ * @returns {Promise<boolean>} Whether the deletion was confirmed
+ */
+function confirmDeletePreview(preview, name, trashed = false) {
+  const modal = document.getElementById("delete-preview-modal");
+  const input = document.getElementById("delete-preview-input");
+  const confirmBtn = document.getElementById("confirm-delete-preview");
+  const cancelBtn = document.getElementById("cancel-delete-preview");
+  if (!modal) return Promise.resolve(false);
+
+  document.getElementById("delete-preview-summary").textContent =
+    `${preview.objects} object(s), ${formatFileSize(preview.bytes)}, will be ${trashed ? "moved to the trash" : "permanently deleted"}.`;
+  document.getElementById("delete-preview-name").textContent = name;
+  const sample = document.getElementById("delete-preview-sample");
+  sample.replaceChildren(
+    ...preview.sample.map((key) => createElement("li", {}, key)),
+  );
+  if (preview.objects > preview.sample.length) {
+    sample.appendChild(
+      createElement("li", {}, `… and ${preview.objects - preview.sample.length} more`),
+    );
+  }
+  input.value = "";
+  confirmBtn.disabled = true;
+
+  return new Promise((resolve) => {
+    const onInput = () => {
+      confirmBtn.disabled = input.value !== name;
+    };
+    const finish = (confirmed) => {
+      input.removeEventListener("input", onInput);
+      confirmBtn.onclick = null;
+      cancelBtn.onclick = null;
+      modal.onclose = null;
+      modal.close();
+      resolve(confirmed);
+    };
+    input.addEventListener("input", onInput);
+    confirmBtn.onclick = () => finish(true);
+    cancelBtn.onclick = () => finish(false);
+    modal.onclose = () => finish(false);
+    modal.showModal();
+  });
+}
+
 // Export for use in other modules
 window.S3Utils = {
   showToast,
@@ -173,4 +224,5 @@ window.S3Utils = {
   showLoading,
   hideLoading,
   createElement,
+  confirmDeletePreview,
 };